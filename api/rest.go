@@ -2,10 +2,15 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/exelban/JAM/pkg/html"
 	"github.com/exelban/JAM/pkg/monitor"
@@ -17,6 +22,7 @@ import (
 	"github.com/tdewolff/minify/v2/svg"
 	"github.com/wcharczuk/go-chart/v2"
 	"github.com/wcharczuk/go-chart/v2/drawing"
+	"golang.org/x/sync/singleflight"
 )
 
 type Rest struct {
@@ -25,8 +31,10 @@ type Rest struct {
 	UI        *types.UI
 
 	Version string
+	Token   string // token of the private api endpoints
 
 	minify *minify.M
+	cache  pageCache
 }
 
 func (s *Rest) Router() *http.ServeMux {
@@ -44,13 +52,106 @@ func (s *Rest) Router() *http.ServeMux {
 
 	router.HandleFunc("GET /response-time/{id}", s.responseTime)
 
+	router.HandleFunc("GET /metrics", s.metrics)
+
+	router.HandleFunc("GET /push/{token}", s.push)
+	router.HandleFunc("POST /push/{token}", s.push)
+
+	s.v1(router)
+
 	return router.mux
 }
 
 func (s *Rest) public(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
-	ctx := r.Context()
+	p, err := s.page(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, types.ErrHostNotFound) {
+			s.notFound(w, r)
+			return
+		}
+		log.Printf("[ERROR] %v", err)
+		http.Error(w, fmt.Sprintf("error %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// the browsers and the proxies must revalidate the page, unchanged page is answered with 304 by the etag or the modification time
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", p.etag)
+	http.ServeContent(w, r, "", p.modified, bytes.NewReader(p.body))
+}
+
+// pageTTL - how long the rendered page is cached if the stats were not changed, the relative times on the page are updated after it
+const pageTTL = time.Minute
+
+// page - rendered public page
+type page struct {
+	body     []byte
+	etag     string
+	modified time.Time
+	rendered time.Time
+}
+
+// pageCache - rendered public pages by the host id, the cache is dropped when the monitor version changes
+type pageCache struct {
+	version uint64
+	pages   map[string]*page
+	group   singleflight.Group
+	mu      sync.Mutex
+}
+
+// page - returns the public page from the cache or renders it. The concurrent requests of the same page after the change
+// are waiting for the single render instead of rendering the page each, the different pages are rendered in parallel.
+func (s *Rest) page(ctx context.Context, id string) (*page, error) {
+	version, modified := s.Monitor.Version()
+
+	s.cache.mu.Lock()
+	if s.cache.pages == nil || s.cache.version < version {
+		s.cache.version = version
+		s.cache.pages = make(map[string]*page)
+	}
+	prev, ok := s.cache.pages[id]
+	s.cache.mu.Unlock()
+	if ok && !s.Templates.Debug && time.Since(prev.rendered) < pageTTL {
+		return prev, nil
+	}
+
+	v, err, _ := s.cache.group.Do(fmt.Sprintf("%d/%s", version, id), func() (interface{}, error) {
+		// the render is shared by the requests, so it must not be canceled by the first of them
+		body, err := s.render(context.WithoutCancel(ctx), id)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(body)
+		p := &page{
+			body:     body,
+			etag:     fmt.Sprintf(`"%x"`, sum[:12]),
+			modified: modified,
+			rendered: time.Now(),
+		}
+		// the page could be changed without the stats change, e.g. the duration of the incident
+		if p.modified.IsZero() || (prev != nil && prev.etag != p.etag) {
+			p.modified = p.rendered
+		}
+
+		s.cache.mu.Lock()
+		if s.cache.version == version {
+			s.cache.pages[id] = p
+		}
+		s.cache.mu.Unlock()
+
+		return p, nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	return v.(*page), nil
+}
+
+// render - generates the minified public page of all hosts or of the host by id
+func (s *Rest) render(ctx context.Context, id string) ([]byte, error) {
 	var stats *types.Stats = nil
 	var err error
 	if id == "" {
@@ -59,18 +160,13 @@ func (s *Rest) public(w http.ResponseWriter, r *http.Request) {
 		stats, err = s.Monitor.StatsByID(ctx, id, false)
 	}
 	if err != nil {
-		if errors.Is(types.ErrHostNotFound, err) {
-			s.notFound(w, r)
-			return
+		if errors.Is(err, types.ErrHostNotFound) {
+			return nil, err
 		}
-		log.Printf("[ERROR] get stats: %v", err)
-		http.Error(w, fmt.Sprintf("error get stats: %v", err), http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("get stats: %w", err)
 	}
-
 	if stats == nil {
-		http.Error(w, "stats not found", http.StatusNotFound)
-		return
+		return nil, types.ErrHostNotFound
 	}
 
 	data := struct {
@@ -83,20 +179,15 @@ func (s *Rest) public(w http.ResponseWriter, r *http.Request) {
 
 	var buf bytes.Buffer
 	if err := s.Templates.Public.Execute(&buf, data); err != nil {
-		log.Printf("[ERROR] generate public html: %v", err)
-		http.Error(w, fmt.Sprintf("error generate public html: %v", err), http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("generate public html: %w", err)
 	}
 
 	minified, err := s.minify.Bytes("text/html", buf.Bytes())
 	if err != nil {
-		log.Printf("[ERROR] minify public html: %v", err)
-		http.Error(w, fmt.Sprintf("error minify public html: %v", err), http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("minify public html: %w", err)
 	}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	_, _ = w.Write(minified)
+	return minified, nil
 }
 
 func (s *Rest) notFound(w http.ResponseWriter, r *http.Request) {
@@ -127,6 +218,56 @@ func (s *Rest) static(w http.ResponseWriter, r *http.Request) {
 	http.ServeFileFS(w, r, s.Templates.FS, path)
 }
 
+// metrics - exports the hosts metrics in the prometheus text format. The api token is required if it's set
+func (s *Rest) metrics(w http.ResponseWriter, r *http.Request) {
+	if s.Token != "" && !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="JAM"`)
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := s.Monitor.WriteMetrics(w, s.UI != nil && s.UI.HideURL); err != nil {
+		log.Printf("[ERROR] write metrics: %v", err)
+	}
+}
+
+// push - check-in endpoint for the push hosts. The job can report own failure with status=down and add a message with msg.
+func (s *Rest) push(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<16)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, fmt.Sprintf("error parse form: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	ok := true
+	switch strings.ToLower(r.FormValue("status")) {
+	case "", "up", "ok", "success":
+	case "down", "fail", "failure", "error":
+		ok = false
+	default:
+		http.Error(w, "unknown status", http.StatusBadRequest)
+		return
+	}
+	msg := r.FormValue("msg")
+	if len(msg) > 1024 {
+		msg = msg[:1024]
+	}
+
+	if err := s.Monitor.Push(r.PathValue("token"), ok, msg); err != nil {
+		if errors.Is(err, types.ErrHostNotFound) {
+			http.Error(w, "host not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("[ERROR] push: %v", err)
+		http.Error(w, fmt.Sprintf("error push: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
 func (s *Rest) responseTime(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	ctx := r.Context()
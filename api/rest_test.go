@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/exelban/JAM/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRest_page(t *testing.T) {
+	ts, _, hosts := testServer(t, "")
+
+	get := func(path, etag string) (int, string, []byte) {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		require.NoError(t, err)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, resp.Header.Get("ETag"), body
+	}
+
+	code, etag, body := get("/", "")
+	require.Equal(t, http.StatusOK, code)
+	require.NotEmpty(t, etag)
+	require.NotEmpty(t, body)
+
+	code, _, _ = get("/", etag)
+	require.Equal(t, http.StatusNotModified, code)
+
+	// the concurrent requests of the different pages are rendered in parallel and get the same page from the cache
+	var wg sync.WaitGroup
+	codes, etags := make([]int, 20), make([]string, 20)
+	for i := range etags {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path := "/"
+			if i%2 == 1 {
+				path += hosts[0].ID
+			}
+			codes[i], etags[i], _ = get(path, "")
+		}()
+	}
+	wg.Wait()
+	for i := range etags {
+		require.Equal(t, http.StatusOK, codes[i])
+		require.Equal(t, etags[i%2], etags[i])
+	}
+	require.Equal(t, etag, etags[0])
+
+	// the status change renders the page again
+	for range 2 {
+		require.Equal(t, http.StatusOK, call(t, http.MethodPost, ts.URL+"/push/api-token?status=down", "", "", nil))
+	}
+	code, changed, _ := get("/", etag)
+	require.Equal(t, http.StatusOK, code)
+	require.NotEqual(t, etag, changed)
+}
+
+func TestRest_metrics(t *testing.T) {
+	ts, m, hosts := testServer(t, "secret")
+
+	require.Equal(t, http.StatusUnauthorized, call(t, http.MethodGet, ts.URL+"/metrics", "", "", nil))
+	require.Equal(t, http.StatusUnauthorized, call(t, http.MethodGet, ts.URL+"/metrics", "wrong", "", nil))
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/metrics", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, string(body), `id="`+hosts[0].ID+`"`)
+	require.Contains(t, string(body), `name="push://api"`)
+	require.NotContains(t, string(body), hosts[2].ID)
+
+	t.Run("hide url", func(t *testing.T) {
+		s := &Rest{Monitor: m, UI: &types.UI{HideURL: true}}
+		rec := httptest.NewRecorder()
+		s.metrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), `id="`+hosts[0].ID+`"`)
+		require.NotContains(t, rec.Body.String(), "push://")
+	})
+}
+
+func TestRest_push(t *testing.T) {
+	ts, m, hosts := testServer(t, "")
+	ctx := context.Background()
+
+	form := func(path, body string) int {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	require.Equal(t, http.StatusOK, form("/push/db-token", "status=down&msg=backup+failed"))
+	r, err := m.Store.LastResponse(ctx, hosts[3].ID)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	require.Equal(t, http.StatusInternalServerError, r.Code)
+	require.Equal(t, "backup failed", r.Body)
+
+	r, err = m.Store.LastResponse(ctx, hosts[1].ID)
+	require.NoError(t, err)
+	require.Nil(t, r)
+
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, ts.URL+"/push/web-token", "", "", nil))
+	r, err = m.Store.LastResponse(ctx, hosts[1].ID)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Empty(t, r.Body)
+
+	require.Equal(t, http.StatusNotFound, form("/push/unknown-token", ""))
+	require.Equal(t, http.StatusBadRequest, form("/push/web-token", "status=unknown"))
+}
@@ -0,0 +1,788 @@
+package api
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/exelban/JAM/pkg/monitor"
+	"github.com/exelban/JAM/store"
+	"github.com/exelban/JAM/types"
+)
+
+//go:embed openapi.json
+var openAPI []byte
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// apiHost - host in the v1 api
+type apiHost struct {
+	ID          string           `json:"id"`
+	Name        *string          `json:"name,omitempty"`
+	Description *string          `json:"description,omitempty"`
+	Group       *string          `json:"group,omitempty"`
+	Type        types.HostType   `json:"type"`
+	URL         string           `json:"url,omitempty"`
+	Status      types.StatusType `json:"status"`
+	LastCheck   *time.Time       `json:"lastCheck,omitempty"`
+}
+
+// apiHostStats - host with the stats in the v1 api
+type apiHostStats struct {
+	apiHost
+	Uptime       float64        `json:"uptime"`       // percent of successful checks in the last 30 days
+	ResponseTime int64          `json:"responseTime"` // average response time in milliseconds in the last 30 days
+	SSL          *apiSSL        `json:"ssl,omitempty"`
+	LastOutage   *time.Time     `json:"lastOutage,omitempty"`
+	Chart        []apiChartItem `json:"chart"`
+}
+type apiSSL struct {
+	ExpireAt     time.Time `json:"expireAt"`
+	ExpireInDays int       `json:"expireInDays"`
+	Issuer       string    `json:"issuer,omitempty"`
+	DNSNames     []string  `json:"dnsNames,omitempty"`
+	ChainValid   bool      `json:"chainValid"`
+}
+type apiChartItem struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Status    types.StatusType `json:"status"`
+}
+
+// apiResponse - check result in the v1 api, all durations are in milliseconds
+type apiResponse struct {
+	Timestamp    time.Time        `json:"timestamp"`
+	Status       types.StatusType `json:"status"`
+	StatusCode   int              `json:"statusCode,omitempty"`
+	ResponseTime int64            `json:"responseTime"`
+	DNS          int64            `json:"dns,omitempty"`
+	Connect      int64            `json:"connect,omitempty"`
+	TLSHandshake int64            `json:"tlsHandshake,omitempty"`
+	TTFB         int64            `json:"ttfb,omitempty"`
+	Body         string           `json:"body,omitempty"`
+	Reasons      []string         `json:"reasons,omitempty"`
+	IsAggregated bool             `json:"isAggregated,omitempty"`
+	Uptime       float64          `json:"uptime,omitempty"`
+	Count        int              `json:"count,omitempty"`
+
+	Resolution int64                    `json:"resolution,omitempty"` // seconds of the rollup period
+	MinTime    int64                    `json:"minTime,omitempty"`
+	MaxTime    int64                    `json:"maxTime,omitempty"`
+	P95Time    int64                    `json:"p95Time,omitempty"`
+	Statuses   map[types.StatusType]int `json:"statuses,omitempty"`
+}
+
+// apiIncident - incident in the v1 api
+type apiIncident struct {
+	ID         int              `json:"id"`
+	Severity   types.StatusType `json:"severity"`
+	Start      time.Time        `json:"start"`
+	End        *time.Time       `json:"end,omitempty"`
+	Duration   int64            `json:"duration"` // seconds
+	StatusCode int              `json:"statusCode,omitempty"`
+	StatusText string           `json:"statusText,omitempty"`
+	Response   string           `json:"response,omitempty"`
+	Reasons    []string         `json:"reasons,omitempty"`
+
+	Acknowledged *time.Time            `json:"acknowledged,omitempty"`
+	Updates      []apiUpdate           `json:"updates"`            // public updates
+	Timeline     []types.IncidentEvent `json:"timeline,omitempty"` // whole timeline, only for the requests with the api token
+}
+type apiUpdate struct {
+	Message string    `json:"message"`
+	TS      time.Time `json:"ts"`
+}
+
+// apiIncidentUpdate - request body of the incident acknowledgement and update
+type apiIncidentUpdate struct {
+	Author  string `json:"author"`
+	Message string `json:"message"`
+	Public  bool   `json:"public"`
+}
+
+// apiAnnouncement - manual incident or announcement in the v1 api
+type apiAnnouncement struct {
+	ID       int              `json:"id"`
+	Title    string           `json:"title"`
+	Severity types.StatusType `json:"severity"`
+	Message  string           `json:"message,omitempty"`
+	Hosts    []string         `json:"hosts,omitempty"` // only for the requests with the api token
+	Groups   []string         `json:"groups,omitempty"`
+	Start    time.Time        `json:"start"`
+	End      *time.Time       `json:"end,omitempty"`
+	Config   bool             `json:"config,omitempty"`
+	Updates  []apiUpdate      `json:"updates"`
+}
+
+// apiAnnouncementRequest - request body of the new announcement
+type apiAnnouncementRequest struct {
+	Title    string           `json:"title"`
+	Severity types.StatusType `json:"severity"`
+	Message  string           `json:"message"`
+	Hosts    []string         `json:"hosts"`
+	Groups   []string         `json:"groups"`
+	Start    *time.Time       `json:"start"`
+	End      *time.Time       `json:"end"`
+}
+
+// apiSummary - overall status in the v1 api
+type apiSummary struct {
+	Status types.StatusType         `json:"status"`
+	Hosts  int                      `json:"hosts"`
+	Count  map[types.StatusType]int `json:"count"`
+	Groups []apiGroup               `json:"groups,omitempty"`
+}
+type apiGroup struct {
+	Name   string           `json:"name"`
+	Status types.StatusType `json:"status"`
+	Uptime int              `json:"uptime"`
+}
+
+// apiList - paginated list in the v1 api
+type apiList[T any] struct {
+	Items []T `json:"items"`
+	Skip  int `json:"skip"`
+	Limit int `json:"limit"`
+}
+
+func (s *Rest) v1(router *Router) {
+	router.HandleFunc("GET /api/v1/openapi.json", s.v1OpenAPI)
+	router.HandleFunc("GET /api/v1/summary", s.v1Summary)
+	router.HandleFunc("GET /api/v1/hosts", s.v1Hosts)
+	router.HandleFunc("GET /api/v1/hosts/{id}", s.v1Host)
+	router.HandleFunc("GET /api/v1/hosts/{id}/responses", s.v1Responses)
+	router.HandleFunc("GET /api/v1/hosts/{id}/incidents", s.v1Incidents)
+	router.HandleFunc("GET /api/v1/hosts/{id}/incidents/{incident}", s.v1Incident)
+	router.HandleFunc("POST /api/v1/hosts/{id}/incidents/{incident}/ack", s.auth(s.v1Acknowledge))
+	router.HandleFunc("POST /api/v1/hosts/{id}/incidents/{incident}/updates", s.auth(s.v1IncidentUpdate))
+	router.HandleFunc("GET /api/v1/announcements", s.v1Announcements)
+	router.HandleFunc("POST /api/v1/announcements", s.auth(s.v1Announce))
+	router.HandleFunc("GET /api/v1/announcements/{id}", s.v1Announcement)
+	router.HandleFunc("DELETE /api/v1/announcements/{id}", s.auth(s.v1DeleteAnnouncement))
+	router.HandleFunc("POST /api/v1/announcements/{id}/updates", s.auth(s.v1AnnouncementUpdate))
+	router.HandleFunc("POST /api/v1/announcements/{id}/resolve", s.auth(s.v1ResolveAnnouncement))
+	router.HandleFunc("GET /api/v1/backup", s.auth(s.v1Backup))
+}
+
+func (s *Rest) v1OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPI)
+}
+
+func (s *Rest) v1Summary(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.Monitor.Stats(r.Context())
+	if err != nil {
+		log.Printf("[ERROR] get stats: %v", err)
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("get stats: %v", err))
+		return
+	}
+
+	summary := apiSummary{
+		Status: stats.Status,
+		Count: map[types.StatusType]int{
+			types.UP:          0,
+			types.DEGRADED:    0,
+			types.DOWN:        0,
+			types.UNREACHABLE: 0,
+			types.FLAPPING:    0,
+			types.MAINTENANCE: 0,
+			types.Unknown:     0,
+		},
+	}
+	for _, h := range s.Monitor.Hosts() {
+		if h.Host.Hidden {
+			continue
+		}
+		summary.Hosts++
+		summary.Count[h.Status]++
+	}
+	for _, stat := range stats.Hosts {
+		if stat.Host != "" {
+			continue
+		}
+		summary.Groups = append(summary.Groups, apiGroup{
+			Name:   stat.ID,
+			Status: stat.Status,
+			Uptime: stat.Uptime,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+func (s *Rest) v1Hosts(w http.ResponseWriter, r *http.Request) {
+	skip, limit, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hosts := []monitor.HostStatus{}
+	for _, h := range s.Monitor.Hosts() {
+		if !h.Host.Hidden {
+			hosts = append(hosts, h)
+		}
+	}
+
+	list := apiList[apiHost]{
+		Items: make([]apiHost, 0, min(max(len(hosts)-skip, 0), limit)),
+		Skip:  skip,
+		Limit: limit,
+	}
+	for i := skip; i < len(hosts) && i < skip+limit; i++ {
+		list.Items = append(list.Items, s.apiHost(hosts[i]))
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Rest) v1Host(w http.ResponseWriter, r *http.Request) {
+	h, ok := s.v1FindHost(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := s.Monitor.StatsByID(r.Context(), h.Host.ID, false)
+	if err != nil {
+		log.Printf("[ERROR] get stats: %v", err)
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("get stats: %v", err))
+		return
+	}
+	stat := stats.Hosts[0]
+
+	res := apiHostStats{
+		apiHost: s.apiHost(h),
+		Chart:   make([]apiChartItem, 0, len(stat.Chart.Points)),
+	}
+	for _, p := range stat.Chart.Points {
+		res.Chart = append(res.Chart, apiChartItem{
+			Timestamp: p.TS,
+			Status:    p.Status,
+		})
+	}
+	if d := stat.Details; d != nil {
+		res.Uptime = d.UptimePercent
+		res.ResponseTime = d.AvgResponseTime.Milliseconds()
+		if d.SSL != nil {
+			res.SSL = &apiSSL{
+				ExpireAt:     d.SSL.ExpireAt,
+				ExpireInDays: d.SSL.ExpireInDays,
+				Issuer:       d.SSL.Issuer,
+				DNSNames:     d.SSL.DNSNames,
+				ChainValid:   d.SSL.ChainValid,
+			}
+		}
+		if d.LastOutage != nil {
+			res.LastOutage = &d.LastOutage.At
+		}
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Rest) v1Responses(w http.ResponseWriter, r *http.Request) {
+	h, ok := s.v1FindHost(w, r)
+	if !ok {
+		return
+	}
+	skip, limit, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	from, err := parseTime(r.URL.Query().Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("from: %v", err))
+		return
+	}
+	to, err := parseTime(r.URL.Query().Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("to: %v", err))
+		return
+	}
+
+	responses, err := s.Monitor.Responses(r.Context(), h.Host.ID, from, to, skip, limit)
+	if err != nil {
+		log.Printf("[ERROR] get responses: %v", err)
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("get responses: %v", err))
+		return
+	}
+
+	list := apiList[apiResponse]{
+		Items: make([]apiResponse, 0, len(responses)),
+		Skip:  skip,
+		Limit: limit,
+	}
+	for _, resp := range responses {
+		list.Items = append(list.Items, apiResponse{
+			Timestamp:    resp.Timestamp,
+			Status:       resp.StatusType,
+			StatusCode:   resp.Code,
+			ResponseTime: resp.Time.Milliseconds(),
+			DNS:          resp.DNS.Milliseconds(),
+			Connect:      resp.Connect.Milliseconds(),
+			TLSHandshake: resp.TLSHandshake.Milliseconds(),
+			TTFB:         resp.TTFB.Milliseconds(),
+			Body:         resp.Body,
+			Reasons:      resp.Reasons,
+			IsAggregated: resp.IsAggregated,
+			Uptime:       resp.Uptime,
+			Count:        resp.Count,
+
+			Resolution: int64(resp.Resolution.Seconds()),
+			MinTime:    resp.MinTime.Milliseconds(),
+			MaxTime:    resp.MaxTime.Milliseconds(),
+			P95Time:    resp.P95Time.Milliseconds(),
+			Statuses:   resp.Statuses,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Rest) v1Incidents(w http.ResponseWriter, r *http.Request) {
+	h, ok := s.v1FindHost(w, r)
+	if !ok {
+		return
+	}
+	skip, limit, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	incidents, err := s.Monitor.Incidents(r.Context(), h.Host.ID, skip, limit)
+	if err != nil {
+		log.Printf("[ERROR] get incidents: %v", err)
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("get incidents: %v", err))
+		return
+	}
+
+	list := apiList[apiIncident]{
+		Items: make([]apiIncident, 0, len(incidents)),
+		Skip:  skip,
+		Limit: limit,
+	}
+	private := s.authorized(r)
+	for _, e := range incidents {
+		list.Items = append(list.Items, newAPIIncident(e, private))
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Rest) v1Incident(w http.ResponseWriter, r *http.Request) {
+	h, ok := s.v1FindHost(w, r)
+	if !ok {
+		return
+	}
+	incidentID, err := strconv.Atoi(r.PathValue("incident"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid incident id")
+		return
+	}
+
+	incident, err := s.Monitor.Incident(r.Context(), h.Host.ID, incidentID)
+	if err != nil {
+		writeIncidentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newAPIIncident(incident, s.authorized(r)))
+}
+
+func (s *Rest) v1Acknowledge(w http.ResponseWriter, r *http.Request) {
+	h, ok := s.v1FindHost(w, r)
+	if !ok {
+		return
+	}
+	incidentID, err := strconv.Atoi(r.PathValue("incident"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid incident id")
+		return
+	}
+	var req apiIncidentUpdate
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("decode body: %v", err))
+			return
+		}
+	}
+
+	incident, err := s.Monitor.Acknowledge(r.Context(), h.Host.ID, incidentID, req.Author, req.Message)
+	if err != nil {
+		writeIncidentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newAPIIncident(incident, true))
+}
+
+func (s *Rest) v1IncidentUpdate(w http.ResponseWriter, r *http.Request) {
+	h, ok := s.v1FindHost(w, r)
+	if !ok {
+		return
+	}
+	incidentID, err := strconv.Atoi(r.PathValue("incident"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid incident id")
+		return
+	}
+	var req apiIncidentUpdate
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("decode body: %v", err))
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		writeError(w, http.StatusBadRequest, "message is required")
+		return
+	}
+
+	incident, err := s.Monitor.AddIncidentUpdate(r.Context(), h.Host.ID, incidentID, req.Author, req.Message, req.Public)
+	if err != nil {
+		writeIncidentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newAPIIncident(incident, true))
+}
+
+func (s *Rest) v1Announcements(w http.ResponseWriter, r *http.Request) {
+	skip, limit, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	announcements, err := s.Monitor.Announcements(r.Context())
+	if err != nil {
+		writeAnnouncementError(w, err)
+		return
+	}
+
+	list := apiList[apiAnnouncement]{
+		Items: make([]apiAnnouncement, 0, limit),
+		Skip:  skip,
+		Limit: limit,
+	}
+	private := s.authorized(r)
+	for i := skip; i < len(announcements) && i < skip+limit; i++ {
+		list.Items = append(list.Items, newAPIAnnouncement(announcements[i], private))
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Rest) v1Announcement(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid announcement id")
+		return
+	}
+
+	a, err := s.Monitor.Announcement(r.Context(), id)
+	if err != nil {
+		writeAnnouncementError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newAPIAnnouncement(a, s.authorized(r)))
+}
+
+func (s *Rest) v1Announce(w http.ResponseWriter, r *http.Request) {
+	var req apiAnnouncementRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("decode body: %v", err))
+		return
+	}
+
+	a := &types.Announcement{
+		Title:    strings.TrimSpace(req.Title),
+		Severity: req.Severity,
+		Message:  req.Message,
+		Hosts:    req.Hosts,
+		Groups:   req.Groups,
+		EndTS:    req.End,
+	}
+	if req.Start != nil {
+		a.StartTS = *req.Start
+	}
+	if err := s.Monitor.Announce(r.Context(), a); err != nil {
+		writeAnnouncementError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newAPIAnnouncement(a, true))
+}
+
+func (s *Rest) v1AnnouncementUpdate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid announcement id")
+		return
+	}
+	var req apiIncidentUpdate
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("decode body: %v", err))
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		writeError(w, http.StatusBadRequest, "message is required")
+		return
+	}
+
+	a, err := s.Monitor.AddAnnouncementUpdate(r.Context(), id, req.Author, req.Message)
+	if err != nil {
+		writeAnnouncementError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newAPIAnnouncement(a, true))
+}
+
+func (s *Rest) v1ResolveAnnouncement(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid announcement id")
+		return
+	}
+	var req apiIncidentUpdate
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("decode body: %v", err))
+			return
+		}
+	}
+
+	a, err := s.Monitor.ResolveAnnouncement(r.Context(), id, req.Author, req.Message)
+	if err != nil {
+		writeAnnouncementError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newAPIAnnouncement(a, true))
+}
+
+func (s *Rest) v1DeleteAnnouncement(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid announcement id")
+		return
+	}
+
+	if err := s.Monitor.DeleteAnnouncement(r.Context(), id); err != nil {
+		writeAnnouncementError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newAPIAnnouncement - converts the announcement to the api one, the host references are returned only for the private requests
+func newAPIAnnouncement(a *types.Announcement, private bool) apiAnnouncement {
+	res := apiAnnouncement{
+		ID:       a.ID,
+		Title:    a.Title,
+		Severity: a.Severity,
+		Message:  a.Message,
+		Groups:   a.Groups,
+		Start:    a.StartTS,
+		End:      a.EndTS,
+		Config:   a.Config,
+		Updates:  []apiUpdate{},
+	}
+	for _, u := range a.PublicUpdates() {
+		res.Updates = append(res.Updates, apiUpdate{Message: u.Message, TS: u.TS})
+	}
+	if private {
+		res.Hosts = a.Hosts
+	}
+	return res
+}
+
+// writeAnnouncementError - writes the error of the announcement operation with the matching status code
+func writeAnnouncementError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrAnnouncementInvalid):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, types.ErrAnnouncementNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, types.ErrAnnouncementResolved), errors.Is(err, types.ErrAnnouncementReadOnly):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("[ERROR] announcement: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// v1Backup - streams the backup of the whole storage in the JSON Lines format, the same as the export command writes
+func (s *Rest) v1Backup(w http.ResponseWriter, r *http.Request) {
+	// the backup of the long history takes longer than the write timeout of the server
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("[WARN] backup: failed to reset the write deadline: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="jam-%s.jsonl"`, time.Now().Format("20060102-150405")))
+	stats, err := store.Export(r.Context(), s.Monitor.Store, w)
+	if err != nil {
+		// the response is already started, so the error is only logged and the backup is truncated
+		log.Printf("[ERROR] backup: %v", err)
+		return
+	}
+	log.Printf("[INFO] backup: %s", stats)
+}
+
+// newAPIIncident - converts the incident to the api one, the whole timeline is returned only for the private requests
+func newAPIIncident(e *types.Incident, private bool) apiIncident {
+	end := time.Now()
+	if e.EndTS != nil {
+		end = *e.EndTS
+	}
+	res := apiIncident{
+		ID:         e.ID,
+		Severity:   e.GetSeverity(),
+		Start:      e.StartTS,
+		End:        e.EndTS,
+		Duration:   int64(end.Sub(e.StartTS).Seconds()),
+		StatusCode: e.Details.StatusCode,
+		StatusText: e.Details.StatusText,
+		Response:   e.Details.Response,
+		Reasons:    e.Details.Reasons,
+		Updates:    []apiUpdate{},
+	}
+	if ack := e.Acknowledgement(); ack != nil {
+		res.Acknowledged = &ack.TS
+	}
+	for _, u := range e.PublicUpdates() {
+		res.Updates = append(res.Updates, apiUpdate{Message: u.Message, TS: u.TS})
+	}
+	if private {
+		res.Timeline = e.Timeline
+	}
+	return res
+}
+
+// writeIncidentError - writes the error of the incident operation with the matching status code
+func writeIncidentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrHostNotFound), errors.Is(err, types.ErrIncidentNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, types.ErrIncidentClosed):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("[ERROR] incident: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// auth - allows only the requests with the api token, the endpoints are disabled if the token is not set
+func (s *Rest) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Token == "" {
+			writeError(w, http.StatusForbidden, "api token is not configured")
+			return
+		}
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="JAM"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authorized - checks the bearer token of the request
+func (s *Rest) authorized(r *http.Request) bool {
+	if s.Token == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+// v1FindHost - returns the host from the path, hidden hosts are not available in the api
+func (s *Rest) v1FindHost(w http.ResponseWriter, r *http.Request) (monitor.HostStatus, bool) {
+	h, err := s.Monitor.Host(r.PathValue("id"))
+	if err != nil || h.Host.Hidden {
+		if err != nil && !errors.Is(err, types.ErrHostNotFound) {
+			log.Printf("[ERROR] get host: %v", err)
+		}
+		writeError(w, http.StatusNotFound, types.ErrHostNotFound.Error())
+		return h, false
+	}
+	return h, true
+}
+
+func (s *Rest) apiHost(h monitor.HostStatus) apiHost {
+	res := apiHost{
+		ID:          h.Host.ID,
+		Name:        h.Host.Name,
+		Description: h.Host.Description,
+		Group:       h.Host.Group,
+		Type:        h.Host.Type,
+		Status:      h.Status,
+	}
+	if s.UI == nil || !s.UI.HideURL {
+		res.URL = h.Host.SecureURL()
+	}
+	if !h.LastCheck.IsZero() {
+		res.LastCheck = &h.LastCheck
+	}
+	return res
+}
+
+// pagination - returns skip and limit from the query
+func pagination(r *http.Request) (int, int, error) {
+	skip, limit := 0, defaultLimit
+	if v := r.URL.Query().Get("skip"); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 {
+			return 0, 0, errors.New("skip must be a positive number")
+		}
+		skip = i
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil || i <= 0 {
+			return 0, 0, errors.New("limit must be a positive number")
+		}
+		limit = min(i, maxLimit)
+	}
+	return skip, limit, nil
+}
+
+// parseTime - parses RFC3339 or unix timestamp, empty value returns zero time
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(ts, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("[ERROR] encode json: %v", err)
+	}
+}
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, struct {
+		Error string `json:"error"`
+	}{
+		Error: msg,
+	})
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exelban/JAM/pkg/html"
+	"github.com/exelban/JAM/pkg/monitor"
+	"github.com/exelban/JAM/store"
+	"github.com/exelban/JAM/types"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer - runs the api with the push hosts, so the watchers do not call anything. The templates are read from the repository
+func testServer(t *testing.T, token string) (*httptest.Server, *monitor.Monitor, []*types.Host) {
+	ctx := context.Background()
+	interval := time.Hour
+	cfg := &types.Cfg{
+		FileHosts: []*types.Host{
+			{URL: "push://api", Token: "api-token", Interval: &interval},
+			{URL: "push://web", Token: "web-token", Interval: &interval},
+			{URL: "push://internal", Token: "internal-token", Interval: &interval, Hidden: true},
+			{URL: "push://db", Token: "db-token", Interval: &interval},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	m := &monitor.Monitor{Store: store.NewMemory(ctx)}
+	require.NoError(t, m.Run(cfg))
+
+	templates := &html.Templates{FS: os.DirFS("..")}
+	require.NoError(t, templates.Run(ctx))
+
+	s := &Rest{Monitor: m, Templates: templates, Token: token, UI: &cfg.UI}
+	ts := httptest.NewServer(s.Router())
+	t.Cleanup(ts.Close)
+
+	return ts, m, cfg.Hosts
+}
+
+// call - sends the request to the test server and decodes the json response into v
+func call(t *testing.T, method, url, token, body string, v any) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	if v != nil && len(b) > 0 {
+		require.NoError(t, json.Unmarshal(b, v), string(b))
+	}
+	return resp.StatusCode
+}
+
+func TestRest_v1Hosts(t *testing.T) {
+	ts, _, hosts := testServer(t, "")
+
+	var list apiList[apiHost]
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, ts.URL+"/api/v1/hosts", "", "", &list))
+	require.Equal(t, 0, list.Skip)
+	require.Equal(t, defaultLimit, list.Limit)
+	require.Len(t, list.Items, 3)
+	for i, id := range []string{hosts[0].ID, hosts[1].ID, hosts[3].ID} {
+		require.Equal(t, id, list.Items[i].ID)
+	}
+
+	list = apiList[apiHost]{}
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, ts.URL+"/api/v1/hosts?skip=1&limit=1", "", "", &list))
+	require.Len(t, list.Items, 1)
+	require.Equal(t, hosts[1].ID, list.Items[0].ID)
+	require.Equal(t, 1, list.Limit)
+
+	list = apiList[apiHost]{}
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, ts.URL+"/api/v1/hosts?skip=10", "", "", &list))
+	require.Empty(t, list.Items)
+
+	list = apiList[apiHost]{}
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, ts.URL+"/api/v1/hosts?limit=5000", "", "", &list))
+	require.Equal(t, maxLimit, list.Limit)
+
+	for _, query := range []string{"skip=-1", "skip=a", "limit=0", "limit=a"} {
+		require.Equal(t, http.StatusBadRequest, call(t, http.MethodGet, ts.URL+"/api/v1/hosts?"+query, "", "", nil), query)
+	}
+}
+
+func TestRest_v1Host(t *testing.T) {
+	ts, _, hosts := testServer(t, "")
+
+	var host apiHostStats
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, ts.URL+"/api/v1/hosts/"+hosts[0].ID, "", "", &host))
+	require.Equal(t, hosts[0].ID, host.ID)
+	require.Equal(t, types.PushType, host.Type)
+	require.NotNil(t, host.Chart)
+
+	var e struct {
+		Error string `json:"error"`
+	}
+	require.Equal(t, http.StatusNotFound, call(t, http.MethodGet, ts.URL+"/api/v1/hosts/unknown", "", "", &e))
+	require.Equal(t, types.ErrHostNotFound.Error(), e.Error)
+	require.Equal(t, http.StatusNotFound, call(t, http.MethodGet, ts.URL+"/api/v1/hosts/"+hosts[2].ID, "", "", nil))
+	require.Equal(t, http.StatusNotFound, call(t, http.MethodGet, ts.URL+"/api/v1/hosts/"+hosts[2].ID+"/responses", "", "", nil))
+	require.Equal(t, http.StatusNotFound, call(t, http.MethodGet, ts.URL+"/api/v1/hosts/"+hosts[2].ID+"/incidents", "", "", nil))
+}
+
+func TestRest_v1Summary(t *testing.T) {
+	ts, _, _ := testServer(t, "")
+
+	var summary apiSummary
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, ts.URL+"/api/v1/summary", "", "", &summary))
+	require.Equal(t, 3, summary.Hosts)
+	require.Equal(t, 3, summary.Count[types.Unknown])
+}
+
+func TestRest_v1Responses(t *testing.T) {
+	ts, m, hosts := testServer(t, "")
+	ctx := context.Background()
+	id := hosts[0].ID
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 10 {
+		require.NoError(t, m.Store.AddResponse(ctx, id, &types.HttpResponse{
+			Timestamp:  start.Add(time.Minute * time.Duration(i)),
+			StatusType: types.UP,
+			Code:       200,
+			Time:       time.Duration(i) * time.Millisecond,
+		}))
+	}
+	url := ts.URL + "/api/v1/hosts/" + id + "/responses"
+
+	var list apiList[apiResponse]
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, url, "", "", &list))
+	require.Len(t, list.Items, 10)
+	require.Equal(t, int64(3), list.Items[3].ResponseTime)
+	require.Equal(t, 200, list.Items[3].StatusCode)
+
+	list = apiList[apiResponse]{}
+	from, to := start.Add(time.Minute*2).Format(time.RFC3339), start.Add(time.Minute*7).Format(time.RFC3339)
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, url+"?from="+from+"&to="+to, "", "", &list))
+	require.Len(t, list.Items, 6)
+	require.Equal(t, start.Add(time.Minute*2), list.Items[0].Timestamp.UTC())
+
+	list = apiList[apiResponse]{}
+	query := fmt.Sprintf("?from=%d&skip=3&limit=2", start.Add(time.Minute*2).Unix())
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, url+query, "", "", &list))
+	require.Len(t, list.Items, 2)
+	require.Equal(t, start.Add(time.Minute*5), list.Items[0].Timestamp.UTC())
+	require.Equal(t, 3, list.Skip)
+	require.Equal(t, 2, list.Limit)
+
+	list = apiList[apiResponse]{}
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, url+"?from=-1", "", "", &list))
+	require.Len(t, list.Items, 10)
+
+	for _, query := range []string{"from=yesterday", "to=2024-01-01", "skip=-1", "limit=0"} {
+		require.Equal(t, http.StatusBadRequest, call(t, http.MethodGet, url+"?"+query, "", "", nil), query)
+	}
+}
+
+func TestRest_v1Incidents(t *testing.T) {
+	ts, m, hosts := testServer(t, "secret")
+	ctx := context.Background()
+	id := hosts[1].ID
+
+	for i := range 3 {
+		start := time.Now().Add(-time.Hour * time.Duration(3-i))
+		incident := &types.Incident{
+			Severity: types.DOWN,
+			StartTS:  start,
+			Details:  types.IncidentDetails{StatusCode: 500},
+			Timeline: []types.IncidentEvent{{Type: types.StatusEvent, Status: types.DOWN, TS: start}},
+		}
+		if i < 2 {
+			end := start.Add(time.Minute)
+			incident.EndTS = &end
+		}
+		require.NoError(t, m.Store.AddIncident(ctx, id, incident))
+	}
+	url := ts.URL + "/api/v1/hosts/" + id + "/incidents"
+
+	var list apiList[apiIncident]
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, url, "", "", &list))
+	require.Len(t, list.Items, 3)
+	require.Nil(t, list.Items[0].End)
+	require.Empty(t, list.Items[0].Timeline)
+	open := list.Items[0].ID
+
+	list = apiList[apiIncident]{}
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, url+"?skip=1&limit=1", "secret", "", &list))
+	require.Len(t, list.Items, 1)
+	require.NotNil(t, list.Items[0].End)
+	require.Len(t, list.Items[0].Timeline, 1)
+
+	var incident apiIncident
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, fmt.Sprintf("%s/%d", url, open), "", "", &incident))
+	require.Equal(t, open, incident.ID)
+	require.Equal(t, http.StatusNotFound, call(t, http.MethodGet, fmt.Sprintf("%s/%d", url, open+10), "", "", nil))
+	require.Equal(t, http.StatusBadRequest, call(t, http.MethodGet, url+"/abc", "", "", nil))
+
+	ack := fmt.Sprintf("%s/%d/ack", url, open)
+	require.Equal(t, http.StatusUnauthorized, call(t, http.MethodPost, ack, "", "", nil))
+	require.Equal(t, http.StatusUnauthorized, call(t, http.MethodPost, ack, "wrong", "", nil))
+	incident = apiIncident{}
+	require.Equal(t, http.StatusOK, call(t, http.MethodPost, ack, "secret", `{"author":"john"}`, &incident))
+	require.NotNil(t, incident.Acknowledged)
+	require.Equal(t, http.StatusConflict, call(t, http.MethodPost, fmt.Sprintf("%s/%d/ack", url, open-1), "secret", "", nil))
+
+	updates := fmt.Sprintf("%s/%d/updates", url, open)
+	require.Equal(t, http.StatusBadRequest, call(t, http.MethodPost, updates, "secret", `{"message":" "}`, nil))
+	require.Equal(t, http.StatusBadRequest, call(t, http.MethodPost, updates, "secret", `{`, nil))
+	incident = apiIncident{}
+	require.Equal(t, http.StatusCreated, call(t, http.MethodPost, updates, "secret", `{"message":"investigating","public":true}`, &incident))
+	require.Len(t, incident.Updates, 1)
+}
+
+func TestRest_auth(t *testing.T) {
+	ts, _, _ := testServer(t, "")
+
+	var e struct {
+		Error string `json:"error"`
+	}
+	require.Equal(t, http.StatusForbidden, call(t, http.MethodGet, ts.URL+"/api/v1/backup", "", "", &e))
+	require.Equal(t, "api token is not configured", e.Error)
+	require.Equal(t, http.StatusForbidden, call(t, http.MethodPost, ts.URL+"/api/v1/announcements", "anything", `{"title":"test"}`, nil))
+
+	ts, _, _ = testServer(t, "secret")
+	require.Equal(t, http.StatusUnauthorized, call(t, http.MethodGet, ts.URL+"/api/v1/backup", "", "", nil))
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, ts.URL+"/api/v1/backup", "secret", "", nil))
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, ts.URL+"/api/v1/openapi.json", "", "", nil))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/exelban/JAM/store"
+)
+
+// backup - runs the export or the import command against the storage from the arguments.
+// The storage is opened without the aggregation, so the history is moved as it is.
+func backup(ctx context.Context, command string, args arguments) (err error) {
+	if args.StorageType == "memory" {
+		return errors.New("the memory storage cannot be backed up")
+	}
+
+	s, err := store.Open(ctx, args.StorageType, args.PostgresDSN)
+	if err != nil {
+		return fmt.Errorf("open store: %w", err)
+	}
+	defer func() {
+		if cerr := s.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("close store: %w", cerr)
+		}
+	}()
+
+	switch command {
+	case "export":
+		var w io.Writer = os.Stdout
+		if path := args.Export.Args.File; path != "" {
+			f, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+
+		bw := bufio.NewWriter(w)
+		stats, err := store.Export(ctx, s, bw)
+		if err != nil {
+			return err
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(*os.File); ok && f != os.Stdout {
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+		log.Printf("[INFO] exported %s", stats)
+	case "import":
+		var r io.Reader = os.Stdin
+		if path := args.Import.Args.File; path != "" {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		stats, err := store.Import(ctx, s, bufio.NewReader(r))
+		if err != nil {
+			return fmt.Errorf("%w, imported %s before the error", err, stats)
+		}
+		log.Printf("[INFO] imported %s", stats)
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	return nil
+}
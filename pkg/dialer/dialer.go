@@ -32,6 +32,12 @@ func (d *Dialer) Dial(ctx context.Context, h *types.Host) types.HttpResponse {
 			resp <- d.mongoCall(ctx, h)
 		case types.ICMPType:
 			resp <- d.icmpCall(ctx, h)
+		case types.TCPType:
+			resp <- d.tcpCall(ctx, h)
+		case types.DNSType:
+			resp <- d.dnsCall(ctx, h)
+		case types.TLSType:
+			resp <- d.tlsCall(ctx, h)
 		default:
 			resp <- d.httpCall(ctx, h)
 		}
@@ -39,3 +45,8 @@ func (d *Dialer) Dial(ctx context.Context, h *types.Host) types.HttpResponse {
 
 	return <-resp
 }
+
+// Usage - returns the number of connections in use and the max number of connections
+func (d *Dialer) Usage() (int, int) {
+	return len(d.sem), cap(d.sem)
+}
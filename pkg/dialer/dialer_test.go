@@ -2,8 +2,12 @@ package dialer
 
 import (
 	"context"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -76,6 +80,215 @@ func TestDialer_Dial(t *testing.T) {
 	})
 }
 
+func TestDialer_httpCall_request(t *testing.T) {
+	dialer := New(3)
+	ctx := context.Background()
+
+	router := http.NewServeMux()
+	router.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		if string(b) != `{"query":"{ health }"}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	router.HandleFunc("PUT /form", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.FormValue("key") != "value" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	t.Run("body with bearer token", func(t *testing.T) {
+		body := `{"query":"{ health }"}`
+		resp := dialer.Dial(ctx, &types.Host{
+			URL:  ts.URL + "/graphql",
+			Body: &body,
+			Auth: &types.Auth{Token: "token"},
+		})
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		resp = dialer.Dial(ctx, &types.Host{
+			URL:  ts.URL + "/graphql",
+			Body: &body,
+		})
+		require.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("body from file", func(t *testing.T) {
+		file, err := os.CreateTemp("", "body.json")
+		require.NoError(t, err)
+		defer os.Remove(file.Name())
+		_, err = file.WriteString(`{"query":"{ health }"}`)
+		require.NoError(t, err)
+		path := file.Name()
+
+		resp := dialer.Dial(ctx, &types.Host{
+			Method:   http.MethodPost,
+			URL:      ts.URL + "/graphql",
+			BodyFile: &path,
+			Auth:     &types.Auth{Token: "token"},
+		})
+		require.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("form with basic auth", func(t *testing.T) {
+		resp := dialer.Dial(ctx, &types.Host{
+			Method: http.MethodPut,
+			URL:    ts.URL + "/form",
+			Form:   map[string]string{"key": "value"},
+			Auth:   &types.Auth{Username: "user", Password: "pass"},
+		})
+		require.Equal(t, http.StatusOK, resp.Code)
+	})
+}
+
+func TestDialer_tcpCall(t *testing.T) {
+	dialer := New(3)
+	ctx := context.Background()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				_, _ = conn.Write([]byte("+PONG\r\n"))
+			}(conn)
+		}
+	}()
+	addr := "tcp://" + ln.Addr().String()
+
+	t.Run("connect", func(t *testing.T) {
+		resp := dialer.Dial(ctx, &types.Host{
+			Type: types.TCPType,
+			URL:  addr,
+		})
+		require.True(t, resp.OK)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.NotZero(t, resp.Connect)
+	})
+
+	t.Run("banner match", func(t *testing.T) {
+		payload, banner := "PING\r\n", "+PONG"
+		resp := dialer.Dial(ctx, &types.Host{
+			Type:    types.TCPType,
+			URL:     addr,
+			Payload: &payload,
+			Banner:  &banner,
+		})
+		require.True(t, resp.OK)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "+PONG\r\n", string(resp.Bytes))
+	})
+
+	t.Run("banner mismatch", func(t *testing.T) {
+		banner := "220"
+		resp := dialer.Dial(ctx, &types.Host{
+			Type:   types.TCPType,
+			URL:    addr,
+			Banner: &banner,
+		})
+		require.False(t, resp.OK)
+		require.Equal(t, http.StatusExpectationFailed, resp.Code)
+	})
+
+	t.Run("banner in packets", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				go func(conn net.Conn) {
+					defer conn.Close()
+					_, _ = conn.Write([]byte("220 "))
+					time.Sleep(50 * time.Millisecond)
+					_, _ = conn.Write([]byte("smtp.example.com ESMTP\r\n"))
+					time.Sleep(time.Second)
+				}(conn)
+			}
+		}()
+
+		banner := "220 smtp.example.com"
+		resp := dialer.Dial(ctx, &types.Host{
+			Type:   types.TCPType,
+			URL:    "tcp://" + l.Addr().String(),
+			Banner: &banner,
+		})
+		require.True(t, resp.OK)
+		require.Equal(t, "220 smtp.example.com ESMTP\r\n", string(resp.Bytes))
+
+		timeout := 200 * time.Millisecond
+		banner = "220 smtp.example.com ESMTP ready"
+		resp = dialer.Dial(ctx, &types.Host{
+			Type:            types.TCPType,
+			URL:             "tcp://" + l.Addr().String(),
+			Banner:          &banner,
+			TimeoutInterval: &timeout,
+		})
+		require.False(t, resp.OK)
+		require.Equal(t, http.StatusExpectationFailed, resp.Code)
+		require.Equal(t, "220 smtp.example.com ESMTP\r\n", string(resp.Bytes))
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		closedAddr := l.Addr().String()
+		require.NoError(t, l.Close())
+
+		resp := dialer.Dial(ctx, &types.Host{
+			Type: types.TCPType,
+			URL:  "tcp://" + closedAddr,
+		})
+		require.False(t, resp.OK)
+		require.Equal(t, 523, resp.Code)
+	})
+}
+
+func TestDialer_tlsCall(t *testing.T) {
+	dialer := New(3)
+	ctx := context.Background()
+
+	ts := httptest.NewTLSServer(http.NewServeMux())
+	defer ts.Close()
+
+	resp := dialer.Dial(ctx, &types.Host{
+		Type: types.TLSType,
+		URL:  strings.Replace(ts.URL, "https://", "tls://", 1),
+	})
+	require.False(t, resp.OK)
+	require.Equal(t, 526, resp.Code)
+	require.NotNil(t, resp.SSLCertExpiry)
+	require.NotNil(t, resp.Certificate)
+	require.False(t, resp.Certificate.ChainValid)
+	require.NotEmpty(t, resp.Certificate.Issuer)
+	require.NotEmpty(t, resp.Certificate.ChainError)
+	require.NotZero(t, resp.TLSHandshake)
+}
+
 func srv(timeout time.Duration) (*httptest.Server, *atomic.Value, func()) {
 	router := http.NewServeMux()
 	status := atomic.Value{}
@@ -0,0 +1,184 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/exelban/JAM/types"
+)
+
+// dnsCall resolves the name from the host url and compares the answer with expected records from the conditions.
+// The url format is dns://[resolver[:port]]/name?type=A, if resolver is not provided the system one will be used.
+func (d *Dialer) dnsCall(ctx context.Context, h *types.Host) (response types.HttpResponse) {
+	server, name, typ, err := parseDNSURL(h.URL)
+	if err != nil {
+		response.Body = err.Error()
+		response.Code = http.StatusBadRequest
+		return
+	}
+
+	timeout := time.Second * 30
+	if h.TimeoutInterval != nil {
+		timeout = *h.TimeoutInterval
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resolver := net.DefaultResolver
+	if server != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	response.Timestamp = time.Now()
+	startTime := time.Now()
+	records, err := lookup(ctx, resolver, typ, name)
+	response.DNS = time.Since(startTime)
+	response.Time = response.DNS
+	if err != nil {
+		response.Body = err.Error()
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			response.Code = http.StatusNotFound
+		} else if errors.As(err, &dnsErr) && dnsErr.IsTimeout {
+			response.Code = 522
+		} else {
+			response.Code = 523
+		}
+		return
+	}
+	response.Bytes = []byte(strings.Join(records, "\n"))
+
+	if h.Conditions != nil && len(h.Conditions.Records) > 0 && !matchRecords(records, h.Conditions.Records) {
+		response.Code = http.StatusExpectationFailed
+		response.Body = fmt.Sprintf("unexpected %s records: %s", typ, strings.Join(records, ", "))
+		return
+	}
+
+	response.Code = http.StatusOK
+	response.OK = true
+
+	return
+}
+
+// parseDNSURL - returns resolver address, name and record type from the dns url
+func parseDNSURL(str string) (string, string, string, error) {
+	u, err := url.Parse(str)
+	if err != nil {
+		return "", "", "", fmt.Errorf("parse dns url: %w", err)
+	}
+
+	server, name := "", strings.Trim(u.Path, "/")
+	if name == "" {
+		name = u.Host
+	} else if u.Hostname() != "" {
+		port := u.Port()
+		if port == "" {
+			port = "53"
+		}
+		server = net.JoinHostPort(u.Hostname(), port)
+	}
+	if name == "" {
+		return "", "", "", errors.New("dns name is not provided")
+	}
+
+	typ := strings.ToUpper(u.Query().Get("type"))
+	if typ == "" {
+		typ = "A"
+	}
+
+	return server, name, typ, nil
+}
+
+// lookup - resolves the name and returns the normalized list of records for the type
+func lookup(ctx context.Context, r *net.Resolver, typ, name string) ([]string, error) {
+	list := []string{}
+
+	switch typ {
+	case "A", "AAAA":
+		network := "ip4"
+		if typ == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			list = append(list, ip.String())
+		}
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, cname)
+	case "MX":
+		mxs, err := r.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			list = append(list, mx.Host)
+		}
+	case "NS":
+		nss, err := r.LookupNS(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nss {
+			list = append(list, ns.Host)
+		}
+	case "TXT":
+		txts, err := r.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, txts...)
+	default:
+		return nil, fmt.Errorf("unsupported record type: %s", typ)
+	}
+
+	for i, v := range list {
+		list[i] = normalizeRecord(v)
+	}
+	sort.Strings(list)
+
+	return list, nil
+}
+
+// matchRecords - checks if the answer set is equal to the expected one, the order does not matter
+func matchRecords(records, expected []string) bool {
+	if len(records) != len(expected) {
+		return false
+	}
+
+	want := make([]string, 0, len(expected))
+	for _, v := range expected {
+		want = append(want, normalizeRecord(v))
+	}
+	sort.Strings(want)
+
+	for i := range records {
+		if records[i] != want[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func normalizeRecord(v string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(v)), ".")
+}
@@ -0,0 +1,197 @@
+package dialer
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exelban/JAM/types"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver - runs the udp dns server on the address that answers the A queries with the records by name,
+// the unknown names get NXDOMAIN and the names in silent are not answered at all
+func fakeResolver(t *testing.T, addr string, records map[string][]net.IP, silent ...string) string {
+	conn, err := net.ListenPacket("udp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < 12 {
+				continue
+			}
+
+			// the question is the only one in the query: the labels, the type and the class
+			end, labels := 12, []string{}
+			for end < n && buf[end] != 0 {
+				labels = append(labels, string(buf[end+1:end+1+int(buf[end])]))
+				end += int(buf[end]) + 1
+			}
+			end += 5
+			if end > n {
+				continue
+			}
+			name := strings.ToLower(strings.Join(labels, "."))
+			if slices.Contains(silent, name) {
+				continue
+			}
+
+			ips, ok := records[name]
+			resp := append([]byte{}, buf[:end]...)
+			binary.BigEndian.PutUint16(resp[2:], 0x8180)
+			if !ok {
+				binary.BigEndian.PutUint16(resp[2:], 0x8183)
+			}
+			binary.BigEndian.PutUint16(resp[6:], uint16(len(ips)))
+			binary.BigEndian.PutUint32(resp[8:], 0)
+			for _, ip := range ips {
+				resp = append(resp, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+				resp = append(resp, ip.To4()...)
+			}
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestDialer_dnsCall(t *testing.T) {
+	dialer := New(3)
+	ctx := context.Background()
+	records := map[string][]net.IP{
+		"example.com": {net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.1")},
+	}
+	server := fakeResolver(t, "127.0.0.1:0", records, "slow.example.com")
+
+	t.Run("records", func(t *testing.T) {
+		resp := dialer.Dial(ctx, &types.Host{
+			Type: types.DNSType,
+			URL:  "dns://" + server + "/example.com",
+			Conditions: &types.Success{
+				Records: []string{"10.0.0.1", "10.0.0.2"},
+			},
+		})
+		require.True(t, resp.OK, resp.Body)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "10.0.0.1\n10.0.0.2", string(resp.Bytes))
+		require.NotZero(t, resp.DNS)
+	})
+
+	t.Run("ipv6 resolver", func(t *testing.T) {
+		if conn, err := net.ListenPacket("udp", "[::1]:0"); err != nil {
+			t.Skip("ipv6 is not available")
+		} else {
+			_ = conn.Close()
+		}
+		server := fakeResolver(t, "[::1]:0", records)
+
+		resp := dialer.Dial(ctx, &types.Host{
+			Type: types.DNSType,
+			URL:  "dns://" + server + "/example.com",
+		})
+		require.True(t, resp.OK, resp.Body)
+		require.Equal(t, "10.0.0.1\n10.0.0.2", string(resp.Bytes))
+	})
+
+	t.Run("unexpected records", func(t *testing.T) {
+		resp := dialer.Dial(ctx, &types.Host{
+			Type: types.DNSType,
+			URL:  "dns://" + server + "/example.com",
+			Conditions: &types.Success{
+				Records: []string{"10.0.0.1"},
+			},
+		})
+		require.False(t, resp.OK)
+		require.Equal(t, http.StatusExpectationFailed, resp.Code)
+		require.Equal(t, "unexpected A records: 10.0.0.1, 10.0.0.2", resp.Body)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		resp := dialer.Dial(ctx, &types.Host{
+			Type: types.DNSType,
+			URL:  "dns://" + server + "/missing.example.com",
+		})
+		require.False(t, resp.OK)
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		timeout := 200 * time.Millisecond
+		resp := dialer.Dial(ctx, &types.Host{
+			Type:            types.DNSType,
+			URL:             "dns://" + server + "/slow.example.com",
+			TimeoutInterval: &timeout,
+		})
+		require.False(t, resp.OK)
+		require.Equal(t, 522, resp.Code)
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		resp := dialer.Dial(ctx, &types.Host{
+			Type: types.DNSType,
+			URL:  "dns://" + server + "/example.com?type=SRV",
+		})
+		require.False(t, resp.OK)
+		require.Equal(t, 523, resp.Code)
+	})
+}
+
+func TestParseDNSURL(t *testing.T) {
+	t.Run("with resolver", func(t *testing.T) {
+		server, name, typ, err := parseDNSURL("dns://1.1.1.1/example.com?type=mx")
+		require.NoError(t, err)
+		require.Equal(t, "1.1.1.1:53", server)
+		require.Equal(t, "example.com", name)
+		require.Equal(t, "MX", typ)
+	})
+	t.Run("with resolver and port", func(t *testing.T) {
+		server, name, typ, err := parseDNSURL("dns://10.0.0.2:5353/example.com")
+		require.NoError(t, err)
+		require.Equal(t, "10.0.0.2:5353", server)
+		require.Equal(t, "example.com", name)
+		require.Equal(t, "A", typ)
+	})
+	t.Run("ipv6 resolver", func(t *testing.T) {
+		server, name, _, err := parseDNSURL("dns://[::1]/example.com")
+		require.NoError(t, err)
+		require.Equal(t, "[::1]:53", server)
+		require.Equal(t, "example.com", name)
+
+		server, _, _, err = parseDNSURL("dns://[2001:db8::1]:5353/example.com")
+		require.NoError(t, err)
+		require.Equal(t, "[2001:db8::1]:5353", server)
+	})
+	t.Run("system resolver", func(t *testing.T) {
+		server, name, typ, err := parseDNSURL("dns://example.com?type=TXT")
+		require.NoError(t, err)
+		require.Empty(t, server)
+		require.Equal(t, "example.com", name)
+		require.Equal(t, "TXT", typ)
+	})
+	t.Run("no name", func(t *testing.T) {
+		_, _, _, err := parseDNSURL("dns://")
+		require.Error(t, err)
+	})
+}
+
+func TestMatchRecords(t *testing.T) {
+	records := []string{"10.0.0.1", "10.0.0.2"}
+
+	require.True(t, matchRecords(records, []string{"10.0.0.2", "10.0.0.1"}))
+	require.False(t, matchRecords(records, []string{"10.0.0.1"}))
+	require.False(t, matchRecords(records, []string{"10.0.0.1", "10.0.0.3"}))
+	require.True(t, matchRecords([]string{"mx.example.com"}, []string{"MX.example.com."}))
+}
@@ -1,6 +1,7 @@
 package dialer
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"io"
@@ -8,15 +9,30 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptrace"
+	"net/url"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/exelban/JAM/types"
 )
 
+// maxBodySize - the max size of the response body used for the conditions check
+const maxBodySize = 1 << 20
+
 // httpCall makes a HTTP request to the host
 func (d *Dialer) httpCall(ctx context.Context, h *types.Host) (response types.HttpResponse) {
-	req, err := http.NewRequest(h.Method, h.URL, nil)
+	body, contentType, err := requestBody(h)
+	if err != nil {
+		log.Printf("[ERROR] prepare request body %v", err)
+		return
+	}
+	method := h.Method
+	if method == "" && body != nil {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequest(method, h.URL, body)
 	if err != nil {
 		log.Printf("[ERROR] prepare request %v", err)
 		return
@@ -37,6 +53,16 @@ func (d *Dialer) httpCall(ctx context.Context, h *types.Host) (response types.Ht
 		GotFirstResponseByte: func() { response.TTFB = time.Since(start) },
 	}))
 
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if h.Auth != nil {
+		if h.Auth.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+h.Auth.Token)
+		} else if h.Auth.Username != "" || h.Auth.Password != "" {
+			req.SetBasicAuth(h.Auth.Username, h.Auth.Password)
+		}
+	}
 	for key, value := range h.Headers {
 		req.Header.Set(key, value)
 	}
@@ -87,17 +113,39 @@ func (d *Dialer) httpCall(ctx context.Context, h *types.Host) (response types.Ht
 
 	if tlsState != nil && len(tlsState.PeerCertificates) > 0 {
 		response.SSLCertExpiry = &tlsState.PeerCertificates[0].NotAfter
+		response.Certificate = certificate(tlsState, req.URL.Hostname())
 	}
 
-	b, err := io.ReadAll(resp.Body)
+	response.Headers = resp.Header
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
 	if err != nil {
 		log.Printf("[ERROR] read body %v", err)
 		return
 	}
-	if len(b) < 1024 {
-		response.Bytes = b
-	}
+	response.Bytes = b
 	response.OK = true
 
 	return
 }
+
+// requestBody - returns the request body and its content type from the inline body, body file or form values
+func requestBody(h *types.Host) (io.Reader, string, error) {
+	switch {
+	case len(h.Form) > 0:
+		values := url.Values{}
+		for key, value := range h.Form {
+			values.Set(key, value)
+		}
+		return strings.NewReader(values.Encode()), "application/x-www-form-urlencoded", nil
+	case h.Body != nil:
+		return strings.NewReader(*h.Body), "", nil
+	case h.BodyFile != nil:
+		b, err := os.ReadFile(*h.BodyFile)
+		if err != nil {
+			return nil, "", err
+		}
+		return bytes.NewReader(b), "", nil
+	}
+	return nil, "", nil
+}
@@ -0,0 +1,115 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/exelban/JAM/types"
+)
+
+// tcpCall opens a raw tcp connection to the host. If payload is defined it will be sent after connect.
+// If payload or banner is defined the response will be read and compared with the banner.
+func (d *Dialer) tcpCall(ctx context.Context, h *types.Host) (response types.HttpResponse) {
+	addr := strings.TrimPrefix(h.URL, "tcp://")
+	addr = strings.TrimSuffix(addr, "/")
+
+	timeout := time.Second * 30
+	if h.TimeoutInterval != nil {
+		timeout = *h.TimeoutInterval
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	response.Timestamp = time.Now()
+
+	dialer := &net.Dialer{}
+	startTime := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	response.Connect = time.Since(startTime)
+	if err != nil {
+		response.Time = time.Since(startTime)
+		response.Body = err.Error()
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			response.Code = 522
+		} else {
+			response.Code = 523
+		}
+		return
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if h.Payload != nil {
+		if _, err := conn.Write([]byte(*h.Payload)); err != nil {
+			response.Time = time.Since(startTime)
+			response.Body = err.Error()
+			response.Code = 521
+			return
+		}
+	}
+
+	if h.Payload != nil || h.Banner != nil {
+		buf, err := readBanner(conn, h.Banner)
+		response.TTFB = time.Since(startTime)
+		if err != nil {
+			response.Time = time.Since(startTime)
+			response.Body = err.Error()
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				response.Code = 522
+			} else {
+				response.Code = 521
+			}
+			return
+		}
+		response.Bytes = buf
+	}
+	response.Time = time.Since(startTime)
+
+	if h.Banner != nil && !strings.HasPrefix(string(response.Bytes), *h.Banner) {
+		response.Code = http.StatusExpectationFailed
+		response.Body = fmt.Sprintf("unexpected banner: %q", string(response.Bytes))
+		return
+	}
+
+	response.Code = http.StatusOK
+	response.OK = true
+
+	return
+}
+
+// tcpReadLimit - maximal size of the response read from the tcp connection, the longer banner extends it
+const tcpReadLimit = 1024
+
+// readBanner - reads the response until the banner is received, the mismatch is found, the connection is closed or the deadline is reached.
+// The banner could come in several packets. Without the banner only the first packet is read
+func readBanner(conn net.Conn, banner *string) ([]byte, error) {
+	limit := tcpReadLimit
+	if banner != nil && len(*banner) > limit {
+		limit = len(*banner)
+	}
+
+	buf := make([]byte, 0, limit)
+	for {
+		n, err := conn.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err != nil {
+			// the partial response is compared with the banner, so the mismatch is reported instead of the timeout
+			if errors.Is(err, io.EOF) || len(buf) > 0 {
+				return buf, nil
+			}
+			return nil, err
+		}
+		if banner == nil || len(buf) == cap(buf) || len(buf) >= len(*banner) || !strings.HasPrefix(*banner, string(buf)) {
+			return buf, nil
+		}
+	}
+}
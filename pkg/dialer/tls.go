@@ -0,0 +1,110 @@
+package dialer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/exelban/JAM/types"
+)
+
+// tlsCall makes a TLS handshake with the host and validates the certificate chain.
+// The url format is tls://host[:port], if port is not provided 443 will be used.
+func (d *Dialer) tlsCall(ctx context.Context, h *types.Host) (response types.HttpResponse) {
+	addr := strings.TrimSuffix(strings.TrimPrefix(h.URL, "tls://"), "/")
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+		addr = net.JoinHostPort(addr, "443")
+	}
+
+	timeout := time.Second * 30
+	if h.TimeoutInterval != nil {
+		timeout = *h.TimeoutInterval
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	response.Timestamp = time.Now()
+
+	startTime := time.Now()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	response.Connect = time.Since(startTime)
+	if err != nil {
+		response.Time = time.Since(startTime)
+		response.Body = err.Error()
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			response.Code = 522
+		} else {
+			response.Code = 523
+		}
+		return
+	}
+	defer conn.Close()
+
+	// the chain is verified manually to be able to report the details of invalid certificates
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+	handshakeStart := time.Now()
+	err = tlsConn.HandshakeContext(ctx)
+	response.TLSHandshake = time.Since(handshakeStart)
+	response.Time = time.Since(startTime)
+	if err != nil {
+		response.Body = err.Error()
+		response.Code = 525
+		return
+	}
+
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		response.Body = "no peer certificates"
+		response.Code = 526
+		return
+	}
+	response.SSLCertExpiry = &state.PeerCertificates[0].NotAfter
+	response.Certificate = certificate(&state, host)
+
+	if !response.Certificate.ChainValid {
+		response.Body = response.Certificate.ChainError
+		response.Code = 526
+		return
+	}
+
+	response.Code = http.StatusOK
+	response.OK = true
+
+	return
+}
+
+// certificate - returns the details of the leaf certificate and verifies the chain against the system roots
+func certificate(state *tls.ConnectionState, host string) *types.Certificate {
+	leaf := state.PeerCertificates[0]
+	c := &types.Certificate{
+		Subject:  leaf.Subject.String(),
+		Issuer:   leaf.Issuer.String(),
+		DNSNames: leaf.DNSNames,
+		NotAfter: leaf.NotAfter,
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Intermediates: intermediates,
+	}); err != nil {
+		c.ChainError = fmt.Sprintf("invalid certificate: %s", err)
+	} else {
+		c.ChainValid = true
+	}
+
+	return c
+}
@@ -0,0 +1,220 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/exelban/JAM/types"
+)
+
+// Metrics - collects the counters of the checks and store operations and exports them in the prometheus text format
+type Metrics struct {
+	checks    map[string]uint64
+	failures  map[string]uint64
+	incidents map[string]uint64
+	store     map[string]*duration
+
+	mu sync.RWMutex
+}
+
+type duration struct {
+	sum   time.Duration
+	count uint64
+}
+
+// Host - current state of the host that is exported as gauges
+type Host struct {
+	ID     string
+	Name   string
+	Group  string
+	Type   types.HostType
+	Status types.StatusType
+
+	Response *types.HttpResponse
+}
+
+// Dialer - usage of the dialer semaphore
+type Dialer struct {
+	InUse int
+	Max   int
+}
+
+var statuses = []types.StatusType{types.UP, types.DEGRADED, types.DOWN, types.UNREACHABLE, types.FLAPPING, types.MAINTENANCE, types.Unknown}
+
+func New() *Metrics {
+	return &Metrics{
+		checks:    make(map[string]uint64),
+		failures:  make(map[string]uint64),
+		incidents: make(map[string]uint64),
+		store:     make(map[string]*duration),
+	}
+}
+
+// Check - counts the check of the host, failed checks are counted separately
+func (m *Metrics) Check(hostID string, ok bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checks[hostID]++
+	if !ok {
+		m.failures[hostID]++
+	}
+}
+
+// Incident - counts the opened incident for the host
+func (m *Metrics) Incident(hostID string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.incidents[hostID]++
+}
+
+// Store - observes the duration of the store operation
+func (m *Metrics) Store(operation string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.store[operation]; !ok {
+		m.store[operation] = &duration{}
+	}
+	m.store[operation].sum += d
+	m.store[operation].count++
+}
+
+// Write - writes all metrics in the prometheus text format
+func (m *Metrics) Write(w io.Writer, hosts []Host, dialer Dialer) error {
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].ID < hosts[j].ID
+	})
+
+	b := bufio.NewWriter(w)
+
+	header(b, "jam_host_up", "gauge", "Whether the host is up (1) or not (0).")
+	for _, h := range hosts {
+		v := 0.0
+		if h.Status == types.UP {
+			v = 1
+		}
+		sample(b, "jam_host_up", h.labels(), v)
+	}
+
+	header(b, "jam_host_status", "gauge", "Current status of the host, 1 for the active status.")
+	for _, h := range hosts {
+		for _, s := range statuses {
+			v := 0.0
+			if h.Status == s || (h.Status == "" && s == types.Unknown) {
+				v = 1
+			}
+			sample(b, "jam_host_status", h.labels("status", string(s)), v)
+		}
+	}
+
+	header(b, "jam_host_response_time_seconds", "gauge", "Duration of the last check.")
+	for _, h := range hosts {
+		if h.Response != nil {
+			sample(b, "jam_host_response_time_seconds", h.labels(), h.Response.Time.Seconds())
+		}
+	}
+
+	header(b, "jam_host_response_phase_seconds", "gauge", "Duration of the last check phases: dns, connect, tls and ttfb.")
+	for _, h := range hosts {
+		if h.Response == nil {
+			continue
+		}
+		for _, p := range []struct {
+			name  string
+			value time.Duration
+		}{
+			{"dns", h.Response.DNS},
+			{"connect", h.Response.Connect},
+			{"tls", h.Response.TLSHandshake},
+			{"ttfb", h.Response.TTFB},
+		} {
+			sample(b, "jam_host_response_phase_seconds", h.labels("phase", p.name), p.value.Seconds())
+		}
+	}
+
+	header(b, "jam_host_ssl_expiry_timestamp_seconds", "gauge", "Expiry time of the host certificate.")
+	for _, h := range hosts {
+		if h.Response != nil && h.Response.SSLCertExpiry != nil {
+			sample(b, "jam_host_ssl_expiry_timestamp_seconds", h.labels(), float64(h.Response.SSLCertExpiry.Unix()))
+		}
+	}
+
+	m.mu.RLock()
+	for _, c := range []struct {
+		name   string
+		help   string
+		values map[string]uint64
+	}{
+		{"jam_host_checks_total", "Number of checks of the host.", m.checks},
+		{"jam_host_failures_total", "Number of failed checks of the host.", m.failures},
+		{"jam_host_incidents_total", "Number of incidents opened for the host.", m.incidents},
+	} {
+		header(b, c.name, "counter", c.help)
+		for _, h := range hosts {
+			sample(b, c.name, h.labels(), float64(c.values[h.ID]))
+		}
+	}
+
+	operations := make([]string, 0, len(m.store))
+	for op := range m.store {
+		operations = append(operations, op)
+	}
+	sort.Strings(operations)
+	header(b, "jam_store_operation_duration_seconds", "summary", "Duration of the store operations.")
+	for _, op := range operations {
+		labels := fmt.Sprintf(`operation="%s"`, escape(op))
+		sample(b, "jam_store_operation_duration_seconds_sum", labels, m.store[op].sum.Seconds())
+		sample(b, "jam_store_operation_duration_seconds_count", labels, float64(m.store[op].count))
+	}
+	m.mu.RUnlock()
+
+	header(b, "jam_dialer_connections_in_use", "gauge", "Number of the dialer connections in use.")
+	sample(b, "jam_dialer_connections_in_use", "", float64(dialer.InUse))
+	header(b, "jam_dialer_connections_max", "gauge", "Max number of the dialer connections.")
+	sample(b, "jam_dialer_connections_max", "", float64(dialer.Max))
+
+	return b.Flush()
+}
+
+func (h *Host) labels(extra ...string) string {
+	list := []string{
+		fmt.Sprintf(`id="%s"`, escape(h.ID)),
+		fmt.Sprintf(`name="%s"`, escape(h.Name)),
+		fmt.Sprintf(`group="%s"`, escape(h.Group)),
+		fmt.Sprintf(`type="%s"`, escape(string(h.Type))),
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		list = append(list, fmt.Sprintf(`%s="%s"`, extra[i], escape(extra[i+1])))
+	}
+	return strings.Join(list, ",")
+}
+
+func header(w io.Writer, name, typ, help string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+func sample(w io.Writer, name, labels string, value float64) {
+	if labels == "" {
+		_, _ = fmt.Fprintf(w, "%s %g\n", name, value)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "%s{%s} %g\n", name, labels, value)
+}
+func escape(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/exelban/JAM/store"
+	"github.com/exelban/JAM/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_Write(t *testing.T) {
+	m := New()
+	m.Check("host-1", true)
+	m.Check("host-1", false)
+	m.Incident("host-1")
+	m.Store("AddResponse", time.Millisecond*500)
+
+	expiry := time.Unix(1700000000, 0)
+	hosts := []Host{
+		{
+			ID:     "host-1",
+			Name:   `name "1"`,
+			Group:  "group",
+			Type:   types.HttpType,
+			Status: types.DOWN,
+			Response: &types.HttpResponse{
+				Time:          time.Millisecond * 250,
+				DNS:           time.Millisecond * 10,
+				SSLCertExpiry: &expiry,
+			},
+		},
+		{
+			ID:     "host-2",
+			Name:   "name-2",
+			Type:   types.TCPType,
+			Status: types.UP,
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, m.Write(&buf, hosts, Dialer{InUse: 2, Max: 128}))
+	out := buf.String()
+
+	labels := `id="host-1",name="name \"1\"",group="group",type="http"`
+	require.Contains(t, out, "# TYPE jam_host_up gauge\n")
+	require.Contains(t, out, `jam_host_up{`+labels+`} 0`)
+	require.Contains(t, out, `jam_host_up{id="host-2",name="name-2",group="",type="tcp"} 1`)
+	require.Contains(t, out, `jam_host_status{`+labels+`,status="down"} 1`)
+	require.Contains(t, out, `jam_host_status{`+labels+`,status="up"} 0`)
+	require.Contains(t, out, `jam_host_response_time_seconds{`+labels+`} 0.25`)
+	require.Contains(t, out, `jam_host_response_phase_seconds{`+labels+`,phase="dns"} 0.01`)
+	require.Contains(t, out, `jam_host_ssl_expiry_timestamp_seconds{`+labels+`} 1.7e+09`)
+	require.Contains(t, out, `jam_host_checks_total{`+labels+`} 2`)
+	require.Contains(t, out, `jam_host_failures_total{`+labels+`} 1`)
+	require.Contains(t, out, `jam_host_incidents_total{`+labels+`} 1`)
+	require.Contains(t, out, `jam_store_operation_duration_seconds_sum{operation="AddResponse"} 0.5`)
+	require.Contains(t, out, `jam_store_operation_duration_seconds_count{operation="AddResponse"} 1`)
+	require.Contains(t, out, "jam_dialer_connections_in_use 2\n")
+	require.Contains(t, out, "jam_dialer_connections_max 128\n")
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	s := Store(store.NewMemory(ctx), m)
+
+	require.NoError(t, s.AddResponse(ctx, "test", &types.HttpResponse{Timestamp: time.Now()}))
+	_, err := s.FindResponses(ctx, "test")
+	require.NoError(t, err)
+
+	require.Equal(t, uint64(1), m.store["AddResponse"].count)
+	require.Equal(t, uint64(1), m.store["FindResponses"].count)
+}
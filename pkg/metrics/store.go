@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/exelban/JAM/store"
+	"github.com/exelban/JAM/types"
+)
+
+// Store - wraps the store and observes the duration of each operation
+func Store(s store.Interface, m *Metrics) store.Interface {
+	return &instrumented{
+		Interface: s,
+		metrics:   m,
+	}
+}
+
+type instrumented struct {
+	store.Interface
+	metrics *Metrics
+}
+
+func (s *instrumented) observe(operation string, start time.Time) {
+	s.metrics.Store(operation, time.Since(start))
+}
+
+func (s *instrumented) AddResponse(ctx context.Context, hostID string, r *types.HttpResponse) error {
+	defer s.observe("AddResponse", time.Now())
+	return s.Interface.AddResponse(ctx, hostID, r)
+}
+func (s *instrumented) DeleteResponse(ctx context.Context, hostID string, keys []time.Time) error {
+	defer s.observe("DeleteResponse", time.Now())
+	return s.Interface.DeleteResponse(ctx, hostID, keys)
+}
+func (s *instrumented) FindResponses(ctx context.Context, hostID string) ([]*types.HttpResponse, error) {
+	defer s.observe("FindResponses", time.Now())
+	return s.Interface.FindResponses(ctx, hostID)
+}
+func (s *instrumented) FindResponsesRange(ctx context.Context, hostID string, from, to time.Time, limit int) ([]*types.HttpResponse, error) {
+	defer s.observe("FindResponsesRange", time.Now())
+	return s.Interface.FindResponsesRange(ctx, hostID, from, to, limit)
+}
+func (s *instrumented) LastResponses(ctx context.Context, hostID string, n int) ([]*types.HttpResponse, error) {
+	defer s.observe("LastResponses", time.Now())
+	return s.Interface.LastResponses(ctx, hostID, n)
+}
+func (s *instrumented) LastResponse(ctx context.Context, hostID string) (*types.HttpResponse, error) {
+	defer s.observe("LastResponse", time.Now())
+	return s.Interface.LastResponse(ctx, hostID)
+}
+
+func (s *instrumented) Hosts(ctx context.Context) ([]string, error) {
+	defer s.observe("Hosts", time.Now())
+	return s.Interface.Hosts(ctx)
+}
+
+func (s *instrumented) AddIncident(ctx context.Context, hostID string, e *types.Incident) error {
+	defer s.observe("AddIncident", time.Now())
+	return s.Interface.AddIncident(ctx, hostID, e)
+}
+func (s *instrumented) EndIncident(ctx context.Context, hostID string, eventID int, ts time.Time) error {
+	defer s.observe("EndIncident", time.Now())
+	return s.Interface.EndIncident(ctx, hostID, eventID, ts)
+}
+func (s *instrumented) AddIncidentEvent(ctx context.Context, hostID string, incidentID int, e *types.IncidentEvent) error {
+	defer s.observe("AddIncidentEvent", time.Now())
+	return s.Interface.AddIncidentEvent(ctx, hostID, incidentID, e)
+}
+func (s *instrumented) DeleteIncident(ctx context.Context, hostID string, eventID int) error {
+	defer s.observe("DeleteIncident", time.Now())
+	return s.Interface.DeleteIncident(ctx, hostID, eventID)
+}
+func (s *instrumented) FindIncidents(ctx context.Context, hostID string, skip, limit int) ([]*types.Incident, error) {
+	defer s.observe("FindIncidents", time.Now())
+	return s.Interface.FindIncidents(ctx, hostID, skip, limit)
+}
+func (s *instrumented) FindIncident(ctx context.Context, hostID string, id int) (*types.Incident, error) {
+	defer s.observe("FindIncident", time.Now())
+	return s.Interface.FindIncident(ctx, hostID, id)
+}
+
+func (s *instrumented) AddAnnouncement(ctx context.Context, a *types.Announcement) error {
+	defer s.observe("AddAnnouncement", time.Now())
+	return s.Interface.AddAnnouncement(ctx, a)
+}
+func (s *instrumented) AddAnnouncementUpdate(ctx context.Context, id int, e *types.IncidentEvent) error {
+	defer s.observe("AddAnnouncementUpdate", time.Now())
+	return s.Interface.AddAnnouncementUpdate(ctx, id, e)
+}
+func (s *instrumented) EndAnnouncement(ctx context.Context, id int, ts time.Time) error {
+	defer s.observe("EndAnnouncement", time.Now())
+	return s.Interface.EndAnnouncement(ctx, id, ts)
+}
+func (s *instrumented) DeleteAnnouncement(ctx context.Context, id int) error {
+	defer s.observe("DeleteAnnouncement", time.Now())
+	return s.Interface.DeleteAnnouncement(ctx, id)
+}
+func (s *instrumented) FindAnnouncements(ctx context.Context, skip, limit int) ([]*types.Announcement, error) {
+	defer s.observe("FindAnnouncements", time.Now())
+	return s.Interface.FindAnnouncements(ctx, skip, limit)
+}
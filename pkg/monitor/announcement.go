@@ -0,0 +1,220 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/exelban/JAM/types"
+)
+
+// announcementHistory - how long the resolved announcement is shown in the history
+const announcementHistory = time.Hour * 24 * 30
+
+// Announcements - returns the announcements from the config and the store, the latest first
+func (m *Monitor) Announcements(ctx context.Context) ([]*types.Announcement, error) {
+	list, err := m.Store.FindAnnouncements(ctx, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get announcements: %w", err)
+	}
+
+	m.mu.RLock()
+	for _, a := range m.announcements {
+		c := *a
+		list = append(list, &c)
+	}
+	m.mu.RUnlock()
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].StartTS.After(list[j].StartTS)
+	})
+	processAnnouncements(list)
+
+	return list, nil
+}
+
+// Announcement - returns the announcement by id, the announcements from the config have the negative ids
+func (m *Monitor) Announcement(ctx context.Context, id int) (*types.Announcement, error) {
+	if a := m.configAnnouncement(id); a != nil {
+		processAnnouncements([]*types.Announcement{a})
+		return a, nil
+	}
+
+	list, err := m.Store.FindAnnouncements(ctx, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get announcements: %w", err)
+	}
+	for _, a := range list {
+		if a.ID == id {
+			processAnnouncements([]*types.Announcement{a})
+			return a, nil
+		}
+	}
+	return nil, types.ErrAnnouncementNotFound
+}
+
+// configAnnouncement - returns the copy of the announcement from the config by id, nil if there is no such announcement
+func (m *Monitor) configAnnouncement(id int) *types.Announcement {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, a := range m.announcements {
+		if a.ID == id {
+			c := *a
+			return &c
+		}
+	}
+	return nil
+}
+
+// Announce - validates and saves the new announcement, it starts now if the start is not defined
+func (m *Monitor) Announce(ctx context.Context, a *types.Announcement) error {
+	if a.StartTS.IsZero() {
+		a.StartTS = time.Now()
+	}
+	if err := a.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", types.ErrAnnouncementInvalid, err)
+	}
+	a.Config = false
+
+	m.mu.RLock()
+	exists := func(hosts, groups []string) bool {
+		for _, w := range m.watchers {
+			if w.host.Matches(hosts, groups) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, ref := range a.Hosts {
+		if !exists([]string{ref}, nil) {
+			m.mu.RUnlock()
+			return fmt.Errorf("%w: unknown host %s", types.ErrAnnouncementInvalid, ref)
+		}
+	}
+	for _, group := range a.Groups {
+		if !exists(nil, []string{group}) {
+			m.mu.RUnlock()
+			return fmt.Errorf("%w: unknown group %s", types.ErrAnnouncementInvalid, group)
+		}
+	}
+	m.mu.RUnlock()
+
+	if err := m.Store.AddAnnouncement(ctx, a); err != nil {
+		return fmt.Errorf("failed to save announcement: %w", err)
+	}
+	log.Printf("[INFO] announcement %d: %s", a.ID, a.Title)
+	m.touch()
+
+	return nil
+}
+
+// AddAnnouncementUpdate - adds the update to the announcement
+func (m *Monitor) AddAnnouncementUpdate(ctx context.Context, id int, author, message string) (*types.Announcement, error) {
+	if m.configAnnouncement(id) != nil {
+		return nil, types.ErrAnnouncementReadOnly
+	}
+	if err := m.Store.AddAnnouncementUpdate(ctx, id, &types.IncidentEvent{
+		Type:    types.NoteEvent,
+		Author:  author,
+		Message: message,
+		Public:  true,
+		TS:      time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+	m.touch()
+	return m.Announcement(ctx, id)
+}
+
+// ResolveAnnouncement - marks the announcement as resolved, the message is added as the last update
+func (m *Monitor) ResolveAnnouncement(ctx context.Context, id int, author, message string) (*types.Announcement, error) {
+	a, err := m.Announcement(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.Config {
+		return nil, types.ErrAnnouncementReadOnly
+	}
+	if a.EndTS != nil && !a.EndTS.After(time.Now()) {
+		return nil, types.ErrAnnouncementResolved
+	}
+
+	if message != "" {
+		if _, err := m.AddAnnouncementUpdate(ctx, id, author, message); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.Store.EndAnnouncement(ctx, id, time.Now()); err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] announcement %d resolved", id)
+	m.touch()
+
+	return m.Announcement(ctx, id)
+}
+
+// DeleteAnnouncement - removes the announcement created via api
+func (m *Monitor) DeleteAnnouncement(ctx context.Context, id int) error {
+	if m.configAnnouncement(id) != nil {
+		return types.ErrAnnouncementReadOnly
+	}
+	if err := m.Store.DeleteAnnouncement(ctx, id); err != nil {
+		return err
+	}
+	m.touch()
+	return nil
+}
+
+// filterAnnouncements - returns the active and recently resolved announcements that match the filter
+func (m *Monitor) filterAnnouncements(ctx context.Context, match func(a *types.Announcement) bool) ([]*types.Announcement, []*types.Announcement, error) {
+	list, err := m.Announcements(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	var active, resolved []*types.Announcement
+	for _, a := range list {
+		if !match(a) {
+			continue
+		}
+		switch {
+		case a.Active(now):
+			active = append(active, a)
+		case a.EndTS != nil && !a.EndTS.After(now) && now.Sub(*a.EndTS) < announcementHistory:
+			resolved = append(resolved, a)
+		}
+	}
+
+	// the names of the affected hosts and groups are shown on the page, the hidden hosts are not listed
+	m.mu.RLock()
+	for _, a := range slices.Concat(active, resolved) {
+		a.Affected = append([]string{}, a.Groups...)
+		for _, w := range m.watchers {
+			if !w.host.Hidden && w.host.Matches(a.Hosts, nil) {
+				name := w.host.ID
+				if w.host.Name != nil {
+					name = *w.host.Name
+				}
+				a.Affected = append(a.Affected, name)
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	return active, resolved, nil
+}
+
+func processAnnouncements(list []*types.Announcement) {
+	for _, a := range list {
+		a.Start = a.StartTS.Format("2006-01-02 15:04:05")
+		a.End = ""
+		if a.EndTS != nil {
+			a.End = a.EndTS.Format("2006-01-02 15:04:05")
+		}
+	}
+}
@@ -2,9 +2,18 @@ package monitor
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/exelban/JAM/pkg/dialer"
+	"github.com/exelban/JAM/pkg/metrics"
 	"github.com/exelban/JAM/pkg/notify"
 	"github.com/exelban/JAM/store"
 	"github.com/exelban/JAM/types"
@@ -12,12 +21,17 @@ import (
 
 // Monitor - main service which track the hosts liveness
 type Monitor struct {
-	Store store.Interface
+	Store   store.Interface
+	Metrics *metrics.Metrics
 
 	dialer *dialer.Dialer
 	notify *notify.Notify
 
-	watchers map[string]*watcher
+	watchers      map[string]*watcher
+	announcements []*types.Announcement // announcements from the config
+
+	version  atomic.Uint64 // incremented on every change of the stats
+	modified atomic.Int64  // time of the last change of the stats in unix nanoseconds
 
 	mu   sync.RWMutex
 	ctx  context.Context
@@ -42,6 +56,7 @@ func (m *Monitor) Run(cfg *types.Cfg) error {
 			return err
 		}
 		m.notify = n
+		m.announcements = cfg.Announcements
 	}
 	m.mu.Unlock()
 
@@ -55,8 +70,7 @@ func (m *Monitor) Run(cfg *types.Cfg) error {
 				return err
 			}
 		} else {
-			w.cancel()
-			go w.run(m.ctx)
+			w.start(m.ctx)
 		}
 	}
 
@@ -75,6 +89,7 @@ func (m *Monitor) Run(cfg *types.Cfg) error {
 		}
 	}
 	m.mu.Unlock()
+	m.touch()
 
 	return nil
 }
@@ -82,12 +97,17 @@ func (m *Monitor) Run(cfg *types.Cfg) error {
 // add - create a watcher for host
 func (m *Monitor) add(host *types.Host) error {
 	w := &watcher{
-		dialer: m.dialer,
-		notify: m.notify,
-		store:  m.Store,
-		host:   host,
+		dialer:  m.dialer,
+		notify:  m.notify,
+		store:   m.Store,
+		metrics: m.Metrics,
+		host:    host,
+		ctx:     m.ctx,
+
+		statusOf: m.status,
+		changed:  m.touch,
 	}
-	go w.run(m.ctx)
+	w.start(m.ctx)
 
 	m.mu.Lock()
 	m.watchers[host.ID] = w
@@ -95,3 +115,245 @@ func (m *Monitor) add(host *types.Host) error {
 
 	return nil
 }
+
+// HostStatus - the host with its current status
+type HostStatus struct {
+	Host      *types.Host
+	Status    types.StatusType
+	LastCheck time.Time
+}
+
+// Hosts - returns all monitored hosts with the current status sorted by the config order
+func (m *Monitor) Hosts() []HostStatus {
+	list := []HostStatus{}
+
+	m.mu.RLock()
+	for _, w := range m.watchers {
+		list = append(list, w.hostStatus())
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Host.Index < list[j].Host.Index
+	})
+
+	return list
+}
+
+// Host - returns the host with the current status by id
+func (m *Monitor) Host(id string) (HostStatus, error) {
+	m.mu.RLock()
+	w, ok := m.watchers[id]
+	m.mu.RUnlock()
+	if !ok {
+		return HostStatus{}, types.ErrHostNotFound
+	}
+	return w.hostStatus(), nil
+}
+
+// Responses - returns the host responses in the time range, zero from or to means no limit
+func (m *Monitor) Responses(ctx context.Context, id string, from, to time.Time, skip, limit int) ([]*types.HttpResponse, error) {
+	if _, err := m.Host(id); err != nil {
+		return nil, err
+	}
+
+	n := 0
+	if limit > 0 {
+		n = max(skip, 0) + limit
+	}
+	list, err := m.Store.FindResponsesRange(ctx, id, from, to, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+
+	if skip > 0 {
+		if len(list) < skip {
+			return []*types.HttpResponse{}, nil
+		}
+		list = list[skip:]
+	}
+
+	return list, nil
+}
+
+// Incidents - returns the host incidents starting from the latest one
+func (m *Monitor) Incidents(ctx context.Context, id string, skip, limit int) ([]*types.Incident, error) {
+	if _, err := m.Host(id); err != nil {
+		return nil, err
+	}
+
+	incidents, err := m.Store.FindIncidents(ctx, id, skip, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incidents: %w", err)
+	}
+	processIncidents(incidents)
+
+	return incidents, nil
+}
+
+// Incident - returns the host incident by id
+func (m *Monitor) Incident(ctx context.Context, id string, incidentID int) (*types.Incident, error) {
+	if _, err := m.Host(id); err != nil {
+		return nil, err
+	}
+
+	incident, err := m.Store.FindIncident(ctx, id, incidentID)
+	if err != nil {
+		if errors.Is(err, types.ErrIncidentNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+	processIncidents([]*types.Incident{incident})
+
+	return incident, nil
+}
+
+// Acknowledge - acknowledges the open incident, the reminders and escalations of the incident are stopped.
+// The incident acknowledged before is returned as is.
+func (m *Monitor) Acknowledge(ctx context.Context, id string, incidentID int, author, message string) (*types.Incident, error) {
+	err := m.incidentEvent(ctx, id, incidentID, types.IncidentEvent{
+		Type:    types.AckEvent,
+		Author:  author,
+		Message: message,
+	})
+	if err != nil && !errors.Is(err, types.ErrIncidentAcknowledged) {
+		return nil, err
+	}
+	if err == nil {
+		log.Printf("[INFO] %s: incident %d acknowledged by %s", id, incidentID, author)
+	}
+
+	return m.Incident(ctx, id, incidentID)
+}
+
+// AddIncidentUpdate - adds the note to the incident timeline, public notes are shown on the status page
+func (m *Monitor) AddIncidentUpdate(ctx context.Context, id string, incidentID int, author, message string, public bool) (*types.Incident, error) {
+	if _, err := m.Incident(ctx, id, incidentID); err != nil {
+		return nil, err
+	}
+
+	if err := m.incidentEvent(ctx, id, incidentID, types.IncidentEvent{
+		Type:    types.NoteEvent,
+		Author:  author,
+		Message: message,
+		Public:  public,
+	}); err != nil {
+		return nil, err
+	}
+
+	return m.Incident(ctx, id, incidentID)
+}
+
+// incidentEvent - appends the event to the incident timeline, the open incident of the watcher is updated as well
+func (m *Monitor) incidentEvent(ctx context.Context, id string, incidentID int, e types.IncidentEvent) error {
+	m.mu.RLock()
+	w, ok := m.watchers[id]
+	m.mu.RUnlock()
+	if !ok {
+		return types.ErrHostNotFound
+	}
+
+	e.TS = time.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := m.Store.AddIncidentEvent(ctx, id, incidentID, &e); err != nil {
+		return err
+	}
+	if w.incident != nil && w.incident.ID == incidentID {
+		w.incident.Timeline = append(w.incident.Timeline, e)
+	}
+	w.snapshot.resetIncidents()
+	m.touch()
+
+	return nil
+}
+
+// Version - returns the version of the stats and the time of the last change.
+// The version is changed on every status, incident and announcement update and when the charts roll over, so the rendered pages can be cached until then.
+func (m *Monitor) Version() (uint64, time.Time) {
+	modified := m.modified.Load()
+	if modified == 0 {
+		return m.version.Load(), time.Time{}
+	}
+	return m.version.Load(), time.Unix(0, modified)
+}
+
+// touch - marks the stats as changed
+func (m *Monitor) touch() {
+	m.modified.Store(time.Now().UnixNano())
+	m.version.Add(1)
+}
+
+// status - returns the current status of the host by id
+func (m *Monitor) status(id string) types.StatusType {
+	m.mu.RLock()
+	w, ok := m.watchers[id]
+	m.mu.RUnlock()
+	if !ok {
+		return types.Unknown
+	}
+	return w.hostStatus().Status
+}
+
+// Push - registers a check-in for the push host with the provided token
+func (m *Monitor) Push(token string, ok bool, msg string) error {
+	var w *watcher
+	m.mu.RLock()
+	for _, v := range m.watchers {
+		if v.host.Type == types.PushType && subtle.ConstantTimeCompare([]byte(v.host.Token), []byte(token)) == 1 {
+			w = v
+			break
+		}
+	}
+	m.mu.RUnlock()
+
+	if w == nil {
+		return types.ErrHostNotFound
+	}
+	w.push(ok, msg)
+
+	return nil
+}
+
+// WriteMetrics - writes the metrics of all hosts in the prometheus text format. The hidden hosts are skipped
+// and the url is not used as the name of the host if hideURL is set
+func (m *Monitor) WriteMetrics(w io.Writer, hideURL bool) error {
+	hosts := []metrics.Host{}
+
+	m.mu.RLock()
+	for _, wt := range m.watchers {
+		if wt.host.Hidden {
+			continue
+		}
+		wt.mu.RLock()
+		h := metrics.Host{
+			ID:       wt.host.ID,
+			Type:     wt.host.Type,
+			Status:   wt.status,
+			Response: wt.lastResponse,
+		}
+		wt.mu.RUnlock()
+		if !hideURL {
+			h.Name = wt.host.SecureURL()
+		}
+		if wt.host.Name != nil {
+			h.Name = *wt.host.Name
+		}
+		if wt.host.Group != nil {
+			h.Group = *wt.host.Group
+		}
+		hosts = append(hosts, h)
+	}
+	var dialer metrics.Dialer
+	if m.dialer != nil {
+		dialer.InUse, dialer.Max = m.dialer.Usage()
+	}
+	m.mu.RUnlock()
+
+	if m.Metrics == nil {
+		return metrics.New().Write(w, hosts, dialer)
+	}
+	return m.Metrics.Write(w, hosts, dialer)
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/exelban/JAM/pkg/notify"
 	"github.com/exelban/JAM/store"
 	"github.com/exelban/JAM/types"
 	"github.com/stretchr/testify/require"
@@ -280,3 +282,207 @@ func srv(timeout time.Duration) (*httptest.Server, *atomic.Value, func()) {
 
 	return ts, &status, shutdown
 }
+
+func TestMonitor_Acknowledge(t *testing.T) {
+	ctx := context.Background()
+	m := Monitor{
+		Store:    store.NewMemory(ctx),
+		watchers: map[string]*watcher{},
+	}
+	w := &watcher{
+		notify: &notify.Notify{},
+		store:  m.Store,
+		host: &types.Host{
+			ID:               "host",
+			URL:              "http://host",
+			Conditions:       &types.Success{Code: []int{200}},
+			SuccessThreshold: 1,
+			FailureThreshold: 1,
+		},
+		ctx: ctx,
+	}
+	m.watchers[w.host.ID] = w
+
+	w.process(&types.HttpResponse{Code: 200})
+	w.process(&types.HttpResponse{Code: 500})
+	require.NotNil(t, w.incident)
+	id := w.incident.ID
+
+	_, err := m.Acknowledge(ctx, "unknown", id, "john", "")
+	require.ErrorIs(t, err, types.ErrHostNotFound)
+	_, err = m.Acknowledge(ctx, w.host.ID, id+1, "john", "")
+	require.ErrorIs(t, err, types.ErrIncidentNotFound)
+
+	// the concurrent acknowledgements add the single event
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := m.Acknowledge(ctx, w.host.ID, id, "john", "on it")
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	incident, err := m.Incident(ctx, w.host.ID, id)
+	require.NoError(t, err)
+	require.Equal(t, "john", incident.Acknowledgement().Author)
+	require.Equal(t, "on it", incident.Acknowledgement().Message)
+	require.NotNil(t, w.incident.Acknowledgement())
+	acks := 0
+	for _, e := range incident.Timeline {
+		if e.Type == types.AckEvent {
+			acks++
+		}
+	}
+	require.Equal(t, 1, acks)
+
+	incident, err = m.Acknowledge(ctx, w.host.ID, id, "bob", "")
+	require.NoError(t, err)
+	require.Equal(t, "john", incident.Acknowledgement().Author)
+
+	incident, err = m.AddIncidentUpdate(ctx, w.host.ID, id, "john", "database is restarting", true)
+	require.NoError(t, err)
+	_, err = m.AddIncidentUpdate(ctx, w.host.ID, id, "john", "wrong password in the config", false)
+	require.NoError(t, err)
+	require.Len(t, incident.PublicUpdates(), 1)
+	require.Len(t, w.incident.Timeline, 4)
+
+	w.incident.StartTS = time.Now().Add(-time.Minute)
+	w.process(&types.HttpResponse{Code: 200})
+	require.Nil(t, w.incident)
+	_, err = m.Acknowledge(ctx, w.host.ID, id, "john", "")
+	require.ErrorIs(t, err, types.ErrIncidentClosed)
+
+	incident, err = m.AddIncidentUpdate(ctx, w.host.ID, id, "john", "resolved", true)
+	require.NoError(t, err)
+	require.Len(t, incident.PublicUpdates(), 2)
+	require.Equal(t, "resolved", incident.PublicUpdates()[0].Message)
+	require.Len(t, incident.Timeline, 6)
+}
+
+func TestMonitor_Responses(t *testing.T) {
+	ctx := context.Background()
+	m := Monitor{
+		Store:    store.NewMemory(ctx),
+		watchers: map[string]*watcher{},
+	}
+	host := &types.Host{ID: "host"}
+	m.watchers[host.ID] = &watcher{host: host}
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, m.Store.AddResponse(ctx, host.ID, &types.HttpResponse{
+			Timestamp:  now.Add(time.Minute * time.Duration(i)),
+			StatusType: types.UP,
+		}))
+	}
+
+	h, err := m.Host(host.ID)
+	require.NoError(t, err)
+	require.Equal(t, types.Unknown, h.Status)
+
+	_, err = m.Host("unknown")
+	require.ErrorIs(t, err, types.ErrHostNotFound)
+	_, err = m.Responses(ctx, "unknown", time.Time{}, time.Time{}, 0, 0)
+	require.ErrorIs(t, err, types.ErrHostNotFound)
+
+	list, err := m.Responses(ctx, host.ID, time.Time{}, time.Time{}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 10)
+
+	list, err = m.Responses(ctx, host.ID, now.Add(time.Minute*2), now.Add(time.Minute*7), 0, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 6)
+	require.Equal(t, now.Add(time.Minute*2).Unix(), list[0].Timestamp.Unix())
+
+	list, err = m.Responses(ctx, host.ID, now.Add(time.Minute*2), time.Time{}, 3, 2)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, now.Add(time.Minute*5).Unix(), list[0].Timestamp.Unix())
+
+	list, err = m.Responses(ctx, host.ID, time.Time{}, time.Time{}, 20, 2)
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
+
+func TestMonitor_Announce(t *testing.T) {
+	ctx := context.Background()
+	interval := time.Second
+	name := "Website"
+	m := Monitor{
+		Store:    store.NewMemory(ctx),
+		watchers: map[string]*watcher{},
+		announcements: []*types.Announcement{
+			{ID: -1, Title: "Scheduled migration", Severity: types.MAINTENANCE, StartTS: time.Now().Add(-time.Hour), Config: true},
+		},
+	}
+	group := "frontend"
+	for _, h := range []*types.Host{
+		{ID: "api", URL: "http://api", Interval: &interval},
+		{ID: "web", URL: "http://web", Name: &name, Group: &group, Interval: &interval},
+		{ID: "admin", URL: "http://admin", Group: &group, Interval: &interval, Hidden: true},
+	} {
+		m.watchers[h.ID] = &watcher{host: h}
+	}
+
+	require.ErrorIs(t, m.Announce(ctx, &types.Announcement{}), types.ErrAnnouncementInvalid)
+	require.ErrorIs(t, m.Announce(ctx, &types.Announcement{Title: "Outage", Hosts: []string{"http://unknown"}}), types.ErrAnnouncementInvalid)
+	require.ErrorIs(t, m.Announce(ctx, &types.Announcement{Title: "Outage", Groups: []string{"unknown"}}), types.ErrAnnouncementInvalid)
+
+	a := &types.Announcement{Title: "Slow responses", Hosts: []string{"http://web", "http://admin"}}
+	require.NoError(t, m.Announce(ctx, a))
+	require.NotZero(t, a.ID)
+	require.Equal(t, types.DEGRADED, a.Severity)
+	require.False(t, a.StartTS.IsZero())
+
+	list, err := m.Announcements(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, a.ID, list[0].ID)
+	require.True(t, list[1].Config)
+
+	_, err = m.Announcement(ctx, a.ID+1)
+	require.ErrorIs(t, err, types.ErrAnnouncementNotFound)
+	_, err = m.AddAnnouncementUpdate(ctx, a.ID+1, "john", "investigating")
+	require.ErrorIs(t, err, types.ErrAnnouncementNotFound)
+
+	config, err := m.Announcement(ctx, -1)
+	require.NoError(t, err)
+	require.Equal(t, "Scheduled migration", config.Title)
+	_, err = m.AddAnnouncementUpdate(ctx, -1, "john", "investigating")
+	require.ErrorIs(t, err, types.ErrAnnouncementReadOnly)
+	_, err = m.ResolveAnnouncement(ctx, -1, "john", "")
+	require.ErrorIs(t, err, types.ErrAnnouncementReadOnly)
+	require.ErrorIs(t, m.DeleteAnnouncement(ctx, -1), types.ErrAnnouncementReadOnly)
+
+	updated, err := m.AddAnnouncementUpdate(ctx, a.ID, "john", "investigating")
+	require.NoError(t, err)
+	require.Len(t, updated.Updates, 1)
+
+	s, err := m.StatsByID(ctx, "web", false)
+	require.NoError(t, err)
+	require.Len(t, s.Announcements, 2)
+	require.Equal(t, []string{"Website"}, s.Announcements[0].Affected)
+	s, err = m.StatsByID(ctx, "api", false)
+	require.NoError(t, err)
+	require.Len(t, s.Announcements, 1)
+	require.True(t, s.Announcements[0].Config)
+
+	resolved, err := m.ResolveAnnouncement(ctx, a.ID, "john", "fixed")
+	require.NoError(t, err)
+	require.NotNil(t, resolved.EndTS)
+	require.Equal(t, "fixed", resolved.PublicUpdates()[0].Message)
+	_, err = m.ResolveAnnouncement(ctx, a.ID, "john", "")
+	require.ErrorIs(t, err, types.ErrAnnouncementResolved)
+
+	s, err = m.Stats(ctx)
+	require.NoError(t, err)
+	require.Len(t, s.Announcements, 1)
+	require.Len(t, s.AnnouncementHistory, 1)
+	require.Equal(t, a.ID, s.AnnouncementHistory[0].ID)
+
+	require.NoError(t, m.DeleteAnnouncement(ctx, a.ID))
+	require.ErrorIs(t, m.DeleteAnnouncement(ctx, a.ID), types.ErrAnnouncementNotFound)
+}
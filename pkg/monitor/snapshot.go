@@ -0,0 +1,277 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/exelban/JAM/store"
+	"github.com/exelban/JAM/types"
+)
+
+// chartPoints - number of the points in the chart of the host and of the days in the chart of the main page
+const chartPoints = 90
+
+// detailsPeriod - period of the uptime and the response time in the host details
+const detailsPeriod = time.Hour * 24 * 30
+
+// snapshot - the history of the host required for the status page. It's loaded from the store on the first request
+// and updated with every check, so the status page does not read the whole history from the store on each request.
+type snapshot struct {
+	loaded bool
+	day    time.Time // start of the current day
+
+	last  []*types.HttpResponse // last responses for the chart of the host page
+	days  []*types.HttpResponse // aggregated responses of the days before today for the chart of the main page
+	today []*types.HttpResponse // responses of the current day
+	month []*types.HttpResponse // responses of the last 30 days for the details, the checks older than a day are rolled up by hour
+
+	compacted time.Time // the checks before are rolled up in the details
+
+	incidents       []*types.Incident // last incidents of the host
+	incidentsLoaded bool
+
+	mu sync.Mutex
+}
+
+// stats - returns the chart history and the details history of the host with the last incidents.
+// The incidents are copies, so they can be processed by the caller.
+func (s *snapshot) stats(ctx context.Context, st store.Interface, hostID string, dayReport bool, now time.Time) (history, month []*types.HttpResponse, incidents []*types.Incident, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx, st, hostID, now); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get history: %w", err)
+	}
+	if !s.incidentsLoaded {
+		list, err := st.FindIncidents(ctx, hostID, -1, 30)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to get incidents: %w", err)
+		}
+		s.incidents, s.incidentsLoaded = list, true
+	}
+	s.rollover(now)
+
+	if dayReport {
+		history = append(slices.Clone(s.days), s.today...)
+	} else {
+		history = slices.Clone(s.last)
+		month = slices.Clone(s.month)
+	}
+	incidents = make([]*types.Incident, 0, len(s.incidents))
+	for _, e := range s.incidents {
+		c := *e
+		c.Timeline = slices.Clone(e.Timeline)
+		incidents = append(incidents, &c)
+	}
+
+	return history, month, incidents, nil
+}
+
+// add - appends the new response of the host, skipped until the snapshot is loaded.
+// Returns true if the day or the hour of the charts is over, so the aggregated points are changed.
+func (s *snapshot) add(r *types.HttpResponse) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		return false
+	}
+	changed := s.rollover(r.Timestamp)
+
+	// the response could be already read from the store if it was saved during the loading
+	if isNew(s.last, r) {
+		s.last = append(s.last, r)
+		if len(s.last) > chartPoints {
+			s.last = slices.Clone(s.last[len(s.last)-chartPoints:])
+		}
+	}
+	if isNew(s.today, r) && r.Timestamp.After(s.day) {
+		s.today = append(s.today, r)
+	}
+	if isNew(s.month, r) {
+		if len(s.month) > 0 {
+			// only the certificate of the last response is shown
+			prev := s.month[len(s.month)-1]
+			prev.SSLCertExpiry, prev.Certificate = nil, nil
+		}
+		s.month = append(s.month, detailsResponse(r))
+		from := r.Timestamp.Add(-detailsPeriod)
+		if i := sort.Search(len(s.month), func(i int) bool { return s.month[i].Timestamp.After(from) }); i > 0 {
+			s.month = s.month[i:]
+		}
+		if s.compact(r.Timestamp) {
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// resetIncidents - the incidents are reloaded from the store on the next request
+func (s *snapshot) resetIncidents() {
+	s.mu.Lock()
+	s.incidents, s.incidentsLoaded = nil, false
+	s.mu.Unlock()
+}
+
+// load - reads the history of the host from the store if it's not loaded yet
+func (s *snapshot) load(ctx context.Context, st store.Interface, hostID string, now time.Time) error {
+	if s.loaded {
+		return nil
+	}
+
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	last, err := st.LastResponses(ctx, hostID, chartPoints)
+	if err != nil {
+		return err
+	}
+	history, err := st.FindResponsesRange(ctx, hostID, day.AddDate(0, 0, -chartPoints), time.Time{}, 0)
+	if err != nil {
+		return err
+	}
+	month, err := st.FindResponsesRange(ctx, hostID, now.Add(-detailsPeriod), time.Time{}, 0)
+	if err != nil {
+		return err
+	}
+
+	s.day = day
+	s.last = last
+	s.days, s.today = nil, nil
+	// the history is kept in the different resolutions, so the days are aggregated from the raw checks and the hourly aggregations
+	var prev []*types.HttpResponse
+	for _, r := range history {
+		if r.Timestamp.After(day) {
+			s.today = append(s.today, r)
+			continue
+		}
+		if len(prev) > 0 && !sameDay(prev[0].Timestamp, r.Timestamp) {
+			s.days = append(s.days, dayAggregation(prev))
+			prev = nil
+		}
+		prev = append(prev, r)
+	}
+	if len(prev) > 0 {
+		s.days = append(s.days, dayAggregation(prev))
+	}
+	s.month = make([]*types.HttpResponse, 0, len(month))
+	for _, r := range month {
+		s.month = append(s.month, detailsResponse(r))
+	}
+	// the certificate is saved only when it's changed, so the last saved one is moved to the last response
+	var cert *types.Certificate
+	for _, r := range s.month {
+		if r.Certificate != nil {
+			cert = r.Certificate
+		}
+		r.Certificate = nil
+	}
+	for _, r := range s.month[:max(len(s.month)-1, 0)] {
+		r.SSLCertExpiry = nil
+	}
+	if n := len(s.month); n > 0 && cert != nil && s.month[n-1].SSLCertExpiry != nil && cert.NotAfter.Equal(*s.month[n-1].SSLCertExpiry) {
+		s.month[n-1].Certificate = cert
+	}
+	s.compact(now)
+	s.loaded = true
+
+	return nil
+}
+
+// rollover - aggregates the responses of the previous day when the new day starts, returns true if the day is changed
+func (s *snapshot) rollover(now time.Time) bool {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if !day.After(s.day) {
+		return false
+	}
+	if len(s.today) > 0 {
+		s.days = append(s.days, store.AggregateDay(s.day, s.today))
+	}
+	if len(s.days) > chartPoints {
+		s.days = slices.Clone(s.days[len(s.days)-chartPoints:])
+	}
+	s.today = nil
+	s.day = day
+	return true
+}
+
+// compact - rolls up the checks older than a day in the details by hour, the last day is kept in the raw checks for the hourly chart.
+// Returns true if the new hour was rolled up.
+func (s *snapshot) compact(now time.Time) bool {
+	to := startOfHour(now.Add(-time.Hour * 24))
+	if !to.After(s.compacted) {
+		return false
+	}
+	s.compacted = to
+
+	n := sort.Search(len(s.month), func(i int) bool { return !s.month[i].Timestamp.Before(to) })
+	list := make([]*types.HttpResponse, 0, len(s.month))
+	var hour []*types.HttpResponse
+	for _, r := range s.month[:n] {
+		if len(hour) > 0 && (r.IsAggregated || !startOfHour(r.Timestamp).Equal(startOfHour(hour[0].Timestamp))) {
+			list = append(list, store.Rollup(startOfHour(hour[0].Timestamp), store.HourlyResolution, hour))
+			hour = nil
+		}
+		if r.IsAggregated {
+			list = append(list, r)
+			continue
+		}
+		hour = append(hour, r)
+	}
+	if len(hour) > 0 {
+		list = append(list, store.Rollup(startOfHour(hour[0].Timestamp), store.HourlyResolution, hour))
+	}
+	s.month = append(list, s.month[n:]...)
+	return true
+}
+
+// isNew - returns true if the response is newer than the last one in the list
+func isNew(list []*types.HttpResponse, r *types.HttpResponse) bool {
+	return len(list) == 0 || r.Timestamp.After(list[len(list)-1].Timestamp)
+}
+
+// detailsResponse - returns the copy of the response with the fields used by the details and the hourly chart only
+func detailsResponse(r *types.HttpResponse) *types.HttpResponse {
+	return &types.HttpResponse{
+		Timestamp:     r.Timestamp,
+		Status:        r.Status,
+		StatusType:    r.StatusType,
+		Time:          r.Time,
+		SSLCertExpiry: r.SSLCertExpiry,
+		Certificate:   r.Certificate,
+
+		IsAggregated: r.IsAggregated,
+		Uptime:       r.Uptime,
+		Count:        r.Count,
+		Resolution:   r.Resolution,
+		MinTime:      r.MinTime,
+		MaxTime:      r.MaxTime,
+		P95Time:      r.P95Time,
+		Statuses:     r.Statuses,
+	}
+}
+
+// dayAggregation - returns the daily aggregation of the responses of one day
+func dayAggregation(list []*types.HttpResponse) *types.HttpResponse {
+	if len(list) == 1 && store.Resolution(list[0]) >= store.DailyResolution {
+		return list[0]
+	}
+	y, m, d := list[0].Timestamp.Date()
+	return store.AggregateDay(time.Date(y, m, d, 0, 0, 0, 0, list[0].Timestamp.Location()), list)
+}
+
+// sameDay - returns true if both times are in the same day
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// startOfHour - returns the beginning of the hour in the location of the time
+func startOfHour(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
@@ -0,0 +1,135 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/exelban/JAM/store"
+	"github.com/exelban/JAM/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot_stats(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory(ctx)
+	now := time.Date(2024, 5, 10, 15, 30, 0, 0, time.UTC)
+
+	for i := 100; i > 0; i-- {
+		require.NoError(t, s.AddResponse(ctx, "host", &types.HttpResponse{Code: i, Timestamp: now.Add(-time.Second * time.Duration(i)), StatusType: types.UP}))
+	}
+
+	var sn snapshot
+	sn.add(&types.HttpResponse{Timestamp: now.Add(-time.Hour)}) // not loaded yet
+	history, month, incidents, err := sn.stats(ctx, s, "host", false, now)
+	require.NoError(t, err)
+	require.Len(t, history, chartPoints)
+	require.Equal(t, 90, history[0].Code)
+	require.Len(t, month, 100)
+	require.Empty(t, incidents)
+
+	t.Run("add", func(t *testing.T) {
+		r := &types.HttpResponse{Code: 200, Timestamp: now, StatusType: types.DOWN}
+		require.False(t, sn.add(r))
+		require.False(t, sn.add(r))
+
+		history, month, _, err := sn.stats(ctx, s, "host", false, now)
+		require.NoError(t, err)
+		require.Len(t, history, chartPoints)
+		require.Equal(t, 89, history[0].Code)
+		require.Equal(t, r, history[len(history)-1])
+		require.Len(t, month, 101)
+		require.Equal(t, types.DOWN, month[len(month)-1].StatusType)
+	})
+
+	t.Run("rollover", func(t *testing.T) {
+		tomorrow := now.AddDate(0, 0, 1)
+		require.True(t, sn.add(&types.HttpResponse{Code: 300, Timestamp: tomorrow, StatusType: types.UP}))
+
+		require.Len(t, sn.today, 1)
+		require.NotEmpty(t, sn.days)
+		day := sn.days[len(sn.days)-1]
+		require.True(t, day.IsAggregated)
+		require.Equal(t, time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), day.Timestamp)
+		// the checks of the previous day are after 15:00, so none of them are rolled up yet
+		require.Len(t, sn.month, 102)
+		require.False(t, sn.month[0].IsAggregated)
+	})
+
+	t.Run("incidents", func(t *testing.T) {
+		require.NoError(t, s.AddIncident(ctx, "host", &types.Incident{StartTS: now, Timeline: []types.IncidentEvent{{Type: types.StatusEvent}}}))
+
+		_, _, incidents, err := sn.stats(ctx, s, "host", true, now)
+		require.NoError(t, err)
+		require.Empty(t, incidents)
+
+		sn.resetIncidents()
+		_, _, incidents, err = sn.stats(ctx, s, "host", true, now)
+		require.NoError(t, err)
+		require.Len(t, incidents, 1)
+
+		incidents[0].Text = "changed"
+		incidents[0].Timeline[0].Message = "changed"
+		incidents[0].Timeline = append(incidents[0].Timeline, types.IncidentEvent{Type: types.NoteEvent})
+		_, _, incidents, err = sn.stats(ctx, s, "host", true, now)
+		require.NoError(t, err)
+		require.Empty(t, incidents[0].Text)
+		require.Len(t, incidents[0].Timeline, 1)
+		require.Empty(t, incidents[0].Timeline[0].Message)
+	})
+}
+
+func TestSnapshot_certificate(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory(ctx)
+	now := time.Now()
+	expiry := now.AddDate(0, 2, 0)
+	cert := &types.Certificate{Subject: "CN=host", Issuer: "R3", NotAfter: expiry}
+
+	for i := 3; i > 0; i-- {
+		r := &types.HttpResponse{Timestamp: now.Add(-time.Minute * time.Duration(i)), StatusType: types.UP, SSLCertExpiry: &expiry}
+		if i == 3 {
+			r.Certificate = cert
+		}
+		require.NoError(t, s.AddResponse(ctx, "host", r))
+	}
+
+	var sn snapshot
+	_, month, _, err := sn.stats(ctx, s, "host", false, now)
+	require.NoError(t, err)
+	require.Len(t, month, 3)
+	require.Nil(t, month[0].Certificate)
+	require.Nil(t, month[0].SSLCertExpiry)
+	require.Equal(t, cert, month[2].Certificate)
+}
+
+func TestSnapshot_compact(t *testing.T) {
+	now := time.Date(2024, 5, 10, 15, 30, 0, 0, time.UTC)
+	sn := snapshot{loaded: true, day: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)}
+	for i := 30 * 60; i > 0; i-- {
+		sn.add(&types.HttpResponse{Timestamp: now.Add(-time.Minute * time.Duration(i)), StatusType: types.UP})
+	}
+
+	// the checks before the last day are rolled up by hour starting from 9:30, the last day is kept in the raw checks
+	require.Len(t, sn.month, 6+24*60+30)
+	for i, r := range sn.month[:6] {
+		require.True(t, r.IsAggregated)
+		require.Equal(t, time.Date(2024, 5, 9, 9+i, 0, 0, 0, time.UTC), r.Timestamp)
+	}
+	require.Equal(t, 30, sn.month[0].Count)
+	require.Equal(t, 60, sn.month[5].Count)
+	require.False(t, sn.month[6].IsAggregated)
+	require.Equal(t, time.Date(2024, 5, 9, 15, 0, 0, 0, time.UTC), sn.month[6].Timestamp)
+}
+
+func TestMonitor_Version(t *testing.T) {
+	m := Monitor{}
+	version, modified := m.Version()
+	require.Zero(t, version)
+	require.True(t, modified.IsZero())
+
+	m.touch()
+	next, modified := m.Version()
+	require.Greater(t, next, version)
+	require.WithinDuration(t, time.Now(), modified, time.Second)
+}
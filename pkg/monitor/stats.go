@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -23,12 +24,17 @@ func (m *Monitor) Stats(ctx context.Context) (*types.Stats, error) {
 	groups := make(map[string][]*types.Stats)
 	hiddenHosts := make([]string, 0)
 	m.mu.RLock()
+	hosts := make([]*types.Host, 0, len(m.watchers))
 	for _, w := range m.watchers {
+		hosts = append(hosts, w.host)
 		stats, err := m.StatsByID(ctx, w.host.ID, true)
 		if err != nil {
 			return nil, err
 		}
 		host := stats.Hosts[0]
+		if !w.host.Hidden {
+			s.Active = append(s.Active, activeIncidents(host, stats.Incidents, time.Now())...)
+		}
 		if w.host.Group == nil {
 			s.Hosts = append(s.Hosts, host)
 		} else {
@@ -99,10 +105,49 @@ func (m *Monitor) Stats(ctx context.Context) (*types.Stats, error) {
 	sort.Slice(s.Hosts, func(i, j int) bool {
 		return s.Hosts[i].Index < s.Hosts[j].Index
 	})
+	s.Maintenance = maintenanceWindows(hosts, time.Now())
+
+	active, resolved, err := m.filterAnnouncements(ctx, func(a *types.Announcement) bool {
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Announcements, s.AnnouncementHistory = active, resolved
+
+	sort.Slice(s.Active, func(i, j int) bool {
+		return s.Active[i].Incident.StartTS.After(s.Active[j].Incident.StartTS)
+	})
 
 	return s, nil
 }
 
+// activeIncidentsHorizon - how long the resolved incident with the public updates stays on the main page
+const activeIncidentsHorizon = time.Hour * 24
+
+// activeIncidents - returns the open and recently resolved incidents of the host that have public updates
+func activeIncidents(host types.Stat, incidents []*types.Incident, now time.Time) []*types.ActiveIncident {
+	list := []*types.ActiveIncident{}
+	for _, incident := range incidents {
+		if incident.EndTS != nil && now.Sub(*incident.EndTS) > activeIncidentsHorizon {
+			continue
+		}
+		if len(incident.PublicUpdates()) == 0 {
+			continue
+		}
+		list = append(list, &types.ActiveIncident{
+			ID:       host.ID,
+			Name:     host.Name,
+			Host:     host.Host,
+			Incident: incident,
+		})
+	}
+	return list
+}
+
+// maintenanceHorizon - how far in advance the upcoming maintenance is shown
+const maintenanceHorizon = time.Hour * 24 * 7
+
 // StatsByID - returns the stats of a host by id
 func (m *Monitor) StatsByID(ctx context.Context, id string, dayReport bool) (*types.Stats, error) {
 	m.mu.RLock()
@@ -114,24 +159,17 @@ func (m *Monitor) StatsByID(ctx context.Context, id string, dayReport bool) (*ty
 	step := *w.host.Interval
 	m.mu.RUnlock()
 
-	history, err := m.Store.FindResponses(ctx, id)
+	history, month, incidents, err := w.snapshot.stats(ctx, m.Store, id, dayReport, time.Now())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get history: %w", err)
-	}
-
-	incidents, err := m.Store.FindIncidents(ctx, id, -1, 30)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get incidents: %w", err)
+		return nil, err
 	}
 	processIncidents(incidents)
 
 	var details *types.Details
+	var hours types.Chart
 	if !dayReport {
-		details = getDetails(history, incidents)
-	}
-
-	if !dayReport && len(history) > 90 {
-		history = history[len(history)-90:]
+		details = getDetails(month, incidents)
+		hours = genHourChart(month, time.Now())
 	}
 	chart, uptime, responseTime := genChart(history, step, dayReport)
 
@@ -153,19 +191,30 @@ func (m *Monitor) StatsByID(ctx context.Context, id string, dayReport bool) (*ty
 				Uptime:       uptime,
 				ResponseTime: responseTime,
 				Chart:        chart,
+				Hours:        hours,
 				Details:      details,
 				Index:        w.host.Index,
 			},
 		},
-		Incidents: incidents,
+		Incidents:   incidents,
+		Maintenance: maintenanceWindows([]*types.Host{w.host}, time.Now()),
 	}
 	w.mu.RUnlock()
 
+	if !dayReport {
+		s.Announcements, s.AnnouncementHistory, err = m.filterAnnouncements(ctx, func(a *types.Announcement) bool {
+			return a.Applies(w.host)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return s, nil
 }
 
 func (m *Monitor) ResponseTime(ctx context.Context, id string) ([]time.Time, []float64, error) {
-	history, err := m.Store.FindResponses(ctx, id)
+	history, err := m.Store.FindResponsesRange(ctx, id, time.Now().AddDate(0, 0, -90), time.Time{}, 0)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get history: %w", err)
 	}
@@ -258,8 +307,10 @@ func genChart(history []*types.HttpResponse, interval time.Duration, dayReport b
 	}
 	for i, r := range history {
 		pointFormat := "2006-01-02 15:04:05"
-		if r.IsAggregated {
+		if res := store.Resolution(r); res >= store.DailyResolution {
 			pointFormat = "2006-01-02"
+		} else if res >= store.HourlyResolution {
+			pointFormat = "2006-01-02 15:00"
 		}
 		points[space+i] = &types.Point{
 			Timestamp: r.Timestamp.Format(pointFormat),
@@ -272,7 +323,7 @@ func genChart(history []*types.HttpResponse, interval time.Duration, dayReport b
 	for _, c := range points {
 		if c.Status == types.UP {
 			uptime++
-		} else if c.Status == types.Unknown {
+		} else if c.Status == types.Unknown || c.Status == types.MAINTENANCE {
 			unknown++
 		}
 	}
@@ -289,6 +340,47 @@ func genChart(history []*types.HttpResponse, interval time.Duration, dayReport b
 		Intervals: genIntervals(points),
 	}, uptime, (responseTime / time.Duration(len(points))).Truncate(time.Millisecond).String()
 }
+
+// genHourChart - generates the hourly chart of the last 24 hours, the tooltip of the point contains the response time of the hour
+func genHourChart(history []*types.HttpResponse, now time.Time) types.Chart {
+	start := startOfHour(now).Add(-time.Hour * 23)
+	chart := types.Chart{
+		Points:    make([]*types.Point, 0, 24),
+		Intervals: []string{"24h", "24h", "24h"},
+	}
+
+	i := sort.Search(len(history), func(i int) bool { return !history[i].Timestamp.Before(start) })
+	for h := 0; h < 24; h++ {
+		from := start.Add(time.Hour * time.Duration(h))
+		to := from.Add(time.Hour)
+
+		list := []*types.HttpResponse{}
+		for ; i < len(history) && history[i].Timestamp.Before(to); i++ {
+			list = append(list, history[i])
+		}
+
+		p := &types.Point{
+			Timestamp: from.Format("2006-01-02 15:00"),
+			Status:    types.Unknown,
+			TS:        from,
+		}
+		if len(list) > 0 {
+			r := store.Rollup(from, store.HourlyResolution, list)
+			p.Status = r.StatusType
+			if len(r.Statuses) == 1 {
+				for status := range r.Statuses {
+					p.Status = status
+				}
+			}
+			tooltip := fmt.Sprintf("%s, %.1f%% uptime, avg %s, p95 %s, max %s", from.Format("15:00"), r.Uptime*100,
+				formatDuration(r.Time), formatDuration(r.P95Time), formatDuration(r.MaxTime))
+			p.Tooltip = &tooltip
+		}
+		chart.Points = append(chart.Points, p)
+	}
+
+	return chart
+}
 func genIntervals(points []*types.Point) []string {
 	p := []time.Time{points[0].TS, points[30].TS, points[60].TS}
 	for i, ts := range p {
@@ -337,9 +429,25 @@ func getDetails(responses []*types.HttpResponse, incidents []*types.Incident) *t
 	responseTime30Days := time.Duration(0)
 
 	for _, r := range responses {
-		if r.Timestamp.After(time.Now().Add(-time.Hour * 24 * 30)) {
+		if r.IsAggregated && r.Timestamp.After(time.Now().Add(-time.Hour*24*30)) {
+			// the maintenance checks are counted as up in the aggregation
+			count := max(r.Count, 1)
+			maintenance := r.Statuses[types.MAINTENANCE]
+			if count <= maintenance {
+				continue
+			}
+			last30DaysCount += count - maintenance
+			last30DaysUp += max(int(math.Round(r.Uptime*float64(count)))-maintenance, 0)
+			responseTime30Days += r.Time * time.Duration(count-maintenance)
+			continue
+		}
+		if r.Timestamp.After(time.Now().Add(-time.Hour*24*30)) && r.StatusType != types.MAINTENANCE {
 			last30DaysCount++
-			if r.StatusType != types.DOWN {
+			if r.StatusType == types.FLAPPING {
+				if r.Status {
+					last30DaysUp++
+				}
+			} else if r.StatusType != types.DOWN && r.StatusType != types.UNREACHABLE {
 				last30DaysUp++
 			}
 			responseTime30Days += r.Time
@@ -356,6 +464,10 @@ func getDetails(responses []*types.HttpResponse, incidents []*types.Incident) *t
 		d.Uptime = fmt.Sprintf("%.1f", uptime30Days)
 	}
 	d.ResponseTime = formatDuration(responseTime30Days)
+	if last30DaysCount != 0 {
+		d.UptimePercent = uptime30Days
+	}
+	d.AvgResponseTime = responseTime30Days
 
 	if len(incidents) > 0 {
 		lastIncident := incidents[0]
@@ -367,6 +479,7 @@ func getDetails(responses []*types.HttpResponse, incidents []*types.Incident) *t
 			Since:    formatDuration(time.Since(ts)),
 			TS:       ts.Format("2006-01-02 15:04:05"),
 			Duration: lastIncident.Duration,
+			At:       ts,
 		}
 	}
 
@@ -375,6 +488,13 @@ func getDetails(responses []*types.HttpResponse, incidents []*types.Incident) *t
 		d.SSL = &types.SSLDetails{
 			ExpireInDays: int(expireAt.Sub(time.Now()).Hours() / 24),
 			ExpireTS:     expireAt.Format("January 2, 2006"),
+			ExpireAt:     *expireAt,
+		}
+		if cert := responses[len(responses)-1].Certificate; cert != nil {
+			d.SSL.Issuer = cert.Issuer
+			d.SSL.DNSNames = cert.DNSNames
+			d.SSL.ChainValid = cert.ChainValid
+			d.SSL.ChainError = cert.ChainError
 		}
 	}
 
@@ -385,6 +505,7 @@ func generateGroupStatus(hosts *[]types.Stat, i *int) types.StatusType {
 	upHosts := 0
 	downHosts := 0
 	degradedHosts := 0
+	maintenanceHosts := 0
 	unknownHosts := 0
 	for _, stat := range *hosts {
 		status := stat.Status
@@ -393,16 +514,21 @@ func generateGroupStatus(hosts *[]types.Stat, i *int) types.StatusType {
 		}
 		if status == types.UP {
 			upHosts++
-		} else if status == types.DOWN {
+		} else if status == types.DOWN || status == types.UNREACHABLE {
 			downHosts++
-		} else if status == types.DEGRADED {
+		} else if status == types.DEGRADED || status == types.FLAPPING {
 			degradedHosts++
+		} else if status == types.MAINTENANCE {
+			maintenanceHosts++
+			unknownHosts++
 		} else {
 			unknownHosts++
 		}
 	}
 
-	if upHosts == allHosts || unknownHosts > 0 && upHosts > 0 {
+	if allHosts > 0 && maintenanceHosts == allHosts {
+		return types.MAINTENANCE
+	} else if upHosts == allHosts || unknownHosts > 0 && upHosts > 0 {
 		return types.UP
 	} else if downHosts == allHosts {
 		return types.DOWN
@@ -412,10 +538,69 @@ func generateGroupStatus(hosts *[]types.Stat, i *int) types.StatusType {
 		return types.Unknown
 	}
 }
+
+// maintenanceWindows - returns the active and upcoming maintenance windows of the visible hosts sorted by the start
+func maintenanceWindows(hosts []*types.Host, now time.Time) []*types.MaintenanceWindow {
+	windows := make(map[*types.Maintenance]*types.MaintenanceWindow)
+	list := make([]*types.MaintenanceWindow, 0)
+
+	hosts = slices.Clone(hosts)
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].Index < hosts[j].Index
+	})
+	for _, h := range hosts {
+		if h.Hidden {
+			continue
+		}
+		for _, m := range h.MaintenanceWindows {
+			start, end, ok := m.Window(now)
+			if !ok || start.After(now.Add(maintenanceHorizon)) {
+				continue
+			}
+			name := h.SecureURL()
+			if h.Name != nil && *h.Name != "" {
+				name = *h.Name
+			}
+			if w, ok := windows[m]; ok {
+				w.Hosts = append(w.Hosts, name)
+				continue
+			}
+
+			format := "2006-01-02 15:04"
+			endFormat := format
+			if start.Year() == end.Year() && start.YearDay() == end.YearDay() {
+				endFormat = "15:04"
+			}
+			w := &types.MaintenanceWindow{
+				Name:        m.Name,
+				Description: m.Description,
+				Hosts:       []string{name},
+				Active:      !start.After(now),
+				Start:       start,
+				End:         end,
+				StartTS:     start.Format(format),
+				EndTS:       end.Format(endFormat),
+			}
+			windows[m] = w
+			list = append(list, w)
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Start.Before(list[j].Start)
+	})
+
+	return list
+}
+
 func processIncidents(list []*types.Incident) {
 	for i, e := range list {
 		list[i].Start = e.StartTS.Format("2006-01-02 15:04:05")
-		text := fmt.Sprintf("Host is down for %s!", formatDuration(time.Now().Sub(e.StartTS)))
+		state := "down"
+		if e.Severity == types.DEGRADED {
+			state = "degraded"
+		}
+		text := fmt.Sprintf("Host is %s for %s!", state, formatDuration(time.Now().Sub(e.StartTS)))
 		if e.EndTS != nil {
 			duration := e.EndTS.Sub(e.StartTS)
 			list[i].Duration = formatDuration(duration)
@@ -424,7 +609,7 @@ func processIncidents(list []*types.Incident) {
 				format = "15:04:05"
 			}
 			list[i].End = e.EndTS.Format(format)
-			text = fmt.Sprintf("Host was down for %s", formatDuration(duration))
+			text = fmt.Sprintf("Host was %s for %s", state, formatDuration(duration))
 		}
 		list[i].Text = text
 		switch e.Details.StatusCode {
@@ -434,6 +619,10 @@ func processIncidents(list []*types.Incident) {
 			list[i].Details.StatusText = "Connection timed out"
 		case 523:
 			list[i].Details.StatusText = "Origin is unreachable"
+		case 525:
+			list[i].Details.StatusText = "SSL handshake failed"
+		case 526:
+			list[i].Details.StatusText = "Invalid SSL certificate"
 		default:
 			list[i].Details.StatusText = http.StatusText(e.Details.StatusCode)
 		}
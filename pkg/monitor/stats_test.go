@@ -11,13 +11,16 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// dailyRetention - rolls up all checks before today by day
+var dailyRetention = types.Retention{Raw: 1, Hourly: 1, Daily: 2000}
+
 func TestMonitor_Stats(t *testing.T) {
 	ctx := context.Background()
 	interval := time.Hour
 	group := "group"
 
 	t.Run("no hosts", func(t *testing.T) {
-		m := Monitor{}
+		m := Monitor{Store: store.NewMemory(ctx)}
 		s, err := m.Stats(ctx)
 		require.NoError(t, err)
 		require.NotNil(t, s)
@@ -63,7 +66,7 @@ func TestMonitor_Stats(t *testing.T) {
 		for _, r := range generateDays(30) {
 			_ = m.Store.AddResponse(ctx, "test", r)
 		}
-		require.NoError(t, store.Aggregate(ctx, m.Store))
+		require.NoError(t, store.Aggregate(ctx, m.Store, dailyRetention))
 
 		history, err := m.Store.FindResponses(ctx, "test")
 		require.NoError(t, err)
@@ -111,7 +114,7 @@ func TestMonitor_Stats(t *testing.T) {
 		for _, r := range generateDays(rand.IntN(1000-100) + 100) {
 			_ = m.Store.AddResponse(ctx, "test", r)
 		}
-		require.NoError(t, store.Aggregate(ctx, m.Store))
+		require.NoError(t, store.Aggregate(ctx, m.Store, dailyRetention))
 
 		history, err := m.Store.FindResponses(ctx, "test")
 		require.NoError(t, err)
@@ -172,7 +175,7 @@ func TestMonitor_Stats(t *testing.T) {
 		for _, r := range generateDays(rand.IntN(1000-100) + 100) {
 			_ = m.Store.AddResponse(ctx, "test", r)
 		}
-		require.NoError(t, store.Aggregate(ctx, m.Store))
+		require.NoError(t, store.Aggregate(ctx, m.Store, dailyRetention))
 
 		history, err := m.Store.FindResponses(ctx, "test")
 		require.NoError(t, err)
@@ -353,7 +356,7 @@ func TestMonitor_StatsByID(t *testing.T) {
 			responseTime += r.Time
 		}
 		responseTime = responseTime / time.Duration(90)
-		require.NoError(t, store.Aggregate(ctx, m.Store))
+		require.NoError(t, store.Aggregate(ctx, m.Store, dailyRetention))
 
 		s, err := m.StatsByID(ctx, "host", false)
 		require.NoError(t, err)
@@ -398,7 +401,7 @@ func TestMonitor_StatsByID(t *testing.T) {
 		for _, r := range responses {
 			_ = m.Store.AddResponse(ctx, "host", r)
 		}
-		require.NoError(t, store.Aggregate(ctx, m.Store))
+		require.NoError(t, store.Aggregate(ctx, m.Store, dailyRetention))
 
 		responseTime := time.Duration(0)
 		for _, r := range responses[len(responses)-90:] {
@@ -441,7 +444,7 @@ func TestMonitor_StatsByID(t *testing.T) {
 		for _, r := range raw {
 			_ = m.Store.AddResponse(ctx, "host", r)
 		}
-		require.NoError(t, store.Aggregate(ctx, m.Store))
+		require.NoError(t, store.Aggregate(ctx, m.Store, dailyRetention))
 		history, err := m.Store.FindResponses(ctx, "host")
 		require.NoError(t, err)
 		require.Equal(t, 90, len(history))
@@ -494,7 +497,7 @@ func TestMonitor_StatsByID(t *testing.T) {
 		for _, r := range generateDays(90) {
 			_ = m.Store.AddResponse(ctx, "host", r)
 		}
-		require.NoError(t, store.Aggregate(ctx, m.Store))
+		require.NoError(t, store.Aggregate(ctx, m.Store, dailyRetention))
 		history, err := m.Store.FindResponses(ctx, "host")
 		require.NoError(t, err)
 		history = history[len(history)-90:]
@@ -550,7 +553,7 @@ func TestMonitor_StatsByID(t *testing.T) {
 		for _, r := range generateDays(90) {
 			_ = m.Store.AddResponse(ctx, "host", r)
 		}
-		require.NoError(t, store.Aggregate(ctx, m.Store))
+		require.NoError(t, store.Aggregate(ctx, m.Store, dailyRetention))
 		history, err := m.Store.FindResponses(ctx, "host")
 		require.NoError(t, err)
 		history = history[len(history)-90:]
@@ -642,3 +645,115 @@ func generateResponse(ts time.Time) *types.HttpResponse {
 func randInt(min, max int) int {
 	return rand.IntN(max-min) + min
 }
+
+func TestActiveIncidents(t *testing.T) {
+	now := time.Now()
+	resolved, old := now.Add(-time.Hour), now.Add(-time.Hour*48)
+	update := []types.IncidentEvent{{Type: types.NoteEvent, Message: "investigating", Public: true, TS: now}}
+	incidents := []*types.Incident{
+		{ID: 4, StartTS: now, Timeline: update},
+		{ID: 3, StartTS: now, Timeline: []types.IncidentEvent{{Type: types.NoteEvent, Message: "private", TS: now}}},
+		{ID: 2, StartTS: now.Add(-time.Hour * 2), EndTS: &resolved, Timeline: update},
+		{ID: 1, StartTS: now.Add(-time.Hour * 50), EndTS: &old, Timeline: update},
+	}
+
+	list := activeIncidents(types.Stat{ID: "host", Host: "http://host"}, incidents, now)
+	require.Len(t, list, 2)
+	require.Equal(t, 4, list[0].Incident.ID)
+	require.Equal(t, 2, list[1].Incident.ID)
+	require.Equal(t, "host", list[1].ID)
+
+	require.Empty(t, activeIncidents(types.Stat{ID: "host"}, nil, now))
+}
+
+func TestMaintenanceWindows(t *testing.T) {
+	now := time.Now()
+	start, end := now.Add(-time.Minute), now.Add(time.Hour)
+	later, laterEnd := now.Add(time.Hour*24), now.Add(time.Hour*25)
+	far, farEnd := now.Add(time.Hour*24*30), now.Add(time.Hour*24*31)
+	past := now.Add(-time.Hour)
+
+	active := &types.Maintenance{Name: "active", Description: "database upgrade", Start: &start, End: &end}
+	upcoming := &types.Maintenance{Name: "upcoming", Start: &later, End: &laterEnd}
+	distant := &types.Maintenance{Name: "distant", Start: &far, End: &farEnd}
+	finished := &types.Maintenance{Name: "finished", Start: &past, End: &start}
+
+	name := "api"
+	hosts := []*types.Host{
+		{URL: "db", Index: 1, MaintenanceWindows: []*types.Maintenance{upcoming, active}},
+		{URL: "api", Name: &name, Index: 0, MaintenanceWindows: []*types.Maintenance{active, distant, finished}},
+		{URL: "hidden", Index: 2, Hidden: true, MaintenanceWindows: []*types.Maintenance{upcoming}},
+	}
+
+	list := maintenanceWindows(hosts, now)
+	require.Len(t, list, 2)
+
+	require.Equal(t, "active", list[0].Name)
+	require.Equal(t, "database upgrade", list[0].Description)
+	require.True(t, list[0].Active)
+	require.Equal(t, []string{"api", "db"}, list[0].Hosts)
+	require.Equal(t, start, list[0].Start)
+
+	require.Equal(t, "upcoming", list[1].Name)
+	require.False(t, list[1].Active)
+	require.Equal(t, []string{"db"}, list[1].Hosts)
+	require.Equal(t, "db", hosts[0].URL, "the hosts of the caller are not sorted")
+}
+
+func TestGenHourChart(t *testing.T) {
+	now := time.Date(2024, 5, 10, 15, 30, 0, 0, time.UTC)
+	history := []*types.HttpResponse{
+		store.Rollup(time.Date(2024, 5, 9, 10, 0, 0, 0, time.UTC), store.HourlyResolution, []*types.HttpResponse{
+			{StatusType: types.DOWN},
+		}),
+		{Timestamp: time.Date(2024, 5, 9, 16, 10, 0, 0, time.UTC), StatusType: types.UP, Time: time.Millisecond * 10},
+		{Timestamp: time.Date(2024, 5, 9, 16, 20, 0, 0, time.UTC), StatusType: types.UP, Time: time.Millisecond * 30},
+		{Timestamp: time.Date(2024, 5, 10, 14, 0, 0, 0, time.UTC), StatusType: types.MAINTENANCE},
+		{Timestamp: time.Date(2024, 5, 10, 15, 10, 0, 0, time.UTC), StatusType: types.DOWN, Time: time.Second},
+	}
+
+	chart := genHourChart(history, now)
+	require.Len(t, chart.Points, 24)
+	require.Equal(t, "2024-05-09 16:00", chart.Points[0].Timestamp)
+	require.Equal(t, types.UP, chart.Points[0].Status)
+	require.NotNil(t, chart.Points[0].Tooltip)
+	require.Equal(t, "16:00, 100.0% uptime, avg 20ms, p95 30ms, max 30ms", *chart.Points[0].Tooltip)
+	require.Equal(t, types.Unknown, chart.Points[1].Status)
+	require.Nil(t, chart.Points[1].Tooltip)
+	require.Equal(t, types.MAINTENANCE, chart.Points[22].Status)
+	require.Equal(t, types.DOWN, chart.Points[23].Status)
+	require.Len(t, chart.Intervals, 3)
+}
+
+func TestGetDetails(t *testing.T) {
+	now := time.Now()
+	responses := []*types.HttpResponse{
+		store.Rollup(now.Add(-time.Hour*48), store.HourlyResolution, []*types.HttpResponse{
+			{StatusType: types.UP, Time: time.Millisecond * 10},
+			{StatusType: types.DOWN, Time: time.Millisecond * 10},
+			{StatusType: types.MAINTENANCE, Time: time.Millisecond * 10},
+		}),
+		{Timestamp: now.Add(-time.Hour), StatusType: types.UP, Time: time.Millisecond * 10},
+		{Timestamp: now, StatusType: types.UP, Time: time.Millisecond * 10},
+	}
+
+	d := getDetails(responses, nil)
+	require.Equal(t, 75.0, d.UptimePercent)
+	require.Equal(t, "75", d.Uptime)
+}
+
+func TestGenerateGroupStatus(t *testing.T) {
+	stats := func(statuses ...types.StatusType) *[]types.Stat {
+		list := []types.Stat{}
+		for _, s := range statuses {
+			list = append(list, types.Stat{Status: s})
+		}
+		return &list
+	}
+
+	require.Equal(t, types.UP, generateGroupStatus(stats(types.UP, types.UP), nil))
+	require.Equal(t, types.UP, generateGroupStatus(stats(types.UP, types.MAINTENANCE), nil))
+	require.Equal(t, types.MAINTENANCE, generateGroupStatus(stats(types.MAINTENANCE, types.MAINTENANCE), nil))
+	require.Equal(t, types.DEGRADED, generateGroupStatus(stats(types.DOWN, types.MAINTENANCE), nil))
+	require.Equal(t, types.DOWN, generateGroupStatus(stats(types.DOWN, types.DOWN), nil))
+}
@@ -2,60 +2,128 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/exelban/JAM/pkg/dialer"
+	"github.com/exelban/JAM/pkg/metrics"
 	"github.com/exelban/JAM/pkg/notify"
 	"github.com/exelban/JAM/store"
 	"github.com/exelban/JAM/types"
 )
 
 type watcher struct {
-	dialer *dialer.Dialer
-	notify *notify.Notify
-	store  store.Interface
-	host   *types.Host
+	dialer  *dialer.Dialer
+	notify  *notify.Notify
+	store   store.Interface
+	metrics *metrics.Metrics
+	host    *types.Host
 
-	status    types.StatusType
-	lastCheck time.Time
+	statusOf func(id string) types.StatusType // returns the current status of the other host, used for dependencies
+	changed  func()                           // called when the host stats are changed
 
-	successCount int
-	failureCount int
+	status       types.StatusType
+	lastCheck    time.Time
+	lastPush     time.Time
+	lastResponse *types.HttpResponse
 
-	ctx    context.Context
-	cancel context.CancelFunc
+	successCount  int
+	failureCount  int
+	degradedCount int
+
+	ctx    context.Context    // context of the store calls, set on creation
+	cancel context.CancelFunc // stops the check loop
 
 	incident *types.Incident
 
+	sslThreshold int                // last certificate expiry threshold that was notified
+	savedCert    *types.Certificate // last certificate saved to the store, the responses are saved without the unchanged one
+
+	statusBefore types.StatusType // status before the maintenance window started or the host became unreachable
+
+	dependentsDown bool // the host is down and the dependents that became unreachable are not notified yet
+
+	history []bool // results of the last checks used for the flapping detection
+
+	notified map[string]time.Time // channels notified about the open incident and the time of the last notification
+
+	snapshot snapshot // history of the host for the status page
+
 	mu sync.RWMutex
 }
 
-// run - runs check loop for host
-func (w *watcher) run(ctx context.Context) {
-	ctx, cancel := context.WithCancel(ctx)
-	w.ctx = ctx
-	w.cancel = cancel
+// start - runs the check loop in the background, the previous loop is stopped.
+// The context of the loop is set before the start, so it's never read by the api while it's changed
+func (w *watcher) start(ctx context.Context) {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	ctx, w.cancel = context.WithCancel(ctx)
+	go w.run(ctx)
+}
 
+// run - runs check loop for host until the context is done
+func (w *watcher) run(ctx context.Context) {
+	// the incident and the status are read by the api while the watcher is starting
+	w.mu.Lock()
 	incidents, err := w.store.FindIncidents(ctx, w.host.ID, 0, 1)
 	if err != nil {
 		log.Printf("[ERROR] get incidents for %s: %s", w.host.String(), err)
 	}
 	if len(incidents) > 0 && incidents[0].EndTS == nil {
 		w.incident = incidents[0]
+		// the channels were notified before the restart, only the reminders and the next escalation steps are sent
+		w.notified = make(map[string]time.Time)
+		legacy := false
+		for _, e := range w.incident.Timeline {
+			if e.Type != types.NotificationEvent {
+				continue
+			}
+			legacy = legacy || len(e.Channels) == 0
+			for _, c := range e.Channels {
+				w.notified[c] = e.TS
+			}
+		}
+		if legacy && len(w.notified) == 0 { // the incident was opened by the version without the channels in the timeline
+			for _, c := range w.channels(0) {
+				w.notified[c] = w.incident.StartTS
+			}
+		}
 	}
 	if lastResponse, err := w.store.LastResponse(ctx, w.host.ID); err == nil && lastResponse != nil {
 		w.status = lastResponse.StatusType
+		if lastResponse.SSLCertExpiry != nil {
+			// the notification about the threshold of the last check was sent before the restart
+			w.sslThreshold = w.expiryThreshold(*lastResponse.SSLCertExpiry, lastResponse.Timestamp)
+		}
+		if w.status == types.MAINTENANCE || w.status == types.UNREACHABLE {
+			w.statusBefore = types.Unknown
+		}
+		if w.status == types.FLAPPING && w.host.Flapping != nil {
+			w.loadHistory(ctx)
+		}
 	}
+	w.mu.Unlock()
 
 	log.Printf("[INFO] %s: new watcher", w.host.String())
 
-	if w.host.InitialDelay != nil {
-		time.Sleep(*w.host.InitialDelay)
+	if w.host.Type == types.PushType {
+		w.mu.Lock()
+		w.lastPush = time.Now()
+		w.mu.Unlock()
+	} else {
+		if w.host.InitialDelay != nil {
+			time.Sleep(*w.host.InitialDelay)
+		}
+		w.check()
 	}
-	w.check()
 
 	ticker := time.NewTicker(*w.host.Interval)
 	for {
@@ -72,22 +140,141 @@ func (w *watcher) run(ctx context.Context) {
 
 // check - call the host and check host status
 func (w *watcher) check() {
+	if w.host.Type == types.PushType {
+		w.deadline()
+		return
+	}
+
 	resp := w.dialer.Dial(w.ctx, w.host)
+	w.process(&resp)
+}
+
+// hostStatus - returns the host with the current status
+func (w *watcher) hostStatus() HostStatus {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	status := w.status
+	if status == "" {
+		status = types.Unknown
+	}
+	return HostStatus{
+		Host:      w.host,
+		Status:    status,
+		LastCheck: w.lastCheck,
+	}
+}
+
+// push - registers a check-in from the push host
+func (w *watcher) push(ok bool, msg string) {
+	resp := types.HttpResponse{
+		Timestamp: time.Now(),
+		Code:      http.StatusOK,
+		Body:      msg,
+		OK:        true,
+	}
+	if !ok {
+		resp.Code = http.StatusInternalServerError
+	}
+
+	w.mu.Lock()
+	w.lastPush = resp.Timestamp
+	w.mu.Unlock()
+
+	w.process(&resp)
+}
+
+// deadline - records a failure if the push host missed the check-in
+func (w *watcher) deadline() {
+	w.mu.RLock()
+	last := w.lastPush
+	w.mu.RUnlock()
+
+	window := *w.host.Interval
+	if w.host.GracePeriod != nil {
+		window += *w.host.GracePeriod
+	}
+	if time.Since(last) <= window {
+		return
+	}
+
+	w.process(&types.HttpResponse{
+		Timestamp: time.Now(),
+		Code:      http.StatusRequestTimeout,
+		Body:      fmt.Sprintf("no check-in since %s", last.Format(time.RFC3339)),
+	})
+}
+
+// process - validates the response, updates the host status and saves the response
+func (w *watcher) process(resp *types.HttpResponse) {
+	parent := w.parentDown()
+	dependents := w.unreachableDependents()
 
 	w.mu.Lock()
+	status, incident, events := w.status, w.incident, w.incidentEvents()
 	resp.Status = w.host.Status(resp.Code, resp.Bytes)
+	if resp.Status {
+		failures, warnings := w.host.Assert(resp)
+		resp.Reasons = append(failures, warnings...)
+		resp.Status = len(failures) == 0
+		resp.Degraded = len(warnings) > 0
+	}
+	resp.Bytes, resp.Headers = nil, nil
 	w.lastCheck = time.Now()
-	w.validate(&resp)
+	if m := w.host.InMaintenance(w.lastCheck); m != nil {
+		w.maintenance(m)
+	} else if parent != "" && !resp.Status {
+		w.unreachable(parent)
+	} else {
+		if w.status == types.MAINTENANCE || w.status == types.UNREACHABLE {
+			log.Printf("[INFO] %s: %s is over", w.host.String(), w.status)
+			w.status = w.statusBefore
+		}
+		if !w.flap(resp) {
+			w.validate(resp)
+			w.assertions(resp)
+			w.remind(resp)
+		}
+		if w.dependentsDown && w.status == types.DOWN && len(dependents) > 0 {
+			w.notifyDependents(dependents)
+			w.dependentsDown = false
+		}
+	}
+	w.certificate(resp)
 	resp.StatusType = w.status
-	if err := w.store.AddResponse(w.ctx, w.host.ID, &resp); err != nil {
+	w.lastResponse = resp
+	w.metrics.Check(w.host.ID, resp.Status)
+	saved := resp
+	if resp.Certificate != nil && resp.Certificate.Equal(w.savedCert) {
+		// the certificate is saved only when it's changed, the snapshot keeps the last one for the details
+		r := *resp
+		r.Certificate = nil
+		saved = &r
+	}
+	rollover := false
+	if err := w.store.AddResponse(w.ctx, w.host.ID, saved); err != nil {
 		log.Printf("[ERROR] save response to db %s: %s", w.host.String(), err)
+	} else {
+		if resp.Certificate != nil {
+			w.savedCert = resp.Certificate
+		}
+		rollover = w.snapshot.add(resp)
 	}
+	// the last checks in the chart are refreshed with the page ttl, the pages are rendered again right away
+	// only if the status, the incident or the aggregated points of the chart are changed
+	changed := rollover || w.status != status || w.incident != incident || w.incidentEvents() != events
 	w.mu.Unlock()
+	if changed && w.changed != nil {
+		w.changed()
+	}
 
 	debug := fmt.Sprintf("[DEBUG] %s (%s): %s status", w.host.String(), w.host.ID, w.status)
 	if w.status != types.UP {
 		debug += fmt.Sprintf(" (%d - %s)", resp.Code, resp.Body)
 	}
+	if len(resp.Reasons) > 0 {
+		debug += fmt.Sprintf(" [%s]", strings.Join(resp.Reasons, "; "))
+	}
 	log.Println(debug)
 }
 
@@ -96,54 +283,24 @@ func (w *watcher) validate(resp *types.HttpResponse) {
 	if resp.Status { // host is up
 		w.successCount++
 		w.failureCount = 0
+		if resp.Degraded {
+			w.degradedCount++
+		} else {
+			w.degradedCount = 0
+		}
 		if w.successCount >= w.host.SuccessThreshold {
 			newStatus := types.UP
-			if w.status != types.Unknown && w.status != types.UP {
-				if err := w.notify.Send(w.host, newStatus); err != nil {
-					log.Print(err)
-				}
-
-				if w.incident != nil {
-					incidentDuration := time.Since(w.incident.StartTS)
-					if incidentDuration > time.Second {
-						if err := w.store.EndIncident(w.ctx, w.host.ID, w.incident.ID, time.Now()); err != nil {
-							log.Printf("[ERROR] end incident in db %s: %s", w.host.String(), err)
-						}
-					} else {
-						if err := w.store.DeleteIncident(w.ctx, w.host.ID, w.incident.ID); err != nil {
-							log.Printf("[ERROR] delete incident in db %s: %s", w.host.String(), err)
-						}
-					}
-					w.incident = nil
-				}
-			}
-			w.status = newStatus
+			if resp.Degraded && (w.status == types.DEGRADED || w.degradedCount >= w.degradedThreshold()) {
+				newStatus = types.DEGRADED
+			}
+			w.transition(newStatus, resp)
 		}
 	} else { // host is down
 		w.failureCount++
 		w.successCount = 0
+		w.degradedCount = 0
 		if w.failureCount >= w.host.FailureThreshold {
-			newStatus := types.DOWN
-			if w.status != types.Unknown && w.status != types.DOWN {
-				if err := w.notify.Send(w.host, newStatus); err != nil {
-					log.Print(err)
-				}
-
-				if w.incident == nil {
-					w.incident = &types.Incident{
-						Details: types.IncidentDetails{
-							StatusCode: resp.Code,
-							Response:   resp.Body,
-							TS:         resp.Timestamp,
-						},
-						StartTS: time.Now(),
-					}
-					if err := w.store.AddIncident(w.ctx, w.host.ID, w.incident); err != nil {
-						log.Printf("[ERROR] save incident to db %s: %s", w.host.String(), err)
-					}
-				}
-			}
-			w.status = newStatus
+			w.transition(types.DOWN, resp)
 		}
 	}
 
@@ -151,3 +308,517 @@ func (w *watcher) validate(resp *types.HttpResponse) {
 		w.status = types.Unknown
 	}
 }
+
+// maintenance - marks the host as in maintenance, incidents and notifications are suppressed during the window
+func (w *watcher) maintenance(m *types.Maintenance) {
+	if w.status != types.MAINTENANCE {
+		log.Printf("[INFO] %s: maintenance %s started", w.host.String(), m.Name)
+		w.statusBefore = w.status
+		w.status = types.MAINTENANCE
+		w.timeline(w.incident, types.IncidentEvent{Type: types.StatusEvent, Status: types.MAINTENANCE, Message: m.Name})
+		// the planned work must not keep the incident open on the page, the host that is still down after the window opens the new one
+		if w.incident != nil {
+			w.closeIncident()
+			w.notified = nil
+			w.statusBefore = types.UP
+		}
+	}
+	w.successCount = 0
+	w.failureCount = 0
+	w.degradedCount = 0
+}
+
+// unreachable - marks the host as unreachable because the parent is down, incidents and notifications are suppressed
+func (w *watcher) unreachable(parent string) {
+	if w.status != types.UNREACHABLE {
+		log.Printf("[INFO] %s: unreachable, %s is down", w.host.String(), parent)
+		if w.status != types.MAINTENANCE {
+			w.statusBefore = w.status
+		}
+		w.status = types.UNREACHABLE
+		w.timeline(w.incident, types.IncidentEvent{Type: types.StatusEvent, Status: types.UNREACHABLE, Message: fmt.Sprintf("%s is down", parent)})
+	}
+	w.successCount = 0
+	w.failureCount = 0
+	w.degradedCount = 0
+}
+
+// parentDown - returns the id of the host this host depends on that is down or unreachable, empty if all parents are fine
+func (w *watcher) parentDown() string {
+	if w.statusOf == nil {
+		return ""
+	}
+	for _, id := range w.host.Parents {
+		if status := w.statusOf(id); status == types.DOWN || status == types.UNREACHABLE {
+			return id
+		}
+	}
+	return ""
+}
+
+// transition - changes the host status, sends the notification and opens or closes the incident
+func (w *watcher) transition(newStatus types.StatusType, resp *types.HttpResponse) {
+	if w.status != types.Unknown && w.status != newStatus {
+		var resolved *types.Incident
+		if w.incident != nil && w.incident.GetSeverity() != newStatus {
+			resolved = w.incident
+			w.timeline(resolved, types.IncidentEvent{Type: types.StatusEvent, Status: newStatus})
+			w.closeIncident()
+		} else if w.incident != nil {
+			w.timeline(w.incident, types.IncidentEvent{Type: types.StatusEvent, Status: newStatus})
+		}
+		if newStatus != types.UP && w.incident == nil {
+			w.incident = &types.Incident{
+				Severity: newStatus,
+				Details: types.IncidentDetails{
+					StatusCode: resp.Code,
+					Response:   resp.Body,
+					Reasons:    resp.Reasons,
+					TS:         resp.Timestamp,
+				},
+				StartTS: time.Now(),
+				Timeline: []types.IncidentEvent{
+					{Type: types.StatusEvent, Status: newStatus, Message: responseText(resp), TS: time.Now()},
+				},
+			}
+			if len(resp.Reasons) > 0 {
+				w.incident.Timeline = append(w.incident.Timeline, types.IncidentEvent{
+					Type:    types.AssertionEvent,
+					Message: strings.Join(resp.Reasons, "; "),
+					TS:      time.Now(),
+				})
+			}
+			if err := w.store.AddIncident(w.ctx, w.host.ID, w.incident); err != nil {
+				log.Printf("[ERROR] save incident to db %s: %s", w.host.String(), err)
+			}
+			w.snapshot.resetIncidents()
+			w.metrics.Incident(w.host.ID)
+		}
+
+		// the escalated channels must know about the recovery as well
+		channels := w.channels(0)
+		for c := range w.notified {
+			if !slices.Contains(channels, c) {
+				channels = append(channels, c)
+			}
+		}
+		w.notified = nil
+		sent, err := w.notify.SendTo(&notify.Event{
+			Host:      w.host,
+			OldStatus: w.status,
+			Status:    newStatus,
+			Incident:  w.incident,
+			Resolved:  resolved,
+			Response:  resp,
+		}, channels)
+		if err != nil {
+			log.Print(err)
+		}
+		if len(sent) > 0 {
+			event := types.IncidentEvent{Type: types.NotificationEvent, Status: newStatus, Message: "sent to " + strings.Join(sent, ", "), Channels: sent}
+			w.timeline(resolved, event)
+			w.timeline(w.incident, event)
+		}
+		// the channels that failed are not marked as notified, so the next check retries them
+		if w.incident != nil {
+			w.notified = make(map[string]time.Time, len(sent))
+			for _, c := range sent {
+				w.notified[c] = time.Now()
+			}
+		}
+		// the dependents are checked by their own watchers, so the list is sent after they become unreachable
+		w.dependentsDown = newStatus == types.DOWN && len(w.host.Dependents) > 0
+	}
+	w.status = newStatus
+}
+
+// flap - records the check result and detects the flapping.
+// Returns true if the response was handled by the detection and must not change the status.
+func (w *watcher) flap(resp *types.HttpResponse) bool {
+	f := w.host.Flapping
+	if f == nil {
+		return false
+	}
+
+	w.history = append(w.history, resp.Status)
+	if len(w.history) > f.Window {
+		w.history = w.history[len(w.history)-f.Window:]
+	}
+	percent := stateChange(w.history)
+
+	switch {
+	case w.status != types.FLAPPING && len(w.history) == f.Window && percent >= f.High:
+		log.Printf("[INFO] %s: flapping, %.1f%% state change", w.host.String(), percent)
+		var outage time.Duration
+		if w.incident != nil {
+			outage = time.Since(w.incident.StartTS)
+		}
+		channels := w.channels(outage)
+		for c := range w.notified {
+			if !slices.Contains(channels, c) {
+				channels = append(channels, c)
+			}
+		}
+		sent, err := w.notify.SendTo(&notify.Event{
+			Host:      w.host,
+			OldStatus: w.status,
+			Status:    types.FLAPPING,
+			Incident:  w.incident,
+			Response:  resp,
+		}, channels)
+		if err != nil {
+			log.Print(err)
+		}
+		// the incident stays open, but the reminders and escalations are paused until the flapping is over
+		w.timeline(w.incident, types.IncidentEvent{Type: types.StatusEvent, Status: types.FLAPPING, Message: fmt.Sprintf("%.1f%% state change, reminders are paused", percent)})
+		if len(sent) > 0 {
+			w.timeline(w.incident, types.IncidentEvent{Type: types.NotificationEvent, Status: types.FLAPPING, Message: "sent to " + strings.Join(sent, ", "), Channels: sent})
+		}
+		w.status = types.FLAPPING
+	case w.status == types.FLAPPING && percent < f.Low:
+		log.Printf("[INFO] %s: flapping is over, %.1f%% state change", w.host.String(), percent)
+		newStatus := types.DOWN
+		if resp.Status {
+			newStatus = types.UP
+			if resp.Degraded {
+				newStatus = types.DEGRADED
+			}
+		}
+		w.transition(newStatus, resp)
+	case w.status != types.FLAPPING:
+		return false
+	}
+
+	w.successCount = 0
+	w.failureCount = 0
+	w.degradedCount = 0
+	return true
+}
+
+// loadHistory - restores the results of the last checks from the store
+func (w *watcher) loadHistory(ctx context.Context) {
+	// the maintenance and unreachable responses are skipped, so more responses than the window may be required
+	var responses []*types.HttpResponse
+	for n := w.host.Flapping.Window; ; n *= 2 {
+		list, err := w.store.LastResponses(ctx, w.host.ID, n)
+		if err != nil {
+			log.Printf("[ERROR] get responses for %s: %s", w.host.String(), err)
+			return
+		}
+		responses = list
+		if len(list) < n || countChecks(list) >= w.host.Flapping.Window {
+			break
+		}
+	}
+	for i := len(responses) - 1; i >= 0 && len(w.history) < w.host.Flapping.Window; i-- {
+		if r := responses[i]; r.StatusType != types.MAINTENANCE && r.StatusType != types.UNREACHABLE {
+			w.history = append([]bool{r.Status}, w.history...)
+		}
+	}
+}
+
+// countChecks - returns the number of responses which count in the flapping detection
+func countChecks(responses []*types.HttpResponse) int {
+	n := 0
+	for _, r := range responses {
+		if r.StatusType != types.MAINTENANCE && r.StatusType != types.UNREACHABLE {
+			n++
+		}
+	}
+	return n
+}
+
+// stateChange - returns the weighted percent of the state changes, the oldest change weighs 0.8 and the newest 1.2
+func stateChange(history []bool) float64 {
+	if len(history) < 3 {
+		return 0
+	}
+	changes := 0.0
+	for i := 1; i < len(history); i++ {
+		if history[i] != history[i-1] {
+			changes += 0.8 + 0.4*float64(i-1)/float64(len(history)-2)
+		}
+	}
+	return changes * 100 / float64(len(history)-1)
+}
+
+// remind - repeats the notification while the incident is open and escalates it to the additional channels
+func (w *watcher) remind(resp *types.HttpResponse) {
+	if w.incident == nil || w.notified == nil || (w.status != types.DOWN && w.status != types.DEGRADED) {
+		return
+	}
+	if w.incident.Acknowledgement() != nil { // somebody is working on it
+		return
+	}
+
+	now := time.Now()
+	var escalate, remind []string
+	for _, c := range w.channels(now.Sub(w.incident.StartTS)) {
+		last, ok := w.notified[c]
+		if !ok {
+			escalate = append(escalate, c)
+			continue
+		}
+		if interval := w.reminder(c); interval > 0 && now.Sub(last) >= interval {
+			remind = append(remind, c)
+		}
+	}
+
+	// the channels of the incident start that failed before are retried with the escalation
+	if len(escalate) > 0 {
+		log.Printf("[INFO] %s: escalate to %s", w.host.String(), strings.Join(escalate, ", "))
+		sent, err := w.notify.SendTo(&notify.Event{
+			Host:     w.host,
+			Status:   w.status,
+			Incident: w.incident,
+			Response: resp,
+		}, escalate)
+		if err != nil {
+			log.Print(err)
+		}
+		initial := w.channels(0)
+		var retried, escalated []string
+		for _, c := range sent {
+			if slices.Contains(initial, c) {
+				retried = append(retried, c)
+			} else {
+				escalated = append(escalated, c)
+			}
+			w.notified[c] = now
+		}
+		if len(retried) > 0 {
+			w.timeline(w.incident, types.IncidentEvent{Type: types.NotificationEvent, Status: w.status, Message: "sent to " + strings.Join(retried, ", "), Channels: retried})
+		}
+		if len(escalated) > 0 {
+			w.timeline(w.incident, types.IncidentEvent{Type: types.NotificationEvent, Status: w.status, Message: "escalated to " + strings.Join(escalated, ", "), Channels: escalated})
+		}
+	}
+	if len(remind) > 0 {
+		sent, err := w.notify.SendTo(&notify.Event{
+			Host:     w.host,
+			Status:   w.status,
+			Incident: w.incident,
+			Response: resp,
+			Reminder: true,
+		}, remind)
+		if err != nil {
+			log.Print(err)
+		}
+		if len(sent) > 0 {
+			w.timeline(w.incident, types.IncidentEvent{Type: types.NotificationEvent, Status: w.status, Message: "reminder sent to " + strings.Join(sent, ", "), Channels: sent})
+		}
+		for _, c := range sent {
+			w.notified[c] = now
+		}
+	}
+}
+
+// assertions - records the failed assertions of the open incident when they change
+func (w *watcher) assertions(resp *types.HttpResponse) {
+	if w.incident == nil || len(resp.Reasons) == 0 {
+		return
+	}
+	message := strings.Join(resp.Reasons, "; ")
+	for i := len(w.incident.Timeline) - 1; i >= 0; i-- {
+		if w.incident.Timeline[i].Type == types.AssertionEvent {
+			if w.incident.Timeline[i].Message == message {
+				return
+			}
+			break
+		}
+	}
+	w.timeline(w.incident, types.IncidentEvent{Type: types.AssertionEvent, Message: message})
+}
+
+// timeline - appends the event to the incident timeline, does nothing if the incident is nil
+func (w *watcher) timeline(incident *types.Incident, e types.IncidentEvent) {
+	if incident == nil {
+		return
+	}
+	if e.TS.IsZero() {
+		e.TS = time.Now()
+	}
+	incident.Timeline = append(incident.Timeline, e)
+	// the short incidents are deleted on close, so there is nothing to update
+	if err := w.store.AddIncidentEvent(w.ctx, w.host.ID, incident.ID, &e); err != nil && !errors.Is(err, types.ErrIncidentNotFound) {
+		log.Printf("[ERROR] add incident event to db %s: %s", w.host.String(), err)
+	}
+	w.snapshot.resetIncidents()
+}
+
+// responseText - returns the short description of the response for the timeline
+func responseText(resp *types.HttpResponse) string {
+	text := strconv.Itoa(resp.Code)
+	if resp.Body != "" {
+		body := resp.Body
+		if len(body) > 200 {
+			body = body[:200] + "..."
+		}
+		text += ": " + body
+	}
+	return text
+}
+
+// channels - returns the notification channels of the host and the escalation steps reached after the outage duration
+func (w *watcher) channels(outage time.Duration) []string {
+	list := append([]string{}, w.host.Alerts...)
+	if len(list) == 0 && w.notify != nil {
+		// the channels of the escalation steps are notified only after the step delay
+		for _, c := range w.notify.Clients() {
+			if !slices.ContainsFunc(w.host.Escalation, func(step types.Escalation) bool { return slices.Contains(step.Alerts, c) }) {
+				list = append(list, c)
+			}
+		}
+	}
+	for _, step := range w.host.Escalation {
+		if outage < step.After {
+			continue
+		}
+		for _, c := range step.Alerts {
+			if !slices.Contains(list, c) {
+				list = append(list, c)
+			}
+		}
+	}
+	return list
+}
+
+// reminder - returns the reminder interval of the channel, 0 if the reminders are disabled
+func (w *watcher) reminder(channel string) time.Duration {
+	if d, ok := w.host.Reminders[channel]; ok {
+		return d
+	}
+	if w.host.Reminder != nil {
+		return *w.host.Reminder
+	}
+	return 0
+}
+
+// unreachableDependents - returns the names of the dependent hosts that are unreachable.
+// The dependent that failed before this host is down notifies about its own status, and it's listed after its next check.
+func (w *watcher) unreachableDependents() []string {
+	if w.statusOf == nil {
+		return nil
+	}
+	var names []string
+	for _, h := range w.host.Dependents {
+		if w.statusOf(h.ID) != types.UNREACHABLE {
+			continue
+		}
+		name := h.URL
+		if h.Name != nil && *h.Name != "" {
+			name = *h.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// notifyDependents - sends a single notification with the list of the hosts that are unreachable because this host is down
+func (w *watcher) notifyDependents(names []string) {
+	name := w.host.URL
+	if w.host.Name != nil && *w.host.Name != "" {
+		name = *w.host.Name
+	}
+	subject := fmt.Sprintf("❌: %s is DOWN, %d dependent hosts are unreachable", name, len(names))
+	body := fmt.Sprintf("❌: `%s` is DOWN, %d dependent hosts are unreachable: %s", w.host.String(), len(names), strings.Join(names, ", "))
+
+	if err := w.notify.Message(w.host, subject, body); err != nil {
+		log.Print(err)
+	}
+}
+
+// closeIncident - finishes the current incident, very short incidents are removed
+func (w *watcher) closeIncident() {
+	now := time.Now()
+	if now.Sub(w.incident.StartTS) > time.Second {
+		if err := w.store.EndIncident(w.ctx, w.host.ID, w.incident.ID, now); err != nil {
+			log.Printf("[ERROR] end incident in db %s: %s", w.host.String(), err)
+		}
+	} else {
+		if err := w.store.DeleteIncident(w.ctx, w.host.ID, w.incident.ID); err != nil {
+			log.Printf("[ERROR] delete incident in db %s: %s", w.host.String(), err)
+		}
+	}
+	if w.notify != nil {
+		channels := w.channels(now.Sub(w.incident.StartTS))
+		for c := range w.notified {
+			if !slices.Contains(channels, c) {
+				channels = append(channels, c)
+			}
+		}
+		if err := w.notify.Resolve(w.host, w.incident, channels); err != nil {
+			log.Print(err)
+		}
+	}
+	w.incident.EndTS = &now
+	w.incident = nil
+	w.snapshot.resetIncidents()
+}
+
+// incidentEvents - returns the number of events in the timeline of the open incident, -1 if there is no open incident
+func (w *watcher) incidentEvents() int {
+	if w.incident == nil {
+		return -1
+	}
+	return len(w.incident.Timeline)
+}
+
+func (w *watcher) degradedThreshold() int {
+	if w.host.Degraded == nil || w.host.Degraded.Threshold == 0 {
+		return 1
+	}
+	return w.host.Degraded.Threshold
+}
+
+// certificate - sends a notification when the certificate expiry crosses one of the thresholds
+func (w *watcher) certificate(resp *types.HttpResponse) {
+	if resp.SSLCertExpiry == nil || len(w.host.SSLExpiry) == 0 {
+		return
+	}
+
+	days := int(time.Until(*resp.SSLCertExpiry).Hours() / 24)
+	threshold := w.expiryThreshold(*resp.SSLCertExpiry, time.Now())
+	if threshold == 0 { // certificate is renewed or far from the expiry
+		w.sslThreshold = 0
+		return
+	}
+	if w.sslThreshold != 0 && threshold >= w.sslThreshold {
+		return
+	}
+	w.sslThreshold = threshold
+
+	name := w.host.URL
+	if w.host.Name != nil && *w.host.Name != "" {
+		name = *w.host.Name
+	}
+	subject := fmt.Sprintf("⚠️: %s SSL certificate expires in %d days", name, days)
+	body := fmt.Sprintf("⚠️: `%s` SSL certificate expires in %d days (%s)", w.host.String(), days, resp.SSLCertExpiry.Format("January 2, 2006"))
+	if threshold == expiredThreshold {
+		subject = fmt.Sprintf("❌: %s SSL certificate expired", name)
+		body = fmt.Sprintf("❌: `%s` SSL certificate expired on %s", w.host.String(), resp.SSLCertExpiry.Format("January 2, 2006"))
+	}
+
+	if err := w.notify.Message(w.host, subject, body); err != nil {
+		log.Print(err)
+	}
+}
+
+// expiredThreshold - threshold of the expired certificate, lower than any configured one
+const expiredThreshold = -1
+
+// expiryThreshold - returns the smallest threshold crossed by the certificate at the time,
+// expiredThreshold if the certificate is expired or 0 if the expiry is far
+func (w *watcher) expiryThreshold(expiry, ts time.Time) int {
+	if !ts.Before(expiry) {
+		return expiredThreshold
+	}
+	days := int(expiry.Sub(ts).Hours() / 24)
+	threshold := 0
+	for _, t := range w.host.SSLExpiry {
+		if t > 0 && days <= t && (threshold == 0 || t < threshold) {
+			threshold = t
+		}
+	}
+	return threshold
+}
@@ -3,7 +3,12 @@ package monitor
 import (
 	"context"
 	"crypto/rand"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -213,6 +218,925 @@ func TestWatcher_validate(t *testing.T) {
 	})
 }
 
+func TestWatcher_degraded(t *testing.T) {
+	ctx := context.Background()
+	limit := time.Millisecond * 100
+	w := &watcher{
+		notify: &notify.Notify{},
+		store:  store.NewMemory(ctx),
+		host: &types.Host{
+			ID: id(),
+			Conditions: &types.Success{
+				Code: []int{200},
+				Assertions: []types.Assertion{
+					{NotContains: "read-only", NonCritical: true},
+				},
+			},
+			Degraded: &types.Degradation{
+				ResponseTime: &limit,
+				Threshold:    2,
+			},
+			SuccessThreshold: 1,
+			FailureThreshold: 1,
+		},
+		ctx: ctx,
+	}
+	fast := &types.HttpResponse{Code: 200, Time: time.Millisecond * 10}
+	slow := func() *types.HttpResponse {
+		return &types.HttpResponse{Code: 200, Time: time.Millisecond * 200}
+	}
+
+	w.process(fast)
+	require.Equal(t, types.UP, w.status)
+
+	w.process(slow())
+	require.Equal(t, types.UP, w.status)
+	w.process(slow())
+	require.Equal(t, types.DEGRADED, w.status)
+	require.NotNil(t, w.incident)
+	require.Equal(t, types.DEGRADED, w.incident.Severity)
+	require.Equal(t, []string{"response time 200ms exceeds 100ms"}, w.incident.Details.Reasons)
+
+	w.process(&types.HttpResponse{Code: 500})
+	require.Equal(t, types.DOWN, w.status)
+	require.Equal(t, types.DOWN, w.incident.Severity)
+
+	w.process(&types.HttpResponse{Code: 200, Bytes: []byte("database is read-only")})
+	require.Equal(t, types.UP, w.status)
+	require.Nil(t, w.incident)
+	w.process(&types.HttpResponse{Code: 200, Bytes: []byte("database is read-only")})
+	require.Equal(t, types.DEGRADED, w.status)
+
+	w.process(&types.HttpResponse{Code: 200})
+	require.Equal(t, types.UP, w.status)
+	require.Nil(t, w.incident)
+}
+
+func TestWatcher_maintenance(t *testing.T) {
+	ctx := context.Background()
+	start, end := time.Now().Add(-time.Minute), time.Now().Add(time.Minute)
+	window := &types.Maintenance{Name: "deploy", Start: &start, End: &end}
+	require.NoError(t, window.Validate())
+
+	w := &watcher{
+		notify: &notify.Notify{},
+		store:  store.NewMemory(ctx),
+		host: &types.Host{
+			ID:               id(),
+			Conditions:       &types.Success{Code: []int{200}},
+			SuccessThreshold: 1,
+			FailureThreshold: 1,
+		},
+		ctx: ctx,
+	}
+
+	w.process(&types.HttpResponse{Code: 200})
+	require.Equal(t, types.UP, w.status)
+
+	w.host.MaintenanceWindows = []*types.Maintenance{window}
+	w.process(&types.HttpResponse{Code: 500})
+	require.Equal(t, types.MAINTENANCE, w.status)
+	require.Nil(t, w.incident)
+	w.process(&types.HttpResponse{Code: 500})
+	require.Equal(t, types.MAINTENANCE, w.status)
+	require.Nil(t, w.incident)
+
+	incidents, err := w.store.FindIncidents(ctx, w.host.ID, 0, 10)
+	require.NoError(t, err)
+	require.Empty(t, incidents)
+	last, err := w.store.LastResponse(ctx, w.host.ID)
+	require.NoError(t, err)
+	require.Equal(t, types.MAINTENANCE, last.StatusType)
+	require.False(t, last.Status)
+
+	t.Run("host is still down after the window", func(t *testing.T) {
+		w.host.MaintenanceWindows = nil
+		w.process(&types.HttpResponse{Code: 500})
+		require.Equal(t, types.DOWN, w.status)
+		require.NotNil(t, w.incident)
+	})
+
+	t.Run("open incident is closed when the window starts", func(t *testing.T) {
+		w.incident.StartTS = time.Now().Add(-time.Minute)
+		incidentID := w.incident.ID
+
+		w.host.MaintenanceWindows = []*types.Maintenance{window}
+		w.process(&types.HttpResponse{Code: 500})
+		require.Equal(t, types.MAINTENANCE, w.status)
+		require.Nil(t, w.incident)
+		require.Nil(t, w.notified)
+
+		incident, err := w.store.FindIncident(ctx, w.host.ID, incidentID)
+		require.NoError(t, err)
+		require.NotNil(t, incident.EndTS)
+		require.Equal(t, types.MAINTENANCE, incident.Timeline[len(incident.Timeline)-1].Status)
+
+		w.host.MaintenanceWindows = nil
+		w.process(&types.HttpResponse{Code: 500})
+		require.Equal(t, types.DOWN, w.status)
+		require.NotNil(t, w.incident)
+		require.NotEqual(t, incidentID, w.incident.ID)
+	})
+
+	t.Run("host recovered during the window", func(t *testing.T) {
+		w.host.MaintenanceWindows = []*types.Maintenance{window}
+		w.process(&types.HttpResponse{Code: 200})
+		require.Equal(t, types.MAINTENANCE, w.status)
+		require.Nil(t, w.incident)
+
+		w.host.MaintenanceWindows = nil
+		w.process(&types.HttpResponse{Code: 200})
+		require.Equal(t, types.UP, w.status)
+		require.Nil(t, w.incident)
+	})
+}
+
+func TestWatcher_unreachable(t *testing.T) {
+	ctx := context.Background()
+	parent := types.DOWN
+	w := &watcher{
+		notify: &notify.Notify{},
+		store:  store.NewMemory(ctx),
+		host: &types.Host{
+			ID:               id(),
+			Conditions:       &types.Success{Code: []int{200}},
+			SuccessThreshold: 1,
+			FailureThreshold: 1,
+			Parents:          []string{"parent"},
+		},
+		statusOf: func(id string) types.StatusType {
+			require.Equal(t, "parent", id)
+			return parent
+		},
+		ctx: ctx,
+	}
+
+	w.process(&types.HttpResponse{Code: 200})
+	require.Equal(t, types.UP, w.status)
+
+	w.process(&types.HttpResponse{Code: 523})
+	require.Equal(t, types.UNREACHABLE, w.status)
+	require.Nil(t, w.incident)
+
+	parent = types.UNREACHABLE
+	w.process(&types.HttpResponse{Code: 523})
+	require.Equal(t, types.UNREACHABLE, w.status)
+	require.Nil(t, w.incident)
+
+	incidents, err := w.store.FindIncidents(ctx, w.host.ID, 0, 10)
+	require.NoError(t, err)
+	require.Empty(t, incidents)
+
+	parent = types.UP
+	w.process(&types.HttpResponse{Code: 200})
+	require.Equal(t, types.UP, w.status)
+	require.Nil(t, w.incident)
+
+	parent = types.UP
+	w.process(&types.HttpResponse{Code: 500})
+	require.Equal(t, types.DOWN, w.status)
+	require.NotNil(t, w.incident)
+
+	t.Run("host is up while parent is down", func(t *testing.T) {
+		parent = types.DOWN
+		w.process(&types.HttpResponse{Code: 200})
+		require.Equal(t, types.UP, w.status)
+	})
+}
+
+func TestWatcher_notifyDependents(t *testing.T) {
+	ctx := context.Background()
+	var messages []map[string]interface{}
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		msg := map[string]interface{}{}
+		_ = json.Unmarshal(b, &msg)
+		mu.Lock()
+		messages = append(messages, msg)
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	disabled := false
+	n, err := notify.New(ctx, &types.Cfg{
+		Notifications: types.Notifications{
+			Webhook:               &types.Webhook{URL: ts.URL},
+			InitializationMessage: &disabled,
+		},
+	})
+	require.NoError(t, err)
+
+	db, api := "db", "api"
+	statuses := map[string]types.StatusType{}
+	w := &watcher{
+		notify: n,
+		store:  store.NewMemory(ctx),
+		host: &types.Host{
+			ID:               id(),
+			URL:              "http://router",
+			Conditions:       &types.Success{Code: []int{200}},
+			SuccessThreshold: 1,
+			FailureThreshold: 1,
+			Dependents: []*types.Host{
+				{ID: "db", URL: "http://db", Name: &db},
+				{ID: "api", URL: "http://api", Name: &api},
+			},
+		},
+		statusOf: func(id string) types.StatusType {
+			return statuses[id]
+		},
+		ctx: ctx,
+	}
+
+	w.process(&types.HttpResponse{Code: 200})
+	require.Len(t, messages, 1)
+
+	// the dependents are not checked yet
+	w.process(&types.HttpResponse{Code: 500})
+	require.Len(t, messages, 2)
+	require.Equal(t, "down", messages[1]["status"])
+
+	statuses["api"] = types.UNREACHABLE
+	w.process(&types.HttpResponse{Code: 500})
+	require.Len(t, messages, 3)
+	require.Equal(t, "❌: http://router is DOWN, 1 dependent hosts are unreachable", messages[2]["subject"])
+	require.Contains(t, messages[2]["message"], ": api")
+
+	// sent once per outage
+	statuses["db"] = types.UNREACHABLE
+	w.process(&types.HttpResponse{Code: 500})
+	require.Len(t, messages, 3)
+
+	statuses = map[string]types.StatusType{}
+	w.process(&types.HttpResponse{Code: 200})
+	require.Len(t, messages, 4)
+	require.Equal(t, "up", messages[3]["status"])
+
+	t.Run("dependent fails first", func(t *testing.T) {
+		mu.Lock()
+		messages = nil
+		mu.Unlock()
+
+		watchers := map[string]*watcher{}
+		statusOf := func(id string) types.StatusType {
+			return watchers[id].hostStatus().Status
+		}
+		router := &types.Host{
+			ID:               "router",
+			URL:              "http://router",
+			Conditions:       &types.Success{Code: []int{200}},
+			SuccessThreshold: 1,
+			FailureThreshold: 2,
+		}
+		child := &types.Host{
+			ID:               "db",
+			URL:              "http://db",
+			Conditions:       &types.Success{Code: []int{200}},
+			SuccessThreshold: 1,
+			FailureThreshold: 1,
+			Parents:          []string{"router"},
+		}
+		router.Dependents = []*types.Host{child}
+		for _, h := range []*types.Host{router, child} {
+			watchers[h.ID] = &watcher{notify: n, store: store.NewMemory(ctx), host: h, statusOf: statusOf, ctx: ctx}
+		}
+
+		watchers["router"].process(&types.HttpResponse{Code: 200})
+		watchers["db"].process(&types.HttpResponse{Code: 200})
+		require.Len(t, messages, 2)
+
+		// the router needs two failures, so the dependent is down before it
+		watchers["router"].process(&types.HttpResponse{Code: 500})
+		watchers["db"].process(&types.HttpResponse{Code: 500})
+		require.Equal(t, types.DOWN, watchers["db"].status)
+		require.Len(t, messages, 3)
+		require.Equal(t, "db", messages[2]["hostID"])
+		require.Equal(t, "down", messages[2]["status"])
+
+		watchers["router"].process(&types.HttpResponse{Code: 500})
+		require.Equal(t, types.DOWN, watchers["router"].status)
+		require.Len(t, messages, 4)
+
+		watchers["db"].process(&types.HttpResponse{Code: 500})
+		require.Equal(t, types.UNREACHABLE, watchers["db"].status)
+		require.Len(t, messages, 4)
+
+		watchers["router"].process(&types.HttpResponse{Code: 500})
+		require.Len(t, messages, 5)
+		require.Equal(t, "❌: http://router is DOWN, 1 dependent hosts are unreachable", messages[4]["subject"])
+		require.Contains(t, messages[4]["message"], ": http://db")
+	})
+}
+
+func TestWatcher_remind(t *testing.T) {
+	ctx := context.Background()
+	var messages []map[string]interface{}
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		msg := map[string]interface{}{}
+		_ = json.Unmarshal(b, &msg)
+		mu.Lock()
+		messages = append(messages, msg)
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	disabled := false
+	n, err := notify.New(ctx, &types.Cfg{
+		Notifications: types.Notifications{
+			Webhook:               &types.Webhook{URL: ts.URL},
+			InitializationMessage: &disabled,
+		},
+	})
+	require.NoError(t, err)
+
+	reminder := time.Hour
+	w := &watcher{
+		notify: n,
+		store:  store.NewMemory(ctx),
+		host: &types.Host{
+			ID:               id(),
+			URL:              "http://host",
+			Conditions:       &types.Success{Code: []int{200}},
+			SuccessThreshold: 1,
+			FailureThreshold: 1,
+			Alerts:           []string{"slack"},
+			Reminder:         &reminder,
+			Reminders:        map[string]time.Duration{"webhook": 10 * time.Minute},
+			Escalation: []types.Escalation{
+				{After: 15 * time.Minute, Alerts: []string{"webhook"}},
+			},
+		},
+		ctx: ctx,
+	}
+
+	w.process(&types.HttpResponse{Code: 200})
+	w.process(&types.HttpResponse{Code: 500})
+	require.Equal(t, types.DOWN, w.status)
+	require.Empty(t, messages)
+
+	w.incident.StartTS = time.Now().Add(-20 * time.Minute)
+	w.process(&types.HttpResponse{Code: 500})
+	require.Len(t, messages, 1)
+	require.Equal(t, "down", messages[0]["status"])
+	require.Nil(t, messages[0]["reminder"])
+
+	w.process(&types.HttpResponse{Code: 500})
+	require.Len(t, messages, 1)
+
+	w.notified["webhook"] = time.Now().Add(-11 * time.Minute)
+	w.process(&types.HttpResponse{Code: 500})
+	require.Len(t, messages, 2)
+	require.Equal(t, true, messages[1]["reminder"])
+	require.Equal(t, "⏰ ❌: http://host is DOWN for 20m", messages[1]["subject"])
+	require.Equal(t, time.Hour, w.reminder("telegram"))
+	require.Equal(t, 10*time.Minute, w.reminder("webhook"))
+
+	w.incident.Timeline = append(w.incident.Timeline, types.IncidentEvent{Type: types.AckEvent, TS: time.Now()}) // the store is updated by the monitor
+	w.notified["webhook"] = time.Now().Add(-11 * time.Minute)
+	w.process(&types.HttpResponse{Code: 500})
+	require.Len(t, messages, 2)
+
+	w.process(&types.HttpResponse{Code: 200})
+	require.Len(t, messages, 3)
+	require.Equal(t, "up", messages[2]["status"])
+	require.Nil(t, w.notified)
+
+	incidents, err := w.store.FindIncidents(ctx, w.host.ID, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, incidents, 1)
+	var timeline []string
+	for _, e := range incidents[0].Timeline {
+		timeline = append(timeline, fmt.Sprintf("%s %s %s", e.Type, e.Status, e.Message))
+	}
+	require.Equal(t, []string{
+		"status down 500",
+		"notification down escalated to webhook",
+		"notification down reminder sent to webhook",
+		"status up ",
+		"notification up sent to webhook",
+	}, timeline)
+
+	w.process(&types.HttpResponse{Code: 200})
+	require.Len(t, messages, 3)
+
+	t.Run("escalation channels are not the default ones", func(t *testing.T) {
+		w := &watcher{
+			notify: n,
+			host: &types.Host{
+				Escalation: []types.Escalation{
+					{After: 15 * time.Minute, Alerts: []string{"webhook"}},
+				},
+			},
+		}
+		require.Empty(t, w.channels(0))
+		require.Equal(t, []string{"webhook"}, w.channels(20*time.Minute))
+
+		w.host.Escalation = nil
+		require.Equal(t, []string{"webhook"}, w.channels(0))
+	})
+
+	t.Run("failed channel is retried", func(t *testing.T) {
+		failed := true
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			if failed {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}))
+		defer ts.Close()
+		n, err := notify.New(ctx, &types.Cfg{
+			Notifications: types.Notifications{
+				Webhook:               &types.Webhook{URL: ts.URL},
+				InitializationMessage: &disabled,
+			},
+		})
+		require.NoError(t, err)
+
+		w := &watcher{
+			notify: n,
+			store:  store.NewMemory(ctx),
+			host: &types.Host{
+				ID:               id(),
+				URL:              "http://host",
+				Conditions:       &types.Success{Code: []int{200}},
+				SuccessThreshold: 1,
+				FailureThreshold: 1,
+			},
+			ctx: ctx,
+		}
+		w.process(&types.HttpResponse{Code: 200})
+		w.process(&types.HttpResponse{Code: 500})
+		require.Equal(t, types.DOWN, w.status)
+		require.Empty(t, w.notified)
+		w.incident.StartTS = time.Now().Add(-time.Minute) // the short incidents are deleted on close
+
+		mu.Lock()
+		failed = false
+		mu.Unlock()
+		w.process(&types.HttpResponse{Code: 500})
+		require.Contains(t, w.notified, "webhook")
+
+		incidents, err := w.store.FindIncidents(ctx, w.host.ID, 0, 0)
+		require.NoError(t, err)
+		var timeline []string
+		for _, e := range incidents[0].Timeline {
+			timeline = append(timeline, fmt.Sprintf("%s %s %s", e.Type, e.Status, e.Message))
+		}
+		require.Equal(t, []string{"status down 500", "notification down sent to webhook"}, timeline)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		interval := time.Minute
+		w := &watcher{
+			notify: n,
+			store:  store.NewMemory(ctx),
+			host: &types.Host{
+				ID:       id(),
+				Type:     types.PushType,
+				Interval: &interval,
+				Reminder: &reminder,
+			},
+		}
+		start := time.Now().Add(-3 * time.Hour)
+		require.NoError(t, w.store.AddIncident(ctx, w.host.ID, &types.Incident{
+			StartTS: start,
+			Timeline: []types.IncidentEvent{
+				{Type: types.StatusEvent, Status: types.DOWN, TS: start},
+				{Type: types.NotificationEvent, Status: types.DOWN, Channels: []string{"webhook"}, TS: start},
+				{Type: types.NotificationEvent, Status: types.DOWN, Channels: []string{"webhook"}, TS: start.Add(2 * time.Hour)},
+			},
+		}))
+
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		w.run(ctx)
+		require.Equal(t, map[string]time.Time{"webhook": start.Add(2 * time.Hour)}, w.notified)
+
+		// nothing was delivered before the restart, so the channels are notified with the next check
+		w.store = store.NewMemory(ctx)
+		require.NoError(t, w.store.AddIncident(ctx, w.host.ID, &types.Incident{
+			StartTS:  start,
+			Timeline: []types.IncidentEvent{{Type: types.StatusEvent, Status: types.DOWN, TS: start}},
+		}))
+		w.run(ctx)
+		require.Empty(t, w.notified)
+	})
+}
+
+func TestWatcher_flap(t *testing.T) {
+	ctx := context.Background()
+	var messages []map[string]interface{}
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		msg := map[string]interface{}{}
+		_ = json.Unmarshal(b, &msg)
+		mu.Lock()
+		messages = append(messages, msg)
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	disabled := false
+	n, err := notify.New(ctx, &types.Cfg{
+		Notifications: types.Notifications{
+			Webhook:               &types.Webhook{URL: ts.URL},
+			InitializationMessage: &disabled,
+		},
+	})
+	require.NoError(t, err)
+
+	w := &watcher{
+		notify: n,
+		store:  store.NewMemory(ctx),
+		host: &types.Host{
+			ID:               id(),
+			URL:              "http://host",
+			Conditions:       &types.Success{Code: []int{200}},
+			SuccessThreshold: 1,
+			FailureThreshold: 1,
+			Flapping:         &types.Flapping{Window: 5, High: 50, Low: 25},
+		},
+		ctx: ctx,
+	}
+
+	for _, code := range []int{200, 500, 200, 500} {
+		w.process(&types.HttpResponse{Code: code})
+	}
+	require.Equal(t, types.DOWN, w.status)
+	require.Len(t, messages, 4)
+	require.NotNil(t, w.incident)
+
+	w.process(&types.HttpResponse{Code: 200})
+	require.Equal(t, types.FLAPPING, w.status)
+	require.Len(t, messages, 5)
+	require.Equal(t, "flapping", messages[4]["status"])
+	require.Equal(t, "down", messages[4]["oldStatus"])
+	var flapping []string
+	for _, e := range w.incident.Timeline {
+		if e.Status == types.FLAPPING {
+			flapping = append(flapping, e.Message)
+		}
+	}
+	require.Equal(t, []string{"100.0% state change, reminders are paused", "sent to webhook"}, flapping)
+
+	w.process(&types.HttpResponse{Code: 200})
+	w.process(&types.HttpResponse{Code: 200})
+	require.Equal(t, types.FLAPPING, w.status)
+	require.Len(t, messages, 5)
+
+	w.process(&types.HttpResponse{Code: 200})
+	require.Equal(t, types.UP, w.status)
+	require.Len(t, messages, 6)
+	require.Equal(t, "up", messages[5]["status"])
+	require.Equal(t, "flapping", messages[5]["oldStatus"])
+	require.NotNil(t, messages[5]["resolved"])
+	require.Nil(t, w.incident)
+
+	w.process(&types.HttpResponse{Code: 500})
+	require.Equal(t, types.DOWN, w.status)
+	require.Len(t, messages, 7)
+
+	t.Run("channels", func(t *testing.T) {
+		messages = nil
+		w := &watcher{
+			notify: n,
+			store:  store.NewMemory(ctx),
+			host: &types.Host{
+				ID:               id(),
+				URL:              "http://host",
+				Conditions:       &types.Success{Code: []int{200}},
+				SuccessThreshold: 1,
+				FailureThreshold: 1,
+				Flapping:         &types.Flapping{Window: 5, High: 50, Low: 25},
+				Alerts:           []string{"slack"},
+			},
+			ctx: ctx,
+		}
+
+		// the host is notified via slack only, so the webhook receives neither the flapping nor the recovery
+		for _, code := range []int{200, 500, 200, 500, 200} {
+			w.process(&types.HttpResponse{Code: code})
+		}
+		require.Equal(t, types.FLAPPING, w.status)
+		for _, code := range []int{200, 200, 200} {
+			w.process(&types.HttpResponse{Code: code})
+		}
+		require.Equal(t, types.UP, w.status)
+		require.Empty(t, messages)
+	})
+}
+
+func TestWatcher_pagerDuty(t *testing.T) {
+	ctx := context.Background()
+	var actions, keys []string
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e struct {
+			EventAction string `json:"event_action"`
+			DedupKey    string `json:"dedup_key"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&e)
+		mu.Lock()
+		actions = append(actions, e.EventAction)
+		keys = append(keys, e.DedupKey)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	disabled := false
+	n, err := notify.New(ctx, &types.Cfg{
+		Notifications: types.Notifications{
+			PagerDuty:             &types.PagerDuty{RoutingKey: "key", URL: ts.URL},
+			InitializationMessage: &disabled,
+		},
+	})
+	require.NoError(t, err)
+
+	w := &watcher{
+		notify: n,
+		store:  store.NewMemory(ctx),
+		host: &types.Host{
+			ID:               id(),
+			URL:              "http://host",
+			Conditions:       &types.Success{Code: []int{200}},
+			SuccessThreshold: 1,
+			FailureThreshold: 1,
+		},
+		ctx: ctx,
+	}
+
+	// the host fails twice, every incident triggers its own alert and resolves it when it's closed
+	for _, code := range []int{200, 500, 200, 500, 200} {
+		w.process(&types.HttpResponse{Code: code})
+	}
+	require.Equal(t, types.UP, w.status)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"trigger", "resolve", "trigger", "resolve"}, actions)
+	require.Equal(t, keys[0], keys[1])
+	require.Equal(t, keys[2], keys[3])
+}
+
+func TestStateChange(t *testing.T) {
+	require.Equal(t, 0.0, stateChange([]bool{true, false}))
+	require.Equal(t, 0.0, stateChange([]bool{true, true, true, true}))
+	require.InDelta(t, 100, stateChange([]bool{true, false, true}), 0.001)
+	require.InDelta(t, 20, stateChange([]bool{false, true, true, true, true}), 0.001)
+	require.InDelta(t, 30, stateChange([]bool{true, true, true, true, false}), 0.001)
+}
+
+func TestWatcher_process(t *testing.T) {
+	ctx := context.Background()
+	w := &watcher{
+		notify: &notify.Notify{},
+		store:  store.NewMemory(ctx),
+		host: &types.Host{
+			ID: id(),
+			Conditions: &types.Success{
+				Code: []int{200},
+				Assertions: []types.Assertion{
+					{JSONPath: "$.status", Value: "ok"},
+				},
+			},
+			SuccessThreshold: 1,
+			FailureThreshold: 1,
+		},
+		ctx: ctx,
+	}
+
+	w.process(&types.HttpResponse{Code: 200, Bytes: []byte(`{"status": "ok"}`)})
+	require.Equal(t, types.UP, w.status)
+
+	w.process(&types.HttpResponse{Code: 200, Bytes: []byte(`{"status": "fail"}`)})
+	require.Equal(t, types.DOWN, w.status)
+	require.NotNil(t, w.incident)
+	require.Equal(t, []string{`json $.status: expected eq "ok", got "fail"`}, w.incident.Details.Reasons)
+
+	last, err := w.store.LastResponse(ctx, w.host.ID)
+	require.NoError(t, err)
+	require.Empty(t, last.Bytes)
+	require.Len(t, last.Reasons, 1)
+}
+
+func TestWatcher_changed(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	w := &watcher{
+		notify: &notify.Notify{},
+		store:  store.NewMemory(ctx),
+		host: &types.Host{
+			ID:               id(),
+			URL:              "http://host",
+			Conditions:       &types.Success{Code: []int{200}},
+			SuccessThreshold: 1,
+			FailureThreshold: 2,
+		},
+		changed: func() { calls++ },
+		ctx:     ctx,
+	}
+
+	w.process(&types.HttpResponse{Code: 200})
+	require.Equal(t, 1, calls)
+	w.process(&types.HttpResponse{Code: 200})
+	w.process(&types.HttpResponse{Code: 500})
+	require.Equal(t, 1, calls)
+	w.process(&types.HttpResponse{Code: 500})
+	require.Equal(t, types.DOWN, w.status)
+	require.Equal(t, 2, calls)
+	w.process(&types.HttpResponse{Code: 500})
+	require.Equal(t, 2, calls)
+
+	w.process(&types.HttpResponse{Code: 200})
+	require.Equal(t, types.UP, w.status)
+	require.Equal(t, 3, calls)
+}
+
+func TestWatcher_push(t *testing.T) {
+	ctx := context.Background()
+	interval, grace := time.Minute, time.Second*10
+
+	w := &watcher{
+		notify: &notify.Notify{},
+		store:  store.NewMemory(ctx),
+		host: &types.Host{
+			ID:               id(),
+			Type:             types.PushType,
+			Token:            "secret",
+			SuccessThreshold: 1,
+			FailureThreshold: 1,
+			Interval:         &interval,
+			GracePeriod:      &grace,
+		},
+		ctx:      ctx,
+		lastPush: time.Now(),
+	}
+
+	w.check()
+	last, err := w.store.LastResponse(ctx, w.host.ID)
+	require.NoError(t, err)
+	require.Nil(t, last)
+
+	w.push(true, "")
+	require.Equal(t, types.UP, w.status)
+
+	w.push(false, "backup failed")
+	require.Equal(t, types.DOWN, w.status)
+
+	w.push(true, "")
+	require.Equal(t, types.UP, w.status)
+
+	w.lastPush = time.Now().Add(-interval)
+	w.check()
+	require.Equal(t, types.UP, w.status)
+
+	w.lastPush = time.Now().Add(-interval - grace - time.Second)
+	w.check()
+	require.Equal(t, types.DOWN, w.status)
+
+	history, err := w.store.FindResponses(ctx, w.host.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+	require.Equal(t, "backup failed", history[1].Body)
+	require.Equal(t, http.StatusRequestTimeout, history[3].Code)
+}
+
+func TestWatcher_certificate(t *testing.T) {
+	w := &watcher{
+		notify: &notify.Notify{},
+		host: &types.Host{
+			SSLExpiry: []int{30, 14, 7},
+		},
+	}
+	expiry := func(days int) *types.HttpResponse {
+		ts := time.Now().Add(time.Hour*24*time.Duration(days) + time.Hour)
+		return &types.HttpResponse{SSLCertExpiry: &ts}
+	}
+
+	w.certificate(expiry(60))
+	require.Equal(t, 0, w.sslThreshold)
+
+	w.certificate(expiry(25))
+	require.Equal(t, 30, w.sslThreshold)
+	w.certificate(expiry(20))
+	require.Equal(t, 30, w.sslThreshold)
+
+	w.certificate(expiry(10))
+	require.Equal(t, 14, w.sslThreshold)
+
+	w.certificate(expiry(3))
+	require.Equal(t, 7, w.sslThreshold)
+
+	w.certificate(expiry(90))
+	require.Equal(t, 0, w.sslThreshold)
+
+	t.Run("expired after the warning", func(t *testing.T) {
+		ctx := context.Background()
+		var subjects []string
+		var mu sync.Mutex
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			msg := map[string]interface{}{}
+			_ = json.NewDecoder(r.Body).Decode(&msg)
+			mu.Lock()
+			subjects = append(subjects, fmt.Sprint(msg["subject"]))
+			mu.Unlock()
+		}))
+		defer ts.Close()
+
+		disabled := false
+		n, err := notify.New(ctx, &types.Cfg{
+			Notifications: types.Notifications{
+				Webhook:               &types.Webhook{URL: ts.URL},
+				InitializationMessage: &disabled,
+			},
+		})
+		require.NoError(t, err)
+
+		w := &watcher{
+			notify: n,
+			host: &types.Host{
+				URL:       "https://host",
+				SSLExpiry: []int{30, 14, 7},
+			},
+		}
+		w.certificate(expiry(3))
+		require.Equal(t, 7, w.sslThreshold)
+
+		expired := time.Now().Add(-time.Hour)
+		w.certificate(&types.HttpResponse{SSLCertExpiry: &expired})
+		require.Equal(t, expiredThreshold, w.sslThreshold)
+		w.certificate(&types.HttpResponse{SSLCertExpiry: &expired})
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, []string{
+			"⚠️: https://host SSL certificate expires in 3 days",
+			"❌: https://host SSL certificate expired",
+		}, subjects)
+	})
+
+	t.Run("saved on change", func(t *testing.T) {
+		ctx := context.Background()
+		w := &watcher{
+			notify: &notify.Notify{},
+			store:  store.NewMemory(ctx),
+			host: &types.Host{
+				ID:               id(),
+				Conditions:       &types.Success{Code: []int{200}},
+				SuccessThreshold: 1,
+				FailureThreshold: 1,
+			},
+			ctx: ctx,
+		}
+		cert := func(issuer string) *types.Certificate {
+			return &types.Certificate{Subject: "CN=host", Issuer: issuer, DNSNames: []string{"host"}}
+		}
+
+		now := time.Now()
+		for i, issuer := range []string{"R3", "R3", "R3", "R10"} {
+			w.process(&types.HttpResponse{Timestamp: now.Add(time.Second * time.Duration(i)), Code: 200, Certificate: cert(issuer)})
+		}
+
+		history, err := w.store.FindResponses(ctx, w.host.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 4)
+		require.Equal(t, cert("R3"), history[0].Certificate)
+		require.Nil(t, history[1].Certificate)
+		require.Nil(t, history[2].Certificate)
+		require.Equal(t, cert("R10"), history[3].Certificate)
+		require.Equal(t, cert("R10"), w.lastResponse.Certificate)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		ctx := context.Background()
+		interval := time.Minute
+		w := &watcher{
+			notify: &notify.Notify{},
+			store:  store.NewMemory(ctx),
+			host: &types.Host{
+				ID:        id(),
+				Type:      types.PushType,
+				Interval:  &interval,
+				SSLExpiry: []int{30, 14, 7},
+			},
+		}
+		ts := time.Now().Add(-time.Hour)
+		r := expiry(10)
+		r.Timestamp, r.StatusType = ts, types.UP
+		require.NoError(t, w.store.AddResponse(ctx, w.host.ID, r))
+
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		w.run(ctx)
+		require.Equal(t, 14, w.sslThreshold)
+	})
+}
+
 func id() string {
 	n := 12
 	b := make([]byte, n)
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/exelban/JAM/types"
+)
+
+type Discord struct {
+	url     string
+	baseURL string
+	hideURL bool
+
+	timeout time.Duration
+}
+
+type discordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds,omitempty"`
+}
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	URL         string         `json:"url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+}
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+func (d *Discord) string() string {
+	return "discord"
+}
+
+func (d *Discord) send(subject, body string) error {
+	if err := postJSON(d.url, discordMessage{Content: body}, d.timeout); err != nil {
+		return fmt.Errorf("discord: %w", err)
+	}
+	return nil
+}
+
+func (d *Discord) event(e *Event) error {
+	subject, _ := e.remind(d.normalize(e.Host, e.Status))
+
+	embed := discordEmbed{
+		Title:     subject,
+		URL:       hostLink(d.baseURL, e.Host),
+		Color:     discordColor(e.Status),
+		Timestamp: e.Timestamp.Format(time.RFC3339),
+		Fields: []discordField{
+			{Name: "Host", Value: hostName(e.Host, d.hideURL), Inline: true},
+			{Name: "Status", Value: strings.ToUpper(string(e.Status)), Inline: true},
+		},
+	}
+	if e.Host.Description != nil && *e.Host.Description != "" {
+		embed.Description = *e.Host.Description
+	}
+	if e.Response != nil && e.Response.Code != 0 {
+		embed.Fields = append(embed.Fields, discordField{Name: "Status code", Value: strconv.Itoa(e.Response.Code), Inline: true})
+	}
+	if !d.hideURL {
+		embed.Fields = append(embed.Fields, discordField{Name: "URL", Value: e.Host.SecureURL()})
+	}
+
+	if err := postJSON(d.url, discordMessage{Embeds: []discordEmbed{embed}}, d.timeout); err != nil {
+		return fmt.Errorf("discord: %w", err)
+	}
+	return nil
+}
+
+func (d *Discord) normalize(host *types.Host, status types.StatusType) (string, string) {
+	icon := statusIcon(status)
+
+	name := hostName(host, d.hideURL)
+	if !d.hideURL && host.Name != nil && *host.Name != "" {
+		name = fmt.Sprintf("%s (%s)", name, host.SecureURL())
+	}
+
+	text := fmt.Sprintf("%s: `%s` has a new status: %s", icon, name, strings.ToUpper(string(status)))
+	subject := fmt.Sprintf("%s: %s is %s", icon, hostName(host, d.hideURL), strings.ToUpper(string(status)))
+
+	return subject, text
+}
+
+// discordColor - returns the embed color for the status
+func discordColor(status types.StatusType) int {
+	switch status {
+	case types.UP:
+		return 0x2ecc71
+	case types.DEGRADED, types.FLAPPING:
+		return 0xf1c40f
+	default:
+		return 0xe74c3c
+	}
+}
@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/exelban/JAM/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscord_send(t *testing.T) {
+	var req discordMessage
+	router := http.NewServeMux()
+	router.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		req = discordMessage{}
+		_ = json.Unmarshal(b, &req)
+
+		if req.Content == "timeout" {
+			time.Sleep(time.Millisecond * 20)
+		} else if req.Content == "error" {
+			http.Error(w, "error", http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+	ts := httptest.NewServer(router)
+	defer func() {
+		ts.Close()
+	}()
+
+	discord := &Discord{
+		url:     ts.URL,
+		timeout: time.Millisecond * 10,
+	}
+
+	require.NoError(t, discord.send("", "test"))
+	require.Equal(t, "test", req.Content)
+	require.Error(t, discord.send("", "error"))
+	require.Error(t, discord.send("", "timeout"))
+}
+
+func TestDiscord_event(t *testing.T) {
+	var req discordMessage
+	router := http.NewServeMux()
+	router.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		req = discordMessage{}
+		_ = json.Unmarshal(b, &req)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	name := "API"
+	e := &Event{
+		Host:      &types.Host{ID: "abc", Name: &name, URL: "https://api.example.com"},
+		Status:    types.DOWN,
+		Response:  &types.HttpResponse{Code: 503},
+		Timestamp: time.Now(),
+	}
+
+	t.Run("with url", func(t *testing.T) {
+		discord := &Discord{url: ts.URL, baseURL: "https://status.example.com/", timeout: time.Second}
+		require.NoError(t, discord.event(e))
+		require.Len(t, req.Embeds, 1)
+
+		embed := req.Embeds[0]
+		require.Equal(t, "https://status.example.com/abc", embed.URL)
+		require.Contains(t, embed.Title, "API is DOWN")
+		require.Equal(t, discordColor(types.DOWN), embed.Color)
+		require.Contains(t, embed.Fields, discordField{Name: "Status", Value: "DOWN", Inline: true})
+		require.Contains(t, embed.Fields, discordField{Name: "Status code", Value: "503", Inline: true})
+		require.Contains(t, embed.Fields, discordField{Name: "URL", Value: "https://api.example.com"})
+	})
+
+	t.Run("hide url", func(t *testing.T) {
+		discord := &Discord{url: ts.URL, hideURL: true, timeout: time.Second}
+		require.NoError(t, discord.event(e))
+		require.Len(t, req.Embeds, 1)
+
+		b, _ := json.Marshal(req)
+		require.NotContains(t, string(b), "api.example.com")
+		require.Empty(t, req.Embeds[0].URL)
+	})
+}
@@ -1,9 +1,14 @@
 package notify
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -19,6 +24,39 @@ type notify interface {
 	normalize(host *types.Host, status types.StatusType) (string, string)
 }
 
+// eventer - client that sends the whole event instead of the normalized message
+type eventer interface {
+	event(e *Event) error
+}
+
+// resolver - client that resolves its alert when the incident is closed
+type resolver interface {
+	resolve(host *types.Host, incident *types.Incident) error
+}
+
+// Event - change of the host status
+type Event struct {
+	Host      *types.Host
+	OldStatus types.StatusType
+	Status    types.StatusType
+	Incident  *types.Incident     // open incident of the host after the change
+	Resolved  *types.Incident     // incident closed by the change
+	Response  *types.HttpResponse // response that caused the change
+	Reminder  bool                // the status did not change, the notification is repeated while the incident is open
+	Timestamp time.Time
+}
+
+// remind - turns the status change message into the reminder if the event is a reminder
+func (e *Event) remind(subject, body string) (string, string) {
+	if !e.Reminder || e.Incident == nil {
+		return subject, body
+	}
+	d := duration(e.Timestamp.Sub(e.Incident.StartTS))
+	subject = fmt.Sprintf("⏰ %s for %s", subject, d)
+	body = fmt.Sprintf("%s\nStill %s for %s", body, strings.ToUpper(string(e.Status)), d)
+	return subject, body
+}
+
 type Notify struct {
 	clients []notify
 
@@ -63,6 +101,48 @@ func New(ctx context.Context, cfg *types.Cfg) (*Notify, error) {
 		n.clients = append(n.clients, smtp)
 		log.Print("[INFO] SMTP notifications enabled")
 	}
+	if cfg.Notifications.Discord != nil {
+		discord := &Discord{
+			url:     cfg.Notifications.Discord.WebhookURL,
+			baseURL: cfg.UI.URL,
+			hideURL: cfg.UI.HideURL,
+			timeout: time.Second * 10,
+		}
+		n.clients = append(n.clients, discord)
+		log.Print("[INFO] Discord notifications enabled")
+	}
+	if cfg.Notifications.Teams != nil {
+		teams := &Teams{
+			url:     cfg.Notifications.Teams.WebhookURL,
+			baseURL: cfg.UI.URL,
+			hideURL: cfg.UI.HideURL,
+			timeout: time.Second * 10,
+		}
+		n.clients = append(n.clients, teams)
+		log.Print("[INFO] Teams notifications enabled")
+	}
+	if cfg.Notifications.PagerDuty != nil {
+		pagerDuty := &PagerDuty{
+			url:        cfg.Notifications.PagerDuty.URL,
+			routingKey: cfg.Notifications.PagerDuty.RoutingKey,
+			baseURL:    cfg.UI.URL,
+			hideURL:    cfg.UI.HideURL,
+			timeout:    time.Second * 10,
+		}
+		if pagerDuty.url == "" {
+			pagerDuty.url = types.PagerDutyURL
+		}
+		n.clients = append(n.clients, pagerDuty)
+		log.Print("[INFO] PagerDuty notifications enabled")
+	}
+	if cfg.Notifications.Webhook != nil {
+		webhook, err := newWebhook(cfg.Notifications.Webhook, cfg.UI.HideURL)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: %w", err)
+		}
+		n.clients = append(n.clients, webhook)
+		log.Print("[INFO] Webhook notifications enabled")
+	}
 
 	if *cfg.Notifications.InitializationMessage {
 		for _, client := range n.clients {
@@ -92,43 +172,76 @@ func New(ctx context.Context, cfg *types.Cfg) (*Notify, error) {
 	return n, nil
 }
 
-func (n *Notify) Send(host *types.Host, status types.StatusType) error {
-	clients := host.Alerts
+// Send - notifies the clients defined for the host about the status change
+func (n *Notify) Send(e *Event) error {
+	_, err := n.SendTo(e, e.Host.Alerts)
+	return err
+}
+
+// SendTo - notifies the clients from the list about the event, all clients if the list is empty.
+// The failed client does not stop the others, returns the clients that got the event and the joined errors
+func (n *Notify) SendTo(e *Event, clients []string) ([]string, error) {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
 
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
-	for _, c := range n.clients {
-		send := false
-		if clients != nil && len(clients) > 0 {
-			for _, client := range clients {
-				if c.string() == client {
-					send = true
-					break
-				}
-			}
+	var sent []string
+	var errs []error
+	for _, c := range n.filter(clients) {
+		var err error
+		if ec, ok := c.(eventer); ok {
+			err = ec.event(e)
 		} else {
-			send = true
+			subject, body := e.remind(c.normalize(e.Host, e.Status))
+			err = c.send(subject, body)
 		}
-
-		if !send {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.string(), err))
 			continue
 		}
+		sent = append(sent, c.string())
+	}
+
+	return sent, errors.Join(errs...)
+}
+
+// Resolve - tells the clients from the list that the incident is closed, all clients if the list is empty
+func (n *Notify) Resolve(host *types.Host, incident *types.Incident, clients []string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var errs []error
+	for _, c := range n.filter(clients) {
+		if r, ok := c.(resolver); ok {
+			if err := r.resolve(host, incident); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", c.string(), err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
 
-		subject, body := c.normalize(host, status)
+// Message - sends a custom message to the clients defined for the host
+func (n *Notify) Message(host *types.Host, subject, body string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var errs []error
+	for _, c := range n.filter(host.Alerts) {
 		if err := c.send(subject, body); err != nil {
-			return err
+			errs = append(errs, fmt.Errorf("%s: %w", c.string(), err))
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 func (n *Notify) Set(clients []string, status types.StatusType, name, addr string) error {
-	icon := "❌"
-	if status == types.UP {
-		icon = "✅"
-	}
+	icon := statusIcon(status)
 
 	text := fmt.Sprintf("%s: `%s (%s)` has a new status: %s", icon, name, addr, strings.ToUpper(string(status)))
 	subject := fmt.Sprintf("%s: %s is %s", icon, name, strings.ToUpper(string(status)))
@@ -136,27 +249,112 @@ func (n *Notify) Set(clients []string, status types.StatusType, name, addr strin
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
+	for _, c := range n.filter(clients) {
+		if err := c.send(subject, text); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Clients - returns the names of the enabled clients
+func (n *Notify) Clients() []string {
+	list := make([]string, 0, len(n.clients))
 	for _, c := range n.clients {
-		send := false
-		if clients != nil && len(clients) > 0 {
-			for _, client := range clients {
-				if c.string() == client {
-					send = true
-					break
-				}
+		list = append(list, c.string())
+	}
+	return list
+}
+
+// filter - returns the clients from the list, or all clients if the list is empty
+func (n *Notify) filter(list []string) []notify {
+	if len(list) == 0 {
+		return n.clients
+	}
+
+	clients := make([]notify, 0, len(list))
+	for _, c := range n.clients {
+		for _, name := range list {
+			if c.string() == name {
+				clients = append(clients, c)
+				break
 			}
-		} else {
-			send = true
 		}
+	}
 
-		if !send {
-			continue
-		}
+	return clients
+}
 
-		if err := c.send(subject, text); err != nil {
-			return err
-		}
+// postJSON - sends the value as json to the url and expects 2xx response
+func postJSON(url string, v interface{}, timeout time.Duration) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	client := &http.Client{
+		Timeout: timeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("non-ok (%d) response: %s", resp.StatusCode, string(body))
 	}
 
 	return nil
 }
+
+// hostName - returns the name of the host or the url if the name is not defined, the id is used when the url is hidden
+func hostName(host *types.Host, hideURL bool) string {
+	if host.Name != nil && *host.Name != "" {
+		return *host.Name
+	}
+	if hideURL {
+		return host.ID
+	}
+	return host.SecureURL()
+}
+
+// hostLink - returns the link to the host page on the status page, empty if the status page url is not defined
+func hostLink(baseURL string, host *types.Host) string {
+	if baseURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + host.ID
+}
+
+// duration - formats the duration with the minute precision
+func duration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Minute {
+		return "less than a minute"
+	}
+	s := d.String()
+	return strings.TrimSuffix(s, "0s")
+}
+
+// statusIcon - returns the emoji for the status
+func statusIcon(status types.StatusType) string {
+	switch status {
+	case types.UP:
+		return "✅"
+	case types.DEGRADED:
+		return "⚠️"
+	case types.FLAPPING:
+		return "🔀"
+	default:
+		return "❌"
+	}
+}
@@ -47,6 +47,68 @@ func TestNotify_Set(t *testing.T) {
 		clients: []notify{m},
 	}
 
-	require.NoError(t, n.Set(nil, types.UP, "test_ok"))
-	require.Error(t, n.Set(nil, types.UP, "error"))
+	require.NoError(t, n.Set(nil, types.UP, "test_ok", "url"))
+	require.Error(t, n.Set(nil, types.UP, "error", "url"))
+}
+
+func TestNotify_Send(t *testing.T) {
+	var events []*Event
+	var sent []string
+	w := &eventerMock{
+		notifyMock: notifyMock{
+			stringFunc: func() string {
+				return "webhook"
+			},
+		},
+		eventFunc: func(e *Event) error {
+			events = append(events, e)
+			return nil
+		},
+	}
+	m := &notifyMock{
+		stringFunc: func() string {
+			return "mock"
+		},
+		normalizeFunc: func(host *types.Host, status types.StatusType) (string, string) {
+			return "subject", string(status)
+		},
+		sendFunc: func(subject, body string) error {
+			sent = append(sent, body)
+			return nil
+		},
+	}
+
+	n := &Notify{
+		clients: []notify{w, m},
+	}
+
+	require.NoError(t, n.Send(&Event{Host: &types.Host{}, Status: types.DOWN}))
+	require.Len(t, events, 1)
+	require.False(t, events[0].Timestamp.IsZero())
+	require.Equal(t, []string{"down"}, sent)
+	require.Empty(t, w.calls.send)
+
+	require.NoError(t, n.Send(&Event{Host: &types.Host{Alerts: []string{"mock"}}, Status: types.UP}))
+	require.Len(t, events, 1)
+	require.Equal(t, []string{"down", "up"}, sent)
+
+	t.Run("failed client", func(t *testing.T) {
+		events, sent = nil, nil
+		w.eventFunc = func(e *Event) error {
+			return errors.New("outage")
+		}
+		list, err := n.SendTo(&Event{Host: &types.Host{}, Status: types.DOWN}, nil)
+		require.EqualError(t, err, "webhook: outage")
+		require.Equal(t, []string{"mock"}, list)
+		require.Equal(t, []string{"down"}, sent)
+	})
+}
+
+type eventerMock struct {
+	notifyMock
+	eventFunc func(e *Event) error
+}
+
+func (mock *eventerMock) event(e *Event) error {
+	return mock.eventFunc(e)
 }
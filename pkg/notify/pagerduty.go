@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/exelban/JAM/types"
+)
+
+// PagerDuty - triggers and resolves alerts via the Events API v2.
+// Only the incidents are sent, service messages are ignored to not page anybody.
+// The alert is keyed by the host and the incident, so the repeated events of the incident update the same alert.
+type PagerDuty struct {
+	url        string
+	routingKey string
+	baseURL    string
+	hideURL    bool
+
+	timeout time.Duration
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+func (p *PagerDuty) string() string {
+	return "pagerduty"
+}
+
+func (p *PagerDuty) send(subject, body string) error {
+	return nil
+}
+
+// event - triggers the alert of the incident, the next events of the incident update it. The resolve is sent when the incident is closed
+func (p *PagerDuty) event(e *Event) error {
+	if e.Reminder || e.Incident == nil { // the alert is already triggered, PagerDuty handles the repeats itself
+		return nil
+	}
+	subject, _ := p.normalize(e.Host, e.Status)
+	payload := &pagerDutyPayload{
+		Summary:   subject,
+		Source:    hostName(e.Host, p.hideURL),
+		Severity:  pagerDutySeverity(e.Incident.GetSeverity()),
+		Timestamp: e.Incident.StartTS.Format(time.RFC3339),
+		Component: e.Host.ID,
+		Class:     string(e.Host.Type),
+	}
+	if e.Host.Group != nil {
+		payload.Group = *e.Host.Group
+	}
+	if e.Response != nil {
+		payload.CustomDetails = map[string]interface{}{
+			"status_code": e.Response.Code,
+		}
+		if e.Response.Body != "" {
+			payload.CustomDetails["response"] = e.Response.Body
+		}
+		if len(e.Response.Reasons) > 0 {
+			payload.CustomDetails["reasons"] = e.Response.Reasons
+		}
+	}
+	if !p.hideURL {
+		if payload.CustomDetails == nil {
+			payload.CustomDetails = map[string]interface{}{}
+		}
+		payload.CustomDetails["url"] = e.Host.SecureURL()
+	}
+
+	event := pagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "trigger",
+		DedupKey:    dedupKey(e.Host, e.Incident),
+		Payload:     payload,
+	}
+	if link := hostLink(p.baseURL, e.Host); link != "" {
+		event.Links = append(event.Links, pagerDutyLink{Href: link, Text: "Status page"})
+	}
+
+	return p.post(event)
+}
+
+// resolve - resolves the alert of the incident
+func (p *PagerDuty) resolve(host *types.Host, incident *types.Incident) error {
+	return p.post(pagerDutyEvent{RoutingKey: p.routingKey, EventAction: "resolve", DedupKey: dedupKey(host, incident)})
+}
+
+func (p *PagerDuty) normalize(host *types.Host, status types.StatusType) (string, string) {
+	subject := fmt.Sprintf("%s is %s", hostName(host, p.hideURL), strings.ToUpper(string(status)))
+	return subject, subject
+}
+
+func (p *PagerDuty) post(event pagerDutyEvent) error {
+	if err := postJSON(p.url, event, p.timeout); err != nil {
+		return fmt.Errorf("pagerduty %s: %w", event.EventAction, err)
+	}
+	return nil
+}
+
+// dedupKey - returns the key of the PagerDuty alert opened by the incident
+func dedupKey(host *types.Host, incident *types.Incident) string {
+	return fmt.Sprintf("%s-%d", host.ID, incident.ID)
+}
+
+// pagerDutySeverity - maps the incident severity to the PagerDuty one
+func pagerDutySeverity(status types.StatusType) string {
+	if status == types.DEGRADED {
+		return "warning"
+	}
+	return "critical"
+}
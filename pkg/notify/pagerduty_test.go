@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/exelban/JAM/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagerDuty_event(t *testing.T) {
+	var events []pagerDutyEvent
+	var mu sync.Mutex
+	router := http.NewServeMux()
+	router.HandleFunc("POST /v2/enqueue", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		e := pagerDutyEvent{}
+		if err := json.Unmarshal(b, &e); err != nil || e.RoutingKey != "key" {
+			http.Error(w, `{"status":"invalid event"}`, http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"status":"success"}`))
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	pd := &PagerDuty{
+		url:        ts.URL + "/v2/enqueue",
+		routingKey: "key",
+		baseURL:    "https://status.example.com",
+		timeout:    time.Second,
+	}
+	group := "api"
+	host := &types.Host{ID: "host", Group: &group, URL: "https://example.com", Type: types.HttpType}
+	down := &types.Incident{ID: 1, StartTS: time.Now()}
+	degraded := &types.Incident{ID: 2, Severity: types.DEGRADED, StartTS: time.Now()}
+
+	t.Run("service messages are ignored", func(t *testing.T) {
+		require.NoError(t, pd.send("JAM status", "I'm online"))
+		require.Empty(t, events)
+	})
+
+	t.Run("trigger", func(t *testing.T) {
+		events = nil
+		require.NoError(t, pd.event(&Event{
+			Host:     host,
+			Status:   types.DOWN,
+			Incident: down,
+			Response: &types.HttpResponse{Code: 522, Body: "timeout"},
+		}))
+		require.Len(t, events, 1)
+		require.Equal(t, "trigger", events[0].EventAction)
+		require.Equal(t, "host-1", events[0].DedupKey)
+		require.Equal(t, "critical", events[0].Payload.Severity)
+		require.Equal(t, "api", events[0].Payload.Group)
+		require.Equal(t, "https://example.com is DOWN", events[0].Payload.Summary)
+		require.Equal(t, float64(522), events[0].Payload.CustomDetails["status_code"])
+		require.Equal(t, "https://example.com", events[0].Payload.CustomDetails["url"])
+		require.Equal(t, []pagerDutyLink{{Href: "https://status.example.com/host", Text: "Status page"}}, events[0].Links)
+	})
+
+	t.Run("severity change", func(t *testing.T) {
+		events = nil
+		require.NoError(t, pd.resolve(host, down))
+		require.NoError(t, pd.event(&Event{
+			Host:     host,
+			Status:   types.DEGRADED,
+			Incident: degraded,
+			Resolved: down,
+		}))
+		require.Len(t, events, 2)
+		require.Equal(t, "resolve", events[0].EventAction)
+		require.Equal(t, "host-1", events[0].DedupKey)
+		require.Nil(t, events[0].Payload)
+		require.Equal(t, "trigger", events[1].EventAction)
+		require.Equal(t, "host-2", events[1].DedupKey)
+		require.Equal(t, "warning", events[1].Payload.Severity)
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		events = nil
+		require.NoError(t, pd.resolve(host, degraded))
+		require.Len(t, events, 1)
+		require.Equal(t, "resolve", events[0].EventAction)
+		require.Equal(t, "host-2", events[0].DedupKey)
+	})
+
+	t.Run("incidents", func(t *testing.T) {
+		events = nil
+		for i := 0; i < 2; i++ {
+			incident := &types.Incident{ID: 10 + i, StartTS: time.Now()}
+			require.NoError(t, pd.event(&Event{Host: host, Status: types.DOWN, Incident: incident}))
+			require.NoError(t, pd.event(&Event{Host: host, Status: types.DOWN, Incident: incident, Reminder: true}))
+			require.NoError(t, pd.resolve(host, incident))
+		}
+
+		// every incident has its own alert, the resolve is sent right away
+		require.Len(t, events, 4)
+		for i, e := range events {
+			require.Equal(t, []string{"trigger", "resolve"}[i%2], e.EventAction)
+			require.Equal(t, fmt.Sprintf("host-%d", 10+i/2), e.DedupKey)
+		}
+	})
+
+	t.Run("hide url", func(t *testing.T) {
+		events = nil
+		pd := &PagerDuty{url: pd.url, routingKey: "key", hideURL: true, timeout: time.Second}
+		require.NoError(t, pd.event(&Event{Host: host, Status: types.DOWN, Incident: down}))
+		require.Len(t, events, 1)
+		b, _ := json.Marshal(events[0])
+		require.NotContains(t, string(b), "example.com")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		pd := &PagerDuty{url: pd.url, routingKey: "invalid", timeout: time.Second}
+		require.Error(t, pd.event(&Event{Host: host, Status: types.DOWN, Incident: down}))
+	})
+}
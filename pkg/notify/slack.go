@@ -63,10 +63,7 @@ func (s *Slack) send(subject, body string) error {
 }
 
 func (s *Slack) normalize(host *types.Host, status types.StatusType) (string, string) {
-	icon := "❌"
-	if status == types.UP {
-		icon = "✅"
-	}
+	icon := statusIcon(status)
 
 	name := host.URL
 	if host.Name != nil && *host.Name == "" {
@@ -104,10 +104,7 @@ func (s *SMTP) send(subject, body string) error {
 }
 
 func (s *SMTP) normalize(host *types.Host, status types.StatusType) (string, string) {
-	icon := "❌"
-	if status == types.UP {
-		icon = "✅"
-	}
+	icon := statusIcon(status)
 
 	details := fmt.Sprintf(`
 	<li><strong>Address:</strong> <a href="%s">%s</a></li>
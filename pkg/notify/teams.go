@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/exelban/JAM/types"
+)
+
+type Teams struct {
+	url     string
+	baseURL string
+	hideURL bool
+
+	timeout time.Duration
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+type teamsCard struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []teamsBlock  `json:"body"`
+	Actions []teamsAction `json:"actions,omitempty"`
+}
+type teamsBlock struct {
+	Type   string      `json:"type"`
+	Text   string      `json:"text,omitempty"`
+	Size   string      `json:"size,omitempty"`
+	Weight string      `json:"weight,omitempty"`
+	Color  string      `json:"color,omitempty"`
+	Wrap   bool        `json:"wrap,omitempty"`
+	Facts  []teamsFact `json:"facts,omitempty"`
+}
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+type teamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+func (t *Teams) string() string {
+	return "teams"
+}
+
+func (t *Teams) send(subject, body string) error {
+	return t.post(teamsCard{
+		Body: []teamsBlock{
+			{Type: "TextBlock", Text: subject, Weight: "Bolder", Wrap: true},
+			{Type: "TextBlock", Text: body, Wrap: true},
+		},
+	})
+}
+
+func (t *Teams) event(e *Event) error {
+	subject, _ := e.remind(t.normalize(e.Host, e.Status))
+
+	facts := []teamsFact{
+		{Title: "Host", Value: hostName(e.Host, t.hideURL)},
+		{Title: "Status", Value: strings.ToUpper(string(e.Status))},
+	}
+	if e.Response != nil && e.Response.Code != 0 {
+		facts = append(facts, teamsFact{Title: "Status code", Value: strconv.Itoa(e.Response.Code)})
+	}
+	if !t.hideURL {
+		facts = append(facts, teamsFact{Title: "URL", Value: e.Host.SecureURL()})
+	}
+	facts = append(facts, teamsFact{Title: "Time", Value: e.Timestamp.Format(time.RFC1123)})
+
+	card := teamsCard{
+		Body: []teamsBlock{
+			{Type: "TextBlock", Text: subject, Size: "Medium", Weight: "Bolder", Color: teamsColor(e.Status), Wrap: true},
+		},
+	}
+	if e.Host.Description != nil && *e.Host.Description != "" {
+		card.Body = append(card.Body, teamsBlock{Type: "TextBlock", Text: *e.Host.Description, Wrap: true})
+	}
+	card.Body = append(card.Body, teamsBlock{Type: "FactSet", Facts: facts})
+	if link := hostLink(t.baseURL, e.Host); link != "" {
+		card.Actions = append(card.Actions, teamsAction{Type: "Action.OpenUrl", Title: "Open status page", URL: link})
+	}
+
+	return t.post(card)
+}
+
+func (t *Teams) normalize(host *types.Host, status types.StatusType) (string, string) {
+	icon := statusIcon(status)
+
+	name := hostName(host, t.hideURL)
+	if !t.hideURL && host.Name != nil && *host.Name != "" {
+		name = fmt.Sprintf("%s (%s)", name, host.SecureURL())
+	}
+
+	text := fmt.Sprintf("%s: %s has a new status: %s", icon, name, strings.ToUpper(string(status)))
+	subject := fmt.Sprintf("%s: %s is %s", icon, hostName(host, t.hideURL), strings.ToUpper(string(status)))
+
+	return subject, text
+}
+
+// post - sends the adaptive card to the incoming webhook
+func (t *Teams) post(card teamsCard) error {
+	card.Schema = "http://adaptivecards.io/schemas/adaptive-card.json"
+	card.Type = "AdaptiveCard"
+	card.Version = "1.4"
+
+	if err := postJSON(t.url, teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content:     card,
+			},
+		},
+	}, t.timeout); err != nil {
+		return fmt.Errorf("teams: %w", err)
+	}
+	return nil
+}
+
+// teamsColor - returns the adaptive card text color for the status
+func teamsColor(status types.StatusType) string {
+	switch status {
+	case types.UP:
+		return "Good"
+	case types.DEGRADED, types.FLAPPING:
+		return "Warning"
+	default:
+		return "Attention"
+	}
+}
@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/exelban/JAM/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeams_send(t *testing.T) {
+	var req teamsMessage
+	router := http.NewServeMux()
+	router.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		req = teamsMessage{}
+		_ = json.Unmarshal(b, &req)
+
+		if len(req.Attachments) == 1 && len(req.Attachments[0].Content.Body) > 1 {
+			switch req.Attachments[0].Content.Body[1].Text {
+			case "timeout":
+				time.Sleep(time.Millisecond * 20)
+			case "error":
+				http.Error(w, "error", http.StatusBadRequest)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+	ts := httptest.NewServer(router)
+	defer func() {
+		ts.Close()
+	}()
+
+	teams := &Teams{
+		url:     ts.URL,
+		timeout: time.Millisecond * 10,
+	}
+
+	require.NoError(t, teams.send("subject", "test"))
+	require.Equal(t, "message", req.Type)
+	require.Len(t, req.Attachments, 1)
+	require.Equal(t, "application/vnd.microsoft.card.adaptive", req.Attachments[0].ContentType)
+	require.Equal(t, "AdaptiveCard", req.Attachments[0].Content.Type)
+	require.Error(t, teams.send("subject", "error"))
+	require.Error(t, teams.send("subject", "timeout"))
+}
+
+func TestTeams_event(t *testing.T) {
+	var req teamsMessage
+	router := http.NewServeMux()
+	router.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		req = teamsMessage{}
+		_ = json.Unmarshal(b, &req)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	e := &Event{
+		Host:      &types.Host{ID: "abc", URL: "https://api.example.com"},
+		Status:    types.DEGRADED,
+		Response:  &types.HttpResponse{Code: 200},
+		Timestamp: time.Now(),
+	}
+
+	t.Run("with url", func(t *testing.T) {
+		teams := &Teams{url: ts.URL, baseURL: "https://status.example.com", timeout: time.Second}
+		require.NoError(t, teams.event(e))
+		require.Len(t, req.Attachments, 1)
+
+		card := req.Attachments[0].Content
+		require.Equal(t, "Warning", card.Body[0].Color)
+		require.Contains(t, card.Body[0].Text, "https://api.example.com is DEGRADED")
+		facts := card.Body[len(card.Body)-1].Facts
+		require.Contains(t, facts, teamsFact{Title: "Status", Value: "DEGRADED"})
+		require.Contains(t, facts, teamsFact{Title: "Status code", Value: "200"})
+		require.Contains(t, facts, teamsFact{Title: "URL", Value: "https://api.example.com"})
+		require.Equal(t, []teamsAction{{Type: "Action.OpenUrl", Title: "Open status page", URL: "https://status.example.com/abc"}}, card.Actions)
+	})
+
+	t.Run("hide url", func(t *testing.T) {
+		teams := &Teams{url: ts.URL, hideURL: true, timeout: time.Second}
+		require.NoError(t, teams.event(e))
+		require.Len(t, req.Attachments, 1)
+
+		b, _ := json.Marshal(req)
+		require.NotContains(t, string(b), "api.example.com")
+		require.Contains(t, req.Attachments[0].Content.Body[0].Text, "abc is DEGRADED")
+		require.Empty(t, req.Attachments[0].Content.Actions)
+	})
+}
@@ -38,10 +38,7 @@ func (t *Telegram) send(subject, body string) error {
 }
 
 func (t *Telegram) normalize(host *types.Host, status types.StatusType) (string, string) {
-	icon := "❌"
-	if status == types.UP {
-		icon = "✅"
-	}
+	icon := statusIcon(status)
 
 	name := host.URL
 	if host.Name != nil && *host.Name == "" {
@@ -0,0 +1,204 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/exelban/JAM/types"
+)
+
+type Webhook struct {
+	url      string
+	method   string
+	headers  map[string]string
+	template *template.Template
+	secret   string
+	hideURL  bool
+
+	timeout time.Duration
+}
+
+// webhookData - data available in the body template. Host, statuses, incident and response are empty for the service messages.
+type webhookData struct {
+	Subject   string              `json:"subject"`
+	Message   string              `json:"message"`
+	Name      string              `json:"name,omitempty"`
+	URL       string              `json:"url,omitempty"`
+	Host      *webhookHost        `json:"-"`
+	HostID    string              `json:"hostID,omitempty"`
+	OldStatus types.StatusType    `json:"oldStatus,omitempty"`
+	Status    types.StatusType    `json:"status,omitempty"`
+	Incident  *types.Incident     `json:"incident,omitempty"`
+	Resolved  *types.Incident     `json:"resolved,omitempty"`
+	Response  *types.HttpResponse `json:"response,omitempty"`
+	Reminder  bool                `json:"reminder,omitempty"`
+	Timestamp time.Time           `json:"timestamp"`
+}
+
+// webhookHost - host data available in the body template, the config of the host is not exposed because of the credentials and the push token
+type webhookHost struct {
+	ID          string
+	Type        types.HostType
+	Name        string
+	Description string
+	Group       string
+	URL         string // empty if the url is hidden
+}
+
+var webhookFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": func(v interface{}) string {
+		return strings.ToUpper(fmt.Sprint(v))
+	},
+}
+
+func newWebhook(cfg *types.Webhook, hideURL bool) (*Webhook, error) {
+	w := &Webhook{
+		url:     cfg.URL,
+		method:  cfg.Method,
+		headers: cfg.Headers,
+		secret:  cfg.Secret,
+		hideURL: hideURL,
+		timeout: time.Second * 10,
+	}
+	if w.method == "" {
+		w.method = http.MethodPost
+	}
+	if cfg.Body != "" {
+		tmpl, err := template.New("webhook").Funcs(webhookFuncs).Parse(cfg.Body)
+		if err != nil {
+			return nil, fmt.Errorf("parse body template: %w", err)
+		}
+		w.template = tmpl
+	}
+	return w, nil
+}
+
+func (w *Webhook) string() string {
+	return "webhook"
+}
+
+func (w *Webhook) send(subject, body string) error {
+	return w.request(&webhookData{
+		Subject:   subject,
+		Message:   body,
+		Timestamp: time.Now(),
+	})
+}
+
+func (w *Webhook) event(e *Event) error {
+	subject, body := e.remind(w.normalize(e.Host, e.Status))
+
+	host := &webhookHost{
+		ID:   e.Host.ID,
+		Type: e.Host.Type,
+		Name: hostName(e.Host, w.hideURL),
+	}
+	if e.Host.Description != nil {
+		host.Description = *e.Host.Description
+	}
+	if e.Host.Group != nil {
+		host.Group = *e.Host.Group
+	}
+	if !w.hideURL {
+		host.URL = e.Host.SecureURL()
+	}
+
+	return w.request(&webhookData{
+		Subject:   subject,
+		Message:   body,
+		Name:      host.Name,
+		URL:       host.URL,
+		Host:      host,
+		HostID:    e.Host.ID,
+		OldStatus: e.OldStatus,
+		Status:    e.Status,
+		Incident:  e.Incident,
+		Resolved:  e.Resolved,
+		Response:  e.Response,
+		Reminder:  e.Reminder,
+		Timestamp: e.Timestamp,
+	})
+}
+
+func (w *Webhook) normalize(host *types.Host, status types.StatusType) (string, string) {
+	icon := statusIcon(status)
+
+	name := hostName(host, w.hideURL)
+	if !w.hideURL && host.Name != nil && *host.Name != "" {
+		name = fmt.Sprintf("%s (%s)", name, host.SecureURL())
+	}
+
+	text := fmt.Sprintf("%s: `%s` has a new status: %s", icon, name, strings.ToUpper(string(status)))
+	subject := fmt.Sprintf("%s: %s is %s", icon, hostName(host, w.hideURL), strings.ToUpper(string(status)))
+
+	return subject, text
+}
+
+// request - renders the body, signs it and sends it to the webhook url
+func (w *Webhook) request(data *webhookData) error {
+	var body []byte
+	if w.template != nil {
+		buf := new(bytes.Buffer)
+		if err := w.template.Execute(buf, data); err != nil {
+			return fmt.Errorf("execute webhook template: %w", err)
+		}
+		body = buf.Bytes()
+	} else {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+
+	req, err := http.NewRequest(w.method, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if w.template == nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	for key, value := range w.headers {
+		req.Header.Set(key, value)
+	}
+	if w.secret != "" {
+		req.Header.Set("X-JAM-Signature", "sha256="+sign(w.secret, body))
+	}
+
+	client := &http.Client{
+		Timeout: w.timeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("non-ok (%d) response from webhook: %s", resp.StatusCode, string(b))
+	}
+
+	return nil
+}
+
+// sign - returns the hex encoded HMAC-SHA256 of the body
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
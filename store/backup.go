@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/exelban/JAM/types"
+)
+
+// backupVersion - version of the backup format, written in the header of the backup
+const backupVersion = 1
+
+// exportBatch - number of the responses read from the store at once during the export
+const exportBatch = 10000
+
+// record types of the backup
+const (
+	backupHeader       = "header"
+	backupResponse     = "response"
+	backupIncident     = "incident"
+	backupAnnouncement = "announcement"
+)
+
+// backupRecord - line of the backup. The backup is JSON Lines: the header first, then the responses (checks and
+// aggregations) and the incidents of each host, the announcements at the end
+type backupRecord struct {
+	Type    string    `json:"type"`
+	Version int       `json:"version,omitempty"` // only in the header
+	Created time.Time `json:"created,omitzero"`  // only in the header
+	Host    string    `json:"host,omitempty"`
+
+	Response     *types.HttpResponse `json:"response,omitempty"`
+	Incident     *types.Incident     `json:"incident,omitempty"`
+	Announcement *types.Announcement `json:"announcement,omitempty"`
+}
+
+// BackupStats - number of the records written or read by the backup
+type BackupStats struct {
+	Hosts         int `json:"hosts"`
+	Responses     int `json:"responses"`
+	Incidents     int `json:"incidents"`
+	Announcements int `json:"announcements"`
+}
+
+func (s BackupStats) String() string {
+	return fmt.Sprintf("%d hosts, %d responses, %d incidents, %d announcements", s.Hosts, s.Responses, s.Incidents, s.Announcements)
+}
+
+// Export - writes the whole history, the incidents and the announcements of the store to w.
+// Only the hosts with the responses are exported, the same as returned by Hosts.
+func Export(ctx context.Context, s Interface, w io.Writer) (BackupStats, error) {
+	var stats BackupStats
+	enc := json.NewEncoder(w)
+
+	if err := enc.Encode(backupRecord{Type: backupHeader, Version: backupVersion, Created: time.Now().UTC()}); err != nil {
+		return stats, err
+	}
+
+	hosts, err := s.Hosts(ctx)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get hosts: %w", err)
+	}
+	slices.Sort(hosts)
+
+	for _, host := range hosts {
+		stats.Hosts++
+
+		var from time.Time
+		for {
+			list, err := s.FindResponsesRange(ctx, host, from, time.Time{}, exportBatch)
+			if err != nil {
+				return stats, fmt.Errorf("failed to get responses of %s: %w", host, err)
+			}
+			for _, r := range list {
+				if err := enc.Encode(backupRecord{Type: backupResponse, Host: host, Response: r}); err != nil {
+					return stats, err
+				}
+				stats.Responses++
+			}
+			if len(list) < exportBatch {
+				break
+			}
+			from = list[len(list)-1].Timestamp.Add(time.Nanosecond)
+		}
+
+		// the incidents are written from the oldest one, so the import keeps the order of the ids
+		incidents, err := s.FindIncidents(ctx, host, 0, 0)
+		if err != nil {
+			return stats, fmt.Errorf("failed to get incidents of %s: %w", host, err)
+		}
+		slices.Reverse(incidents)
+		for _, e := range incidents {
+			if err := enc.Encode(backupRecord{Type: backupIncident, Host: host, Incident: e}); err != nil {
+				return stats, err
+			}
+			stats.Incidents++
+		}
+	}
+
+	announcements, err := s.FindAnnouncements(ctx, 0, 0)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get announcements: %w", err)
+	}
+	slices.Reverse(announcements)
+	for _, a := range announcements {
+		if err := enc.Encode(backupRecord{Type: backupAnnouncement, Announcement: a}); err != nil {
+			return stats, err
+		}
+		stats.Announcements++
+	}
+
+	return stats, nil
+}
+
+// Import - reads the backup written by Export into the store. The responses replace the existing ones with the same timestamp,
+// the incidents and the announcements which are already in the store (the same start time) are skipped, so the backup can be imported again.
+// The ids of the incidents and the announcements are assigned by the store.
+func Import(ctx context.Context, s Interface, r io.Reader) (BackupStats, error) {
+	var stats BackupStats
+	dec := json.NewDecoder(r)
+
+	var header backupRecord
+	if err := dec.Decode(&header); err != nil {
+		return stats, fmt.Errorf("failed to read backup header: %w", err)
+	}
+	if header.Type != backupHeader {
+		return stats, errors.New("backup header is missing")
+	}
+	if header.Version > backupVersion {
+		return stats, fmt.Errorf("backup version %d is newer than supported %d", header.Version, backupVersion)
+	}
+
+	hosts := map[string]bool{}
+	incidents := map[string]map[time.Time]bool{} // start time of the existing incidents per host
+	var announcements map[time.Time]bool
+
+	for line := 2; ; line++ {
+		var rec backupRecord
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return stats, fmt.Errorf("record %d: %w", line, err)
+		}
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		if rec.Host != "" {
+			hosts[rec.Host] = true
+		}
+
+		switch rec.Type {
+		case backupResponse:
+			if rec.Host == "" || rec.Response == nil {
+				return stats, fmt.Errorf("record %d: response without host", line)
+			}
+			if err := s.AddResponse(ctx, rec.Host, rec.Response); err != nil {
+				return stats, fmt.Errorf("record %d: failed to add response: %w", line, err)
+			}
+			stats.Responses++
+		case backupIncident:
+			if rec.Host == "" || rec.Incident == nil {
+				return stats, fmt.Errorf("record %d: incident without host", line)
+			}
+			existing, ok := incidents[rec.Host]
+			if !ok {
+				list, err := s.FindIncidents(ctx, rec.Host, 0, 0)
+				if err != nil {
+					return stats, fmt.Errorf("record %d: failed to get incidents: %w", line, err)
+				}
+				existing = make(map[time.Time]bool, len(list))
+				for _, e := range list {
+					existing[backupKey(e.StartTS)] = true
+				}
+				incidents[rec.Host] = existing
+			}
+			if existing[backupKey(rec.Incident.StartTS)] {
+				continue
+			}
+			if err := s.AddIncident(ctx, rec.Host, rec.Incident); err != nil {
+				return stats, fmt.Errorf("record %d: failed to add incident: %w", line, err)
+			}
+			existing[backupKey(rec.Incident.StartTS)] = true
+			stats.Incidents++
+		case backupAnnouncement:
+			if rec.Announcement == nil {
+				return stats, fmt.Errorf("record %d: empty announcement", line)
+			}
+			if announcements == nil {
+				list, err := s.FindAnnouncements(ctx, 0, 0)
+				if err != nil {
+					return stats, fmt.Errorf("record %d: failed to get announcements: %w", line, err)
+				}
+				announcements = make(map[time.Time]bool, len(list))
+				for _, a := range list {
+					announcements[backupKey(a.StartTS)] = true
+				}
+			}
+			if announcements[backupKey(rec.Announcement.StartTS)] {
+				continue
+			}
+			if err := s.AddAnnouncement(ctx, rec.Announcement); err != nil {
+				return stats, fmt.Errorf("record %d: failed to add announcement: %w", line, err)
+			}
+			announcements[backupKey(rec.Announcement.StartTS)] = true
+			stats.Announcements++
+		default:
+			return stats, fmt.Errorf("record %d: unknown type %q", line, rec.Type)
+		}
+	}
+	stats.Hosts = len(hosts)
+
+	return stats, nil
+}
+
+// backupKey - returns the start time for the comparison of the incidents and the announcements, postgres keeps the microseconds only
+func backupKey(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exelban/JAM/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, time.Now().UnixMicro()*1000) // postgres keeps the microseconds only
+	end := now.Add(-time.Minute)
+
+	src := NewMemory(ctx)
+	for i := 0; i < 50; i++ {
+		require.NoError(t, src.AddResponse(ctx, "host-1", &types.HttpResponse{Timestamp: now.Add(-time.Second * time.Duration(i)), Code: 200, StatusType: types.UP, Time: time.Millisecond * time.Duration(i)}))
+	}
+	require.NoError(t, src.AddResponse(ctx, "host-1", Rollup(now.AddDate(0, 0, -1).Truncate(time.Hour), HourlyResolution, []*types.HttpResponse{
+		{Timestamp: now.AddDate(0, 0, -1), StatusType: types.UP, Time: time.Millisecond},
+		{Timestamp: now.AddDate(0, 0, -1), StatusType: types.DOWN, Time: time.Millisecond * 3},
+	})))
+	require.NoError(t, src.AddResponse(ctx, "host-2", &types.HttpResponse{Timestamp: now, Code: 500, StatusType: types.DOWN, Reasons: []string{"status code"}}))
+	require.NoError(t, src.AddIncident(ctx, "host-2", &types.Incident{StartTS: now.Add(-time.Hour), EndTS: &end, Timeline: []types.IncidentEvent{{Type: types.AckEvent, Author: "ops", TS: now}}}))
+	require.NoError(t, src.AddIncident(ctx, "host-2", &types.Incident{StartTS: now, Severity: types.DEGRADED}))
+	require.NoError(t, src.AddAnnouncement(ctx, &types.Announcement{Title: "maintenance", Severity: types.MAINTENANCE, StartTS: now}))
+
+	var buf bytes.Buffer
+	stats, err := Export(ctx, src, &buf)
+	require.NoError(t, err)
+	require.Equal(t, BackupStats{Hosts: 2, Responses: 52, Incidents: 2, Announcements: 1}, stats)
+	require.Equal(t, 1+52+2+1, strings.Count(buf.String(), "\n"))
+
+	for name, f := range stores(t, ctx) {
+		t.Run(name, func(t *testing.T) {
+			s := f()
+
+			stats, err := Import(ctx, s, bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			require.Equal(t, BackupStats{Hosts: 2, Responses: 52, Incidents: 2, Announcements: 1}, stats)
+
+			hosts, err := s.Hosts(ctx)
+			require.NoError(t, err)
+			require.ElementsMatch(t, []string{"host-1", "host-2"}, hosts)
+
+			for _, host := range hosts {
+				expected, err := src.FindResponses(ctx, host)
+				require.NoError(t, err)
+				list, err := s.FindResponses(ctx, host)
+				require.NoError(t, err)
+				require.Len(t, list, len(expected))
+				for i, r := range list {
+					require.True(t, expected[i].Timestamp.Equal(r.Timestamp))
+					r.Timestamp = expected[i].Timestamp
+					require.Equal(t, expected[i], r)
+				}
+			}
+
+			incidents, err := s.FindIncidents(ctx, "host-2", 0, 0)
+			require.NoError(t, err)
+			require.Len(t, incidents, 2)
+			require.Equal(t, types.DEGRADED, incidents[0].Severity)
+			require.NotNil(t, incidents[1].EndTS)
+			require.True(t, end.Equal(*incidents[1].EndTS))
+			require.Len(t, incidents[1].Timeline, 1)
+
+			announcements, err := s.FindAnnouncements(ctx, 0, 0)
+			require.NoError(t, err)
+			require.Len(t, announcements, 1)
+			require.Equal(t, "maintenance", announcements[0].Title)
+
+			// the import again does not duplicate the incidents and the announcements
+			stats, err = Import(ctx, s, bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			require.Equal(t, BackupStats{Hosts: 2, Responses: 52}, stats)
+			incidents, err = s.FindIncidents(ctx, "host-2", 0, 0)
+			require.NoError(t, err)
+			require.Len(t, incidents, 2)
+			announcements, err = s.FindAnnouncements(ctx, 0, 0)
+			require.NoError(t, err)
+			require.Len(t, announcements, 1)
+		})
+	}
+
+	t.Run("batches", func(t *testing.T) {
+		s := NewMemory(ctx)
+		for i := 0; i < exportBatch*2+1; i++ {
+			require.NoError(t, s.AddResponse(ctx, "host", &types.HttpResponse{Timestamp: now.Add(-time.Millisecond * time.Duration(i))}))
+		}
+		stats, err := Export(ctx, s, &bytes.Buffer{})
+		require.NoError(t, err)
+		require.Equal(t, exportBatch*2+1, stats.Responses)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, data := range map[string]string{
+			"empty":          ``,
+			"no header":      `{"type":"response","host":"host","response":{}}`,
+			"newer version":  `{"type":"header","version":2}`,
+			"unknown type":   "{\"type\":\"header\",\"version\":1}\n{\"type\":\"check\"}",
+			"no host":        "{\"type\":\"header\",\"version\":1}\n{\"type\":\"response\",\"response\":{}}",
+			"broken record":  "{\"type\":\"header\",\"version\":1}\n{\"type\":",
+			"empty incident": "{\"type\":\"header\",\"version\":1}\n{\"type\":\"incident\",\"host\":\"host\"}",
+		} {
+			_, err := Import(ctx, NewMemory(ctx), strings.NewReader(data))
+			require.Error(t, err, name)
+		}
+	})
+}
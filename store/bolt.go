@@ -1,11 +1,13 @@
 package store
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	"sort"
+	"log"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,19 +15,46 @@ import (
 	bolt "go.etcd.io/bbolt"
 )
 
+// announcementsBucket - bucket of the manually declared announcements, the host buckets are named by the host id
+const announcementsBucket = "announcements"
+
+// boltTimeout - how long to wait for the lock of the database file
+const boltTimeout = time.Second * 5
+
+// schemaBucket - bucket with the version of the database schema
+const schemaBucket = "schema"
+
+// boltMigrations - schema changes applied on startup in order, the applied version is kept in the schema bucket.
+// The database without the version is the initial one: responses in json keyed by unix seconds.
+// The migration can be split into several transactions, so it must continue the work of the interrupted one.
+// Never change the existing migrations, add the new one instead.
+var boltMigrations = []func(db *bolt.DB) error{
+	migrateBoltResponses,
+}
+
 type Bolt struct {
 	conn *bolt.DB
 }
 
+// NewBolt - opens the database and migrates the schema
 func NewBolt(ctx context.Context, path string) (*Bolt, error) {
-	db, err := bolt.Open(path, 0600, nil)
+	// the file is locked by the running instance, so the commands fail instead of waiting for it
+	opts := *bolt.DefaultOptions
+	opts.Timeout = boltTimeout
+	db, err := bolt.Open(path, 0600, &opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open bolt database: %w", err)
 	}
 
-	return &Bolt{
+	b := &Bolt{
 		conn: db,
-	}, nil
+	}
+	if err := b.migrate(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to migrate bolt database: %w", err)
+	}
+
+	return b, nil
 }
 func (b *Bolt) Close() error {
 	return b.conn.Close()
@@ -37,11 +66,7 @@ func (b *Bolt) AddResponse(ctx context.Context, hostID string, r *types.HttpResp
 		if err != nil {
 			return err
 		}
-		data, err := json.Marshal(r)
-		if err != nil {
-			return err
-		}
-		return bucket.Put(itob(int(r.Timestamp.UTC().Unix())), data)
+		return bucket.Put(responseKey(r.Timestamp), encodeResponse(r))
 	})
 }
 func (b *Bolt) DeleteResponse(ctx context.Context, hostID string, keys []time.Time) error {
@@ -52,7 +77,7 @@ func (b *Bolt) DeleteResponse(ctx context.Context, hostID string, keys []time.Ti
 		}
 
 		for _, key := range keys {
-			if err := bucket.Delete(itob(int(key.UTC().Unix()))); err != nil {
+			if err := bucket.Delete(responseKey(key)); err != nil {
 				return err
 			}
 		}
@@ -61,6 +86,9 @@ func (b *Bolt) DeleteResponse(ctx context.Context, hostID string, keys []time.Ti
 	})
 }
 func (b *Bolt) FindResponses(ctx context.Context, hostID string) ([]*types.HttpResponse, error) {
+	return b.FindResponsesRange(ctx, hostID, time.Time{}, time.Time{}, 0)
+}
+func (b *Bolt) FindResponsesRange(ctx context.Context, hostID string, from, to time.Time, limit int) ([]*types.HttpResponse, error) {
 	res := []*types.HttpResponse{}
 
 	err := b.conn.View(func(tx *bolt.Tx) error {
@@ -68,18 +96,50 @@ func (b *Bolt) FindResponses(ctx context.Context, hostID string) ([]*types.HttpR
 		if bucket == nil {
 			return nil
 		}
-		return bucket.ForEach(func(k, v []byte) error {
-			var r types.HttpResponse
-			if err := json.Unmarshal(v, &r); err != nil {
+
+		// the keys are the unix nanoseconds, so the cursor jumps to the beginning of the range
+		c := bucket.Cursor()
+		k, v := c.First()
+		if !from.IsZero() {
+			k, v = c.Seek(responseKey(from))
+		}
+		for ; k != nil; k, v = c.Next() {
+			if !to.IsZero() && bytes.Compare(k, responseKey(to)) > 0 {
+				break
+			}
+			r, err := decodeResponse(keyTime(k), v)
+			if err != nil {
 				return err
 			}
-			res = append(res, &r)
-			return nil
-		})
+			res = append(res, r)
+			if limit > 0 && len(res) == limit {
+				break
+			}
+		}
+		return nil
 	})
-	sort.Slice(res, func(i, j int) bool {
-		return res[i].Timestamp.Before(res[j].Timestamp)
+
+	return res, err
+}
+func (b *Bolt) LastResponses(ctx context.Context, hostID string, n int) ([]*types.HttpResponse, error) {
+	res := []*types.HttpResponse{}
+
+	err := b.conn.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(hostID))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.Last(); k != nil && len(res) < n; k, v = c.Prev() {
+			r, err := decodeResponse(keyTime(k), v)
+			if err != nil {
+				return err
+			}
+			res = append(res, r)
+		}
+		return nil
 	})
+	slices.Reverse(res)
 
 	return res, err
 }
@@ -96,11 +156,11 @@ func (b *Bolt) LastResponse(ctx context.Context, hostID string) (*types.HttpResp
 		if k == nil {
 			return nil
 		}
-		var r types.HttpResponse
-		if err := json.Unmarshal(v, &r); err != nil {
+		r, err := decodeResponse(keyTime(k), v)
+		if err != nil {
 			return err
 		}
-		res = &r
+		res = r
 		return nil
 	})
 
@@ -112,7 +172,7 @@ func (b *Bolt) Hosts(ctx context.Context) ([]string, error) {
 
 	return keys, b.conn.View(func(tx *bolt.Tx) error {
 		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
-			if !strings.HasPrefix(string(name), "e-") {
+			if isResponsesBucket(name) {
 				keys = append(keys, string(name))
 			}
 			return nil
@@ -170,6 +230,33 @@ func (b *Bolt) EndIncident(ctx context.Context, hostID string, eventID int, ts t
 		return nil
 	})
 }
+func (b *Bolt) AddIncidentEvent(ctx context.Context, hostID string, incidentID int, e *types.IncidentEvent) error {
+	return b.conn.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(fmt.Sprintf("e-%s", hostID)))
+		if bucket == nil {
+			return types.ErrIncidentNotFound
+		}
+
+		v := bucket.Get(itob(incidentID))
+		if v == nil {
+			return types.ErrIncidentNotFound
+		}
+		var incident types.Incident
+		if err := json.Unmarshal(v, &incident); err != nil {
+			return err
+		}
+
+		if err := incident.AddEvent(*e); err != nil {
+			return err
+		}
+		data, err := json.Marshal(incident)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(incidentID), data)
+	})
+}
 func (b *Bolt) DeleteIncident(ctx context.Context, hostID string, eventID int) error {
 	return b.conn.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(fmt.Sprintf("e-%s", hostID)))
@@ -220,6 +307,223 @@ func (b *Bolt) FindIncidents(ctx context.Context, hostID string, skip, limit int
 
 	return res, err
 }
+func (b *Bolt) FindIncident(ctx context.Context, hostID string, id int) (*types.Incident, error) {
+	var e *types.Incident
+	err := b.conn.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(fmt.Sprintf("e-%s", hostID)))
+		if bucket == nil {
+			return types.ErrIncidentNotFound
+		}
+		v := bucket.Get(itob(id))
+		if v == nil {
+			return types.ErrIncidentNotFound
+		}
+		return json.Unmarshal(v, &e)
+	})
+	return e, err
+}
+
+func (b *Bolt) AddAnnouncement(ctx context.Context, a *types.Announcement) error {
+	return b.conn.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(announcementsBucket))
+		if err != nil {
+			return err
+		}
+
+		id, _ := bucket.NextSequence()
+		a.ID = int(id)
+		data, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(a.ID), data)
+	})
+}
+func (b *Bolt) AddAnnouncementUpdate(ctx context.Context, id int, e *types.IncidentEvent) error {
+	return b.updateAnnouncement(id, func(a *types.Announcement) {
+		a.Updates = append(a.Updates, *e)
+	})
+}
+func (b *Bolt) EndAnnouncement(ctx context.Context, id int, ts time.Time) error {
+	return b.updateAnnouncement(id, func(a *types.Announcement) {
+		a.EndTS = &ts
+	})
+}
+func (b *Bolt) DeleteAnnouncement(ctx context.Context, id int) error {
+	return b.conn.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(announcementsBucket))
+		if bucket == nil || bucket.Get(itob(id)) == nil {
+			return types.ErrAnnouncementNotFound
+		}
+		return bucket.Delete(itob(id))
+	})
+}
+func (b *Bolt) FindAnnouncements(ctx context.Context, skip, limit int) ([]*types.Announcement, error) {
+	res := []*types.Announcement{}
+	err := b.conn.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(announcementsBucket))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		i := 0
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			if i++; i <= skip {
+				continue
+			}
+			var a types.Announcement
+			if err := json.Unmarshal(v, &a); err != nil {
+				return err
+			}
+			res = append(res, &a)
+			if limit > 0 && len(res) == limit {
+				break
+			}
+		}
+		return nil
+	})
+
+	return res, err
+}
+
+// updateAnnouncement - applies the change to the stored announcement
+func (b *Bolt) updateAnnouncement(id int, change func(a *types.Announcement)) error {
+	return b.conn.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(announcementsBucket))
+		if bucket == nil {
+			return types.ErrAnnouncementNotFound
+		}
+		v := bucket.Get(itob(id))
+		if v == nil {
+			return types.ErrAnnouncementNotFound
+		}
+
+		var a types.Announcement
+		if err := json.Unmarshal(v, &a); err != nil {
+			return err
+		}
+		change(&a)
+		data, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(id), data)
+	})
+}
+
+// migrate - applies the missing migrations in the single transaction
+func (b *Bolt) migrate() error {
+	version := 0
+	if err := b.conn.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(schemaBucket))
+		if err != nil {
+			return err
+		}
+		if v := bucket.Get([]byte("version")); v != nil {
+			version = int(binary.BigEndian.Uint64(v))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if version > len(boltMigrations) {
+		return fmt.Errorf("database schema version %d is newer than supported %d", version, len(boltMigrations))
+	}
+
+	for i := version; i < len(boltMigrations); i++ {
+		if err := boltMigrations[i](b.conn); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if err := b.conn.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte(schemaBucket)).Put([]byte("version"), itob(i+1))
+		}); err != nil {
+			return err
+		}
+		log.Printf("[INFO] bolt schema migrated to version %d", i+1)
+	}
+
+	return nil
+}
+
+// boltMigrationBatch - number of the records rewritten in one transaction, so the large database is not read into the memory at once
+var boltMigrationBatch = 10000
+
+// legacyKeyLimit - the keys in unix seconds are below it, the keys in unix nanoseconds are above it for any time after 1970-01-01 00:18
+const legacyKeyLimit = 1 << 40
+
+// migrateBoltResponses - rewrites the json responses keyed by unix seconds to the binary encoding keyed by unix nanoseconds.
+// The keys in seconds are sorted before the keys in nanoseconds, so every batch takes the first keys of the bucket
+// and the interrupted migration continues from the first key that is not migrated.
+func migrateBoltResponses(db *bolt.DB) error {
+	names := [][]byte{}
+	if err := db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if isResponsesBucket(name) {
+				names = append(names, slices.Clone(name))
+			}
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		for done := false; !done; {
+			if err := db.Update(func(tx *bolt.Tx) error {
+				bucket := tx.Bucket(name)
+				keys, list := [][]byte{}, []*types.HttpResponse{}
+				c := bucket.Cursor()
+				for k, v := c.First(); k != nil && len(list) < boltMigrationBatch; k, v = c.Next() {
+					if binary.BigEndian.Uint64(k) >= legacyKeyLimit {
+						break
+					}
+					var r types.HttpResponse
+					if err := json.Unmarshal(v, &r); err != nil {
+						return fmt.Errorf("%s: %w", name, err)
+					}
+					keys = append(keys, slices.Clone(k))
+					list = append(list, &r)
+				}
+				done = len(list) < boltMigrationBatch
+
+				// the responses are appended in the order of the keys, so the pages can be filled completely
+				bucket.FillPercent = 1
+				for i, r := range list {
+					if err := bucket.Delete(keys[i]); err != nil {
+						return err
+					}
+					if err := bucket.Put(responseKey(r.Timestamp), encodeResponse(r)); err != nil {
+						return err
+					}
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// isResponsesBucket - returns true if the bucket keeps the responses of the host
+func isResponsesBucket(name []byte) bool {
+	s := string(name)
+	return !strings.HasPrefix(s, "e-") && s != announcementsBucket && s != schemaBucket
+}
+
+// responseKey - returns the key of the response, the unix nanoseconds in big endian, so the keys are sorted by time
+func responseKey(ts time.Time) []byte {
+	return itob(int(unixKey(ts)))
+}
+
+// keyTime - returns the timestamp of the response key
+func keyTime(k []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(k)))
+}
 
 func itob(v int) []byte {
 	b := make([]byte, 8)
@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/exelban/JAM/types"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestEncodeResponse(t *testing.T) {
+	ts := time.Unix(0, time.Now().UnixNano())
+	expiry := ts.Add(time.Hour * 24 * 30)
+
+	t.Run("all fields", func(t *testing.T) {
+		r := &types.HttpResponse{
+			Timestamp:     ts,
+			Time:          time.Millisecond * 123,
+			Code:          503,
+			Body:          "service unavailable",
+			Status:        true,
+			StatusType:    types.DOWN,
+			Reasons:       []string{"status code", "body"},
+			Degraded:      true,
+			DNS:           time.Millisecond,
+			TLSHandshake:  time.Millisecond * 2,
+			Connect:       time.Millisecond * 3,
+			TTFB:          time.Millisecond * 4,
+			SSLCertExpiry: &expiry,
+			Certificate: &types.Certificate{
+				Subject:    "example.com",
+				Issuer:     "CA",
+				DNSNames:   []string{"example.com", "www.example.com"},
+				NotAfter:   expiry,
+				ChainValid: true,
+				ChainError: "none",
+			},
+			IsAggregated: true,
+			Uptime:       0.9875,
+			Count:        60,
+			Resolution:   time.Hour,
+			MinTime:      time.Millisecond * 10,
+			MaxTime:      time.Second,
+			P95Time:      time.Millisecond * 500,
+			Statuses:     map[types.StatusType]int{types.UP: 59, types.DOWN: 1},
+		}
+
+		data := encodeResponse(r)
+		j, err := json.Marshal(r)
+		require.NoError(t, err)
+		require.Less(t, len(data)*2, len(j))
+
+		res, err := decodeResponse(ts, data)
+		require.NoError(t, err)
+		require.Equal(t, r, res)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		r := &types.HttpResponse{Timestamp: ts}
+		data := encodeResponse(r)
+		require.Len(t, data, 2)
+
+		res, err := decodeResponse(ts, data)
+		require.NoError(t, err)
+		require.Equal(t, r, res)
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		data := encodeResponse(&types.HttpResponse{Timestamp: ts, Body: "body", StatusType: types.UP})
+		for i := range data {
+			_, err := decodeResponse(ts, data[:i])
+			require.Error(t, err)
+		}
+		_, err := decodeResponse(ts, append(data, 0))
+		require.Error(t, err)
+		_, err = decodeResponse(ts, []byte(`{"code":200}`))
+		require.Error(t, err)
+	})
+}
+
+func TestBolt_migrate(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "jam.db")
+	now := time.Now().Truncate(time.Second)
+
+	// the responses are migrated in several transactions
+	batch := boltMigrationBatch
+	boltMigrationBatch = 3
+	defer func() {
+		boltMigrationBatch = batch
+	}()
+
+	// the initial schema: json responses keyed by unix seconds and json incidents
+	db, err := bolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte("host"))
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			r := types.HttpResponse{Timestamp: now.Add(-time.Minute * time.Duration(i)), Code: 200 + i, StatusType: types.UP, Time: time.Millisecond}
+			if i == 9 {
+				r = types.HttpResponse{Timestamp: now.AddDate(0, 0, -1), IsAggregated: true, Uptime: 0.5, Count: 10}
+			}
+			data, err := json.Marshal(r)
+			require.NoError(t, err)
+			require.NoError(t, bucket.Put(itob(int(r.Timestamp.Unix())), data))
+		}
+
+		bucket, err = tx.CreateBucket([]byte("e-host"))
+		require.NoError(t, err)
+		data, err := json.Marshal(types.Incident{ID: 1, StartTS: now})
+		require.NoError(t, err)
+		return bucket.Put(itob(1), data)
+	}))
+	require.NoError(t, db.Close())
+
+	b, err := NewBolt(ctx, path)
+	require.NoError(t, err)
+
+	hosts, err := b.Hosts(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"host"}, hosts)
+
+	list, err := b.FindResponses(ctx, "host")
+	require.NoError(t, err)
+	require.Len(t, list, 10)
+	require.True(t, list[0].IsAggregated)
+	require.Equal(t, 0.5, list[0].Uptime)
+	require.True(t, list[0].Timestamp.Equal(now.AddDate(0, 0, -1)))
+	for i, r := range list[1:] {
+		require.Equal(t, 208-i, r.Code)
+		require.Equal(t, types.UP, r.StatusType)
+		require.Equal(t, time.Millisecond, r.Time)
+	}
+
+	incidents, err := b.FindIncidents(ctx, "host", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, incidents, 1)
+
+	// the migration is applied once
+	require.NoError(t, b.AddResponse(ctx, "host", &types.HttpResponse{Timestamp: now.Add(time.Millisecond), Code: 100}))
+	require.NoError(t, b.Close())
+	b, err = NewBolt(ctx, path)
+	require.NoError(t, err)
+	list, err = b.FindResponses(ctx, "host")
+	require.NoError(t, err)
+	require.Len(t, list, 11)
+	require.Equal(t, 100, list[10].Code)
+	require.NoError(t, b.Close())
+
+	t.Run("newer schema", func(t *testing.T) {
+		db, err := bolt.Open(path, 0600, nil)
+		require.NoError(t, err)
+		require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte(schemaBucket)).Put([]byte("version"), itob(len(boltMigrations)+1))
+		}))
+		require.NoError(t, db.Close())
+
+		_, err = NewBolt(ctx, path)
+		require.Error(t, err)
+	})
+}
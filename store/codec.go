@@ -0,0 +1,332 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/exelban/JAM/types"
+)
+
+// responseFormat - version of the binary response encoding, stored as the first byte of the record
+const responseFormat = 1
+
+// fields of the response in the binary encoding. The record starts with the bitmask of the present fields,
+// the values follow in the same order. New fields must be added to the end only.
+const (
+	fieldTime = iota
+	fieldCode
+	fieldBody
+	fieldStatus
+	fieldStatusType
+	fieldReasons
+	fieldDegraded
+	fieldDNS
+	fieldTLSHandshake
+	fieldConnect
+	fieldTTFB
+	fieldSSLCertExpiry
+	fieldCertificate
+	fieldIsAggregated
+	fieldUptime
+	fieldCount
+	fieldResolution
+	fieldMinTime
+	fieldMaxTime
+	fieldP95Time
+	fieldStatuses
+
+	fieldsCount
+)
+
+var errCorruptedResponse = errors.New("corrupted response record")
+
+// encodeResponse - encodes the response in the compact binary format. The timestamp is not included, it's the key of the record
+func encodeResponse(r *types.HttpResponse) []byte {
+	var mask uint64
+	buf := make([]byte, 0, 64)
+
+	field := func(id int, present bool, value func()) {
+		if !present {
+			return
+		}
+		mask |= 1 << id
+		if value != nil {
+			value()
+		}
+	}
+	duration := func(id int, d time.Duration) {
+		field(id, d != 0, func() { buf = binary.AppendVarint(buf, int64(d)) })
+	}
+
+	duration(fieldTime, r.Time)
+	field(fieldCode, r.Code != 0, func() { buf = binary.AppendVarint(buf, int64(r.Code)) })
+	field(fieldBody, r.Body != "", func() { buf = appendString(buf, r.Body) })
+	field(fieldStatus, r.Status, nil)
+	field(fieldStatusType, r.StatusType != "", func() { buf = appendString(buf, string(r.StatusType)) })
+	field(fieldReasons, len(r.Reasons) > 0, func() { buf = appendStrings(buf, r.Reasons) })
+	field(fieldDegraded, r.Degraded, nil)
+	duration(fieldDNS, r.DNS)
+	duration(fieldTLSHandshake, r.TLSHandshake)
+	duration(fieldConnect, r.Connect)
+	duration(fieldTTFB, r.TTFB)
+	field(fieldSSLCertExpiry, r.SSLCertExpiry != nil, func() { buf = appendTime(buf, *r.SSLCertExpiry) })
+	field(fieldCertificate, r.Certificate != nil, func() {
+		c := r.Certificate
+		buf = appendString(buf, c.Subject)
+		buf = appendString(buf, c.Issuer)
+		buf = appendStrings(buf, c.DNSNames)
+		buf = appendTime(buf, c.NotAfter)
+		buf = appendBool(buf, c.ChainValid)
+		buf = appendString(buf, c.ChainError)
+	})
+	field(fieldIsAggregated, r.IsAggregated, nil)
+	field(fieldUptime, r.Uptime != 0, func() { buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(r.Uptime)) })
+	field(fieldCount, r.Count != 0, func() { buf = binary.AppendVarint(buf, int64(r.Count)) })
+	duration(fieldResolution, r.Resolution)
+	duration(fieldMinTime, r.MinTime)
+	duration(fieldMaxTime, r.MaxTime)
+	duration(fieldP95Time, r.P95Time)
+	field(fieldStatuses, len(r.Statuses) > 0, func() {
+		keys := make([]types.StatusType, 0, len(r.Statuses))
+		for k := range r.Statuses {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		buf = binary.AppendUvarint(buf, uint64(len(keys)))
+		for _, k := range keys {
+			buf = appendString(buf, string(k))
+			buf = binary.AppendVarint(buf, int64(r.Statuses[k]))
+		}
+	})
+
+	data := make([]byte, 0, len(buf)+binary.MaxVarintLen64+1)
+	data = append(data, responseFormat)
+	data = binary.AppendUvarint(data, mask)
+	return append(data, buf...)
+}
+
+// decodeResponse - decodes the response encoded by encodeResponse with the timestamp from the key
+func decodeResponse(ts time.Time, data []byte) (*types.HttpResponse, error) {
+	if len(data) == 0 {
+		return nil, errCorruptedResponse
+	}
+	if data[0] != responseFormat {
+		return nil, fmt.Errorf("unsupported response format %d", data[0])
+	}
+
+	d := decoder{buf: data[1:]}
+	mask := d.uvarint()
+	if mask>>fieldsCount != 0 {
+		return nil, fmt.Errorf("unsupported response fields %b", mask>>fieldsCount)
+	}
+	has := func(id int) bool {
+		return mask&(1<<id) != 0
+	}
+
+	r := &types.HttpResponse{Timestamp: ts}
+	if has(fieldTime) {
+		r.Time = d.duration()
+	}
+	if has(fieldCode) {
+		r.Code = int(d.varint())
+	}
+	if has(fieldBody) {
+		r.Body = d.string()
+	}
+	r.Status = has(fieldStatus)
+	if has(fieldStatusType) {
+		r.StatusType = types.StatusType(d.string())
+	}
+	if has(fieldReasons) {
+		r.Reasons = d.strings()
+	}
+	r.Degraded = has(fieldDegraded)
+	if has(fieldDNS) {
+		r.DNS = d.duration()
+	}
+	if has(fieldTLSHandshake) {
+		r.TLSHandshake = d.duration()
+	}
+	if has(fieldConnect) {
+		r.Connect = d.duration()
+	}
+	if has(fieldTTFB) {
+		r.TTFB = d.duration()
+	}
+	if has(fieldSSLCertExpiry) {
+		t := d.time()
+		r.SSLCertExpiry = &t
+	}
+	if has(fieldCertificate) {
+		r.Certificate = &types.Certificate{
+			Subject:    d.string(),
+			Issuer:     d.string(),
+			DNSNames:   d.strings(),
+			NotAfter:   d.time(),
+			ChainValid: d.bool(),
+			ChainError: d.string(),
+		}
+	}
+	r.IsAggregated = has(fieldIsAggregated)
+	if has(fieldUptime) {
+		r.Uptime = math.Float64frombits(d.uint64())
+	}
+	if has(fieldCount) {
+		r.Count = int(d.varint())
+	}
+	if has(fieldResolution) {
+		r.Resolution = d.duration()
+	}
+	if has(fieldMinTime) {
+		r.MinTime = d.duration()
+	}
+	if has(fieldMaxTime) {
+		r.MaxTime = d.duration()
+	}
+	if has(fieldP95Time) {
+		r.P95Time = d.duration()
+	}
+	if has(fieldStatuses) {
+		n := d.uvarint()
+		if n > uint64(len(d.buf)) {
+			return nil, errCorruptedResponse
+		}
+		r.Statuses = make(map[types.StatusType]int, n)
+		for range n {
+			k := types.StatusType(d.string())
+			r.Statuses[k] = int(d.varint())
+		}
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(d.buf) != 0 {
+		return nil, errCorruptedResponse
+	}
+
+	return r, nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+func appendStrings(buf []byte, list []string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(list)))
+	for _, s := range list {
+		buf = appendString(buf, s)
+	}
+	return buf
+}
+func appendBool(buf []byte, v bool) []byte {
+	if v {
+		return append(buf, 1)
+	}
+	return append(buf, 0)
+}
+
+// appendTime - appends the time in unix nanoseconds, the zero time is stored as 0
+func appendTime(buf []byte, t time.Time) []byte {
+	if t.IsZero() {
+		return binary.AppendVarint(buf, 0)
+	}
+	return binary.AppendVarint(buf, t.UnixNano())
+}
+
+// decoder - reads the values of the binary encoding, the first error is kept and the next reads return zero values
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errCorruptedResponse
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errCorruptedResponse
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+func (d *decoder) uint64() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 8 {
+		d.err = errCorruptedResponse
+		return 0
+	}
+	v := binary.BigEndian.Uint64(d.buf)
+	d.buf = d.buf[8:]
+	return v
+}
+func (d *decoder) bool() bool {
+	if d.err != nil {
+		return false
+	}
+	if len(d.buf) < 1 {
+		d.err = errCorruptedResponse
+		return false
+	}
+	v := d.buf[0] != 0
+	d.buf = d.buf[1:]
+	return v
+}
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if n > uint64(len(d.buf)) {
+		d.err = errCorruptedResponse
+		return ""
+	}
+	s := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	return s
+}
+func (d *decoder) strings() []string {
+	n := d.uvarint()
+	if d.err != nil || n == 0 {
+		return nil
+	}
+	if n > uint64(len(d.buf)) {
+		d.err = errCorruptedResponse
+		return nil
+	}
+	list := make([]string, 0, n)
+	for range n {
+		list = append(list, d.string())
+	}
+	return list
+}
+func (d *decoder) duration() time.Duration {
+	return time.Duration(d.varint())
+}
+func (d *decoder) time() time.Time {
+	v := d.varint()
+	if v == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, v)
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -10,14 +11,16 @@ import (
 )
 
 type Memory struct {
-	history   map[string]map[time.Time]*types.HttpResponse
-	incidents map[string][]*types.Incident
+	history       map[string][]*types.HttpResponse // sorted by the timestamp
+	incidents     map[string][]*types.Incident
+	announcements []*types.Announcement
+	announcementN int
 	sync.RWMutex
 }
 
 func NewMemory(ctx context.Context) *Memory {
 	return &Memory{
-		history:   make(map[string]map[time.Time]*types.HttpResponse),
+		history:   make(map[string][]*types.HttpResponse),
 		incidents: make(map[string][]*types.Incident),
 	}
 }
@@ -29,66 +32,85 @@ func (m *Memory) AddResponse(ctx context.Context, hostID string, r *types.HttpRe
 	m.Lock()
 	defer m.Unlock()
 
-	if _, ok := m.history[hostID]; !ok {
-		m.history[hostID] = make(map[time.Time]*types.HttpResponse)
+	history := m.history[hostID]
+	i, found := m.search(history, r.Timestamp)
+	if found {
+		history[i] = r
+		return nil
 	}
+	m.history[hostID] = slices.Insert(history, i, r)
 
-	m.history[hostID][r.Timestamp.UTC()] = r
 	return nil
 }
 func (m *Memory) DeleteResponse(ctx context.Context, hostID string, keys []time.Time) error {
 	m.Lock()
 	defer m.Unlock()
 
-	if _, ok := m.history[hostID]; !ok {
+	history, ok := m.history[hostID]
+	if !ok {
 		return nil
 	}
 
 	for _, key := range keys {
-		for ts := range m.history[hostID] {
-			if ts == key.UTC() {
-				delete(m.history[hostID], ts)
-				break
-			}
+		if i, found := m.search(history, key); found {
+			history = slices.Delete(history, i, i+1)
 		}
 	}
+	m.history[hostID] = history
 
 	return nil
 }
 func (m *Memory) FindResponses(ctx context.Context, hostID string) ([]*types.HttpResponse, error) {
+	return m.FindResponsesRange(ctx, hostID, time.Time{}, time.Time{}, 0)
+}
+func (m *Memory) FindResponsesRange(ctx context.Context, hostID string, from, to time.Time, limit int) ([]*types.HttpResponse, error) {
 	m.RLock()
 	defer m.RUnlock()
 
-	if _, ok := m.history[hostID]; !ok {
+	history := m.history[hostID]
+	start, end := 0, len(history)
+	if !from.IsZero() {
+		start, _ = m.search(history, from)
+	}
+	if !to.IsZero() {
+		end = sort.Search(len(history), func(i int) bool {
+			return history[i].Timestamp.After(to)
+		})
+	}
+	if end < start {
+		end = start
+	}
+	if limit > 0 && end-start > limit {
+		end = start + limit
+	}
+
+	return slices.Clone(history[start:end]), nil
+}
+func (m *Memory) LastResponses(ctx context.Context, hostID string, n int) ([]*types.HttpResponse, error) {
+	if n <= 0 {
 		return []*types.HttpResponse{}, nil
 	}
 
-	res := make([]*types.HttpResponse, 0, len(m.history[hostID]))
-	for _, r := range m.history[hostID] {
-		res = append(res, r)
+	m.RLock()
+	defer m.RUnlock()
+
+	history := m.history[hostID]
+	if n < len(history) {
+		history = history[len(history)-n:]
 	}
-	sort.Slice(res, func(i, j int) bool {
-		return res[i].Timestamp.Before(res[j].Timestamp)
-	})
 
-	return res, nil
+	return slices.Clone(history), nil
 }
 func (m *Memory) LastResponse(ctx context.Context, hostID string) (*types.HttpResponse, error) {
 	m.RLock()
 	defer m.RUnlock()
 
-	if _, ok := m.history[hostID]; !ok {
+	history := m.history[hostID]
+	if len(history) == 0 {
 		return nil, nil
 	}
 
-	var last *types.HttpResponse
-	for _, r := range m.history[hostID] {
-		if last == nil || r.Timestamp.After(last.Timestamp) {
-			last = r
-		}
-	}
-
-	return last, nil
+	return history[len(history)-1], nil
 }
 
 func (m *Memory) Hosts(ctx context.Context) ([]string, error) {
@@ -112,7 +134,7 @@ func (m *Memory) AddIncident(ctx context.Context, hostID string, e *types.Incide
 	}
 
 	e.ID = len(m.incidents[hostID]) + 1
-	m.incidents[hostID] = append(m.incidents[hostID], e)
+	m.incidents[hostID] = append(m.incidents[hostID], copyIncident(e))
 
 	return nil
 }
@@ -134,6 +156,18 @@ func (m *Memory) EndIncident(ctx context.Context, hostID string, eventID int, ts
 
 	return nil
 }
+func (m *Memory) AddIncidentEvent(ctx context.Context, hostID string, incidentID int, e *types.IncidentEvent) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, incident := range m.incidents[hostID] {
+		if incident.ID == incidentID {
+			return incident.AddEvent(*e)
+		}
+	}
+
+	return types.ErrIncidentNotFound
+}
 func (m *Memory) DeleteIncident(ctx context.Context, hostID string, eventID int) error {
 	m.Lock()
 	defer m.Unlock()
@@ -161,7 +195,7 @@ func (m *Memory) FindIncidents(ctx context.Context, hostID string, skip, limit i
 
 	res := make([]*types.Incident, 0, len(m.incidents[hostID]))
 	for _, e := range m.incidents[hostID] {
-		res = append(res, e)
+		res = append(res, copyIncident(e))
 	}
 	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
 		res[i], res[j] = res[j], res[i]
@@ -179,3 +213,118 @@ func (m *Memory) FindIncidents(ctx context.Context, hostID string, skip, limit i
 
 	return res, nil
 }
+func (m *Memory) FindIncident(ctx context.Context, hostID string, id int) (*types.Incident, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	for _, e := range m.incidents[hostID] {
+		if e.ID == id {
+			return copyIncident(e), nil
+		}
+	}
+
+	return nil, types.ErrIncidentNotFound
+}
+
+func (m *Memory) AddAnnouncement(ctx context.Context, a *types.Announcement) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.announcementN++
+	a.ID = m.announcementN
+	m.announcements = append(m.announcements, copyAnnouncement(a))
+
+	return nil
+}
+func (m *Memory) AddAnnouncementUpdate(ctx context.Context, id int, e *types.IncidentEvent) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, a := range m.announcements {
+		if a.ID == id {
+			a.Updates = append(a.Updates, *e)
+			return nil
+		}
+	}
+
+	return types.ErrAnnouncementNotFound
+}
+func (m *Memory) EndAnnouncement(ctx context.Context, id int, ts time.Time) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, a := range m.announcements {
+		if a.ID == id {
+			a.EndTS = &ts
+			return nil
+		}
+	}
+
+	return types.ErrAnnouncementNotFound
+}
+func (m *Memory) DeleteAnnouncement(ctx context.Context, id int) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for i, a := range m.announcements {
+		if a.ID == id {
+			m.announcements = append(m.announcements[:i], m.announcements[i+1:]...)
+			return nil
+		}
+	}
+
+	return types.ErrAnnouncementNotFound
+}
+func (m *Memory) FindAnnouncements(ctx context.Context, skip, limit int) ([]*types.Announcement, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	res := make([]*types.Announcement, 0, len(m.announcements))
+	for i := len(m.announcements) - 1; i >= 0; i-- {
+		res = append(res, copyAnnouncement(m.announcements[i]))
+	}
+
+	if skip > 0 {
+		if len(res) < skip {
+			return []*types.Announcement{}, nil
+		}
+		res = res[skip:]
+	}
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+
+	return res, nil
+}
+
+// search - returns the position of the response with the timestamp in the sorted history or the position where it should be inserted
+func (m *Memory) search(history []*types.HttpResponse, ts time.Time) (int, bool) {
+	return sort.Find(len(history), func(i int) int {
+		return ts.Compare(history[i].Timestamp)
+	})
+}
+
+// copyIncident - returns the copy of the incident, so the caller cannot modify the stored one
+func copyIncident(e *types.Incident) *types.Incident {
+	c := *e
+	if e.EndTS != nil {
+		ts := *e.EndTS
+		c.EndTS = &ts
+	}
+	c.Details.Reasons = append([]string(nil), e.Details.Reasons...)
+	c.Timeline = append([]types.IncidentEvent(nil), e.Timeline...)
+	return &c
+}
+
+// copyAnnouncement - returns the copy of the announcement, so the caller cannot modify the stored one
+func copyAnnouncement(a *types.Announcement) *types.Announcement {
+	c := *a
+	if a.EndTS != nil {
+		ts := *a.EndTS
+		c.EndTS = &ts
+	}
+	c.Hosts = append([]string(nil), a.Hosts...)
+	c.Groups = append([]string(nil), a.Groups...)
+	c.Updates = append([]types.IncidentEvent(nil), a.Updates...)
+	return &c
+}
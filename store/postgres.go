@@ -0,0 +1,411 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/exelban/JAM/types"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresMigrations - schema changes applied on startup in order, the applied version is kept in the schema_migrations table.
+// Never change the existing migrations, add the new one instead.
+var postgresMigrations = []string{
+	`CREATE TABLE responses (
+		host_id       TEXT        NOT NULL,
+		ts            TIMESTAMPTZ NOT NULL,
+		status        TEXT        NOT NULL DEFAULT '',
+		code          INTEGER     NOT NULL DEFAULT 0,
+		response_time BIGINT      NOT NULL DEFAULT 0,
+		aggregated    BOOLEAN     NOT NULL DEFAULT FALSE,
+		data          JSONB       NOT NULL,
+		PRIMARY KEY (host_id, ts)
+	);
+	CREATE TABLE incidents (
+		id       BIGSERIAL   PRIMARY KEY,
+		host_id  TEXT        NOT NULL,
+		start_ts TIMESTAMPTZ NOT NULL,
+		end_ts   TIMESTAMPTZ,
+		data     JSONB       NOT NULL
+	);
+	CREATE INDEX incidents_host_id ON incidents (host_id, id);
+	CREATE TABLE announcements (
+		id       BIGSERIAL   PRIMARY KEY,
+		start_ts TIMESTAMPTZ NOT NULL,
+		end_ts   TIMESTAMPTZ,
+		data     JSONB       NOT NULL
+	);`,
+}
+
+// postgresLock - key of the advisory lock, so only one instance migrates the schema
+const postgresLock = 0x4a414d
+
+type Postgres struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgres - connects to the database and migrates the schema. The pool is configured via the dsn, e.g. pool_max_conns=10
+func NewPostgres(ctx context.Context, dsn string) (*Postgres, error) {
+	if dsn == "" {
+		return nil, errors.New("postgres dsn is not defined")
+	}
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgres dsn: %w", err)
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	p := &Postgres{
+		pool: pool,
+	}
+	if err := p.migrate(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to migrate postgres schema: %w", err)
+	}
+
+	return p, nil
+}
+func (p *Postgres) Close() error {
+	p.pool.Close()
+	return nil
+}
+
+func (p *Postgres) AddResponse(ctx context.Context, hostID string, r *types.HttpResponse) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = p.pool.Exec(ctx, `INSERT INTO responses (host_id, ts, status, code, response_time, aggregated, data) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (host_id, ts) DO UPDATE SET status = EXCLUDED.status, code = EXCLUDED.code, response_time = EXCLUDED.response_time, aggregated = EXCLUDED.aggregated, data = EXCLUDED.data`,
+		hostID, pgTime(r.Timestamp), string(r.StatusType), r.Code, r.Time.Milliseconds(), r.IsAggregated, data)
+	return err
+}
+func (p *Postgres) DeleteResponse(ctx context.Context, hostID string, keys []time.Time) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	list := make([]time.Time, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, pgTime(key))
+	}
+	_, err := p.pool.Exec(ctx, `DELETE FROM responses WHERE host_id = $1 AND ts = ANY($2)`, hostID, list)
+	return err
+}
+func (p *Postgres) FindResponses(ctx context.Context, hostID string) ([]*types.HttpResponse, error) {
+	return p.responses(ctx, hostID, time.Time{}, time.Time{}, 0, false)
+}
+func (p *Postgres) FindResponsesRange(ctx context.Context, hostID string, from, to time.Time, limit int) ([]*types.HttpResponse, error) {
+	return p.responses(ctx, hostID, from, to, limit, false)
+}
+func (p *Postgres) LastResponses(ctx context.Context, hostID string, n int) ([]*types.HttpResponse, error) {
+	if n <= 0 {
+		return []*types.HttpResponse{}, nil
+	}
+	list, err := p.responses(ctx, hostID, time.Time{}, time.Time{}, n, true)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(list)
+	return list, nil
+}
+func (p *Postgres) LastResponse(ctx context.Context, hostID string) (*types.HttpResponse, error) {
+	list, err := p.responses(ctx, hostID, time.Time{}, time.Time{}, 1, true)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return list[0], nil
+}
+
+func (p *Postgres) Hosts(ctx context.Context) ([]string, error) {
+	// skip scan over the (host_id, ts) primary key, DISTINCT reads every response
+	rows, err := p.pool.Query(ctx, `WITH RECURSIVE hosts AS (
+		(SELECT host_id FROM responses ORDER BY host_id LIMIT 1)
+		UNION ALL
+		SELECT (SELECT r.host_id FROM responses r WHERE r.host_id > h.host_id ORDER BY r.host_id LIMIT 1)
+		FROM hosts h WHERE h.host_id IS NOT NULL
+	)
+	SELECT host_id FROM hosts WHERE host_id IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+	return keys, nil
+}
+
+func (p *Postgres) AddIncident(ctx context.Context, hostID string, e *types.Incident) error {
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var id int
+		if err := tx.QueryRow(ctx, `INSERT INTO incidents (host_id, start_ts, end_ts, data) VALUES ($1, $2, $3, '{}') RETURNING id`,
+			hostID, pgTime(e.StartTS), pgNullTime(e.EndTS)).Scan(&id); err != nil {
+			return err
+		}
+
+		// the id is a part of the stored json, so it's written after the insert
+		e.ID = id
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `UPDATE incidents SET data = $1 WHERE id = $2`, data, id)
+		return err
+	})
+}
+func (p *Postgres) EndIncident(ctx context.Context, hostID string, eventID int, ts time.Time) error {
+	err := p.updateIncident(ctx, hostID, eventID, func(e *types.Incident) error {
+		e.EndTS = &ts
+		return nil
+	})
+	if errors.Is(err, types.ErrIncidentNotFound) {
+		return nil
+	}
+	return err
+}
+func (p *Postgres) AddIncidentEvent(ctx context.Context, hostID string, incidentID int, e *types.IncidentEvent) error {
+	return p.updateIncident(ctx, hostID, incidentID, func(incident *types.Incident) error {
+		return incident.AddEvent(*e)
+	})
+}
+func (p *Postgres) DeleteIncident(ctx context.Context, hostID string, eventID int) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM incidents WHERE host_id = $1 AND id = $2`, hostID, eventID)
+	return err
+}
+func (p *Postgres) FindIncidents(ctx context.Context, hostID string, skip, limit int) ([]*types.Incident, error) {
+	rows, err := p.pool.Query(ctx, `SELECT data FROM incidents WHERE host_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3`,
+		hostID, pgLimit(limit), max(skip, 0))
+	if err != nil {
+		return nil, err
+	}
+	return collectJSON[types.Incident](rows)
+}
+func (p *Postgres) FindIncident(ctx context.Context, hostID string, id int) (*types.Incident, error) {
+	var data []byte
+	err := p.pool.QueryRow(ctx, `SELECT data FROM incidents WHERE host_id = $1 AND id = $2`, hostID, id).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, types.ErrIncidentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e types.Incident
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (p *Postgres) AddAnnouncement(ctx context.Context, a *types.Announcement) error {
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var id int
+		if err := tx.QueryRow(ctx, `INSERT INTO announcements (start_ts, end_ts, data) VALUES ($1, $2, '{}') RETURNING id`,
+			pgTime(a.StartTS), pgNullTime(a.EndTS)).Scan(&id); err != nil {
+			return err
+		}
+
+		a.ID = id
+		data, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `UPDATE announcements SET data = $1 WHERE id = $2`, data, id)
+		return err
+	})
+}
+func (p *Postgres) AddAnnouncementUpdate(ctx context.Context, id int, e *types.IncidentEvent) error {
+	return p.updateAnnouncement(ctx, id, func(a *types.Announcement) {
+		a.Updates = append(a.Updates, *e)
+	})
+}
+func (p *Postgres) EndAnnouncement(ctx context.Context, id int, ts time.Time) error {
+	return p.updateAnnouncement(ctx, id, func(a *types.Announcement) {
+		a.EndTS = &ts
+	})
+}
+func (p *Postgres) DeleteAnnouncement(ctx context.Context, id int) error {
+	tag, err := p.pool.Exec(ctx, `DELETE FROM announcements WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return types.ErrAnnouncementNotFound
+	}
+	return nil
+}
+func (p *Postgres) FindAnnouncements(ctx context.Context, skip, limit int) ([]*types.Announcement, error) {
+	rows, err := p.pool.Query(ctx, `SELECT data FROM announcements ORDER BY id DESC LIMIT $1 OFFSET $2`, pgLimit(limit), max(skip, 0))
+	if err != nil {
+		return nil, err
+	}
+	return collectJSON[types.Announcement](rows)
+}
+
+// responses - returns the responses of the host in the [from, to] range, zero bounds are not applied.
+// The latest responses are selected first if desc is set, the limit is not applied if it's zero.
+func (p *Postgres) responses(ctx context.Context, hostID string, from, to time.Time, limit int, desc bool) ([]*types.HttpResponse, error) {
+	query := `SELECT data FROM responses WHERE host_id = $1 AND ($2::timestamptz IS NULL OR ts >= $2) AND ($3::timestamptz IS NULL OR ts <= $3)`
+	if desc {
+		query += ` ORDER BY ts DESC`
+	} else {
+		query += ` ORDER BY ts`
+	}
+	query += ` LIMIT $4`
+
+	var fromTS, toTS *time.Time
+	if !from.IsZero() {
+		fromTS = &from
+	}
+	if !to.IsZero() {
+		toTS = &to
+	}
+
+	rows, err := p.pool.Query(ctx, query, hostID, pgNullTime(fromTS), pgNullTime(toTS), pgLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	return collectJSON[types.HttpResponse](rows)
+}
+
+// updateIncident - applies the change to the stored incident, the row is locked until the change is saved
+func (p *Postgres) updateIncident(ctx context.Context, hostID string, id int, change func(e *types.Incident) error) error {
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var data []byte
+		err := tx.QueryRow(ctx, `SELECT data FROM incidents WHERE host_id = $1 AND id = $2 FOR UPDATE`, hostID, id).Scan(&data)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return types.ErrIncidentNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var e types.Incident
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		if err := change(&e); err != nil {
+			return err
+		}
+		if data, err = json.Marshal(e); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE incidents SET data = $1, end_ts = $2 WHERE id = $3`, data, pgNullTime(e.EndTS), id)
+		return err
+	})
+}
+
+// updateAnnouncement - applies the change to the stored announcement, the row is locked until the change is saved
+func (p *Postgres) updateAnnouncement(ctx context.Context, id int, change func(a *types.Announcement)) error {
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var data []byte
+		err := tx.QueryRow(ctx, `SELECT data FROM announcements WHERE id = $1 FOR UPDATE`, id).Scan(&data)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return types.ErrAnnouncementNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var a types.Announcement
+		if err := json.Unmarshal(data, &a); err != nil {
+			return err
+		}
+		change(&a)
+		if data, err = json.Marshal(a); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE announcements SET data = $1, end_ts = $2 WHERE id = $3`, data, pgNullTime(a.EndTS), id)
+		return err
+	})
+}
+
+// migrate - applies the missing migrations in the single transaction
+func (p *Postgres) migrate(ctx context.Context) error {
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresLock); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at TIMESTAMPTZ NOT NULL DEFAULT now())`); err != nil {
+			return err
+		}
+
+		var version int
+		if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+			return err
+		}
+		if version > len(postgresMigrations) {
+			return fmt.Errorf("database schema version %d is newer than supported %d", version, len(postgresMigrations))
+		}
+
+		for i := version; i < len(postgresMigrations); i++ {
+			if _, err := tx.Exec(ctx, postgresMigrations[i]); err != nil {
+				return fmt.Errorf("migration %d: %w", i+1, err)
+			}
+			if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, i+1); err != nil {
+				return err
+			}
+			log.Printf("[INFO] postgres schema migrated to version %d", i+1)
+		}
+
+		return nil
+	})
+}
+
+// collectJSON - decodes the single json column of the rows
+func collectJSON[T any](rows pgx.Rows) ([]*T, error) {
+	res := []*T{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		res = append(res, &v)
+	}
+	return res, rows.Err()
+}
+
+// pgTime - postgres keeps timestamps with microsecond precision, the keys are truncated to match them on delete
+func pgTime(ts time.Time) time.Time {
+	return ts.UTC().Truncate(time.Microsecond)
+}
+
+// pgNullTime - returns the nullable timestamp column value
+func pgNullTime(ts *time.Time) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	v := pgTime(*ts)
+	return &v
+}
+
+// pgLimit - returns the limit value, NULL means no limit
+func pgLimit(limit int) *int {
+	if limit <= 0 {
+		return nil
+	}
+	return &limit
+}
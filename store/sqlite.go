@@ -0,0 +1,416 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/exelban/JAM/types"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema - tables of the sqlite storage, timestamps are unix nanoseconds in UTC.
+// The main fields are duplicated in the columns, so the history can be queried without parsing the json.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS responses (
+	host_id       TEXT    NOT NULL,
+	timestamp     INTEGER NOT NULL,
+	status        TEXT    NOT NULL DEFAULT '',
+	code          INTEGER NOT NULL DEFAULT 0,
+	response_time INTEGER NOT NULL DEFAULT 0,
+	aggregated    INTEGER NOT NULL DEFAULT 0,
+	data          TEXT    NOT NULL,
+	PRIMARY KEY (host_id, timestamp)
+) WITHOUT ROWID;
+
+CREATE TABLE IF NOT EXISTS incidents (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	host_id  TEXT    NOT NULL,
+	start_ts INTEGER NOT NULL,
+	end_ts   INTEGER,
+	data     TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS incidents_host_id ON incidents (host_id, id);
+
+CREATE TABLE IF NOT EXISTS announcements (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	start_ts INTEGER NOT NULL,
+	end_ts   INTEGER,
+	data     TEXT    NOT NULL
+);
+`
+
+type SQLite struct {
+	conn *sql.DB
+}
+
+func NewSQLite(ctx context.Context, path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	// sqlite allows only one writer, the readers from the other processes are not blocked in the WAL mode
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+
+	return &SQLite{
+		conn: db,
+	}, nil
+}
+func (s *SQLite) Close() error {
+	return s.conn.Close()
+}
+
+func (s *SQLite) AddResponse(ctx context.Context, hostID string, r *types.HttpResponse) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.ExecContext(ctx, `INSERT OR REPLACE INTO responses (host_id, timestamp, status, code, response_time, aggregated, data) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		hostID, r.Timestamp.UTC().UnixNano(), string(r.StatusType), r.Code, r.Time.Milliseconds(), r.IsAggregated, string(data))
+	return err
+}
+func (s *SQLite) DeleteResponse(ctx context.Context, hostID string, keys []time.Time) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, `DELETE FROM responses WHERE host_id = ? AND timestamp = ?`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, key := range keys {
+		if _, err := stmt.ExecContext(ctx, hostID, key.UTC().UnixNano()); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+func (s *SQLite) FindResponses(ctx context.Context, hostID string) ([]*types.HttpResponse, error) {
+	return s.FindResponsesRange(ctx, hostID, time.Time{}, time.Time{}, 0)
+}
+func (s *SQLite) FindResponsesRange(ctx context.Context, hostID string, from, to time.Time, limit int) ([]*types.HttpResponse, error) {
+	fromTS, toTS := int64(math.MinInt64), int64(math.MaxInt64)
+	if !from.IsZero() {
+		fromTS = unixKey(from)
+	}
+	if !to.IsZero() {
+		toTS = unixKey(to)
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := s.conn.QueryContext(ctx, `SELECT data FROM responses WHERE host_id = ? AND timestamp BETWEEN ? AND ? ORDER BY timestamp LIMIT ?`,
+		hostID, fromTS, toTS, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.responses(rows)
+}
+func (s *SQLite) LastResponses(ctx context.Context, hostID string, n int) ([]*types.HttpResponse, error) {
+	rows, err := s.conn.QueryContext(ctx, `SELECT data FROM (SELECT timestamp, data FROM responses WHERE host_id = ? ORDER BY timestamp DESC LIMIT ?) ORDER BY timestamp`,
+		hostID, max(n, 0))
+	if err != nil {
+		return nil, err
+	}
+	return s.responses(rows)
+}
+func (s *SQLite) LastResponse(ctx context.Context, hostID string) (*types.HttpResponse, error) {
+	var r types.HttpResponse
+	err := scanJSON(s.conn.QueryRowContext(ctx, `SELECT data FROM responses WHERE host_id = ? ORDER BY timestamp DESC LIMIT 1`, hostID), &r)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *SQLite) Hosts(ctx context.Context) ([]string, error) {
+	rows, err := s.conn.QueryContext(ctx, `SELECT DISTINCT host_id FROM responses`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (s *SQLite) AddIncident(ctx context.Context, hostID string, e *types.Incident) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO incidents (host_id, start_ts, data) VALUES (?, ?, '{}')`, hostID, e.StartTS.UTC().UnixNano())
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	// the id is a part of the stored json, so it's written after the insert
+	e.ID = int(id)
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE incidents SET data = ?, end_ts = ? WHERE id = ?`, string(data), unixNano(e.EndTS), id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+func (s *SQLite) EndIncident(ctx context.Context, hostID string, eventID int, ts time.Time) error {
+	err := s.updateIncident(ctx, hostID, eventID, func(e *types.Incident) error {
+		e.EndTS = &ts
+		return nil
+	})
+	if errors.Is(err, types.ErrIncidentNotFound) {
+		return nil
+	}
+	return err
+}
+func (s *SQLite) AddIncidentEvent(ctx context.Context, hostID string, incidentID int, e *types.IncidentEvent) error {
+	return s.updateIncident(ctx, hostID, incidentID, func(incident *types.Incident) error {
+		return incident.AddEvent(*e)
+	})
+}
+func (s *SQLite) DeleteIncident(ctx context.Context, hostID string, eventID int) error {
+	_, err := s.conn.ExecContext(ctx, `DELETE FROM incidents WHERE host_id = ? AND id = ?`, hostID, eventID)
+	return err
+}
+func (s *SQLite) FindIncidents(ctx context.Context, hostID string, skip, limit int) ([]*types.Incident, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.conn.QueryContext(ctx, `SELECT data FROM incidents WHERE host_id = ? ORDER BY id DESC LIMIT ? OFFSET ?`, hostID, limit, max(skip, 0))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []*types.Incident{}
+	for rows.Next() {
+		var e types.Incident
+		if err := scanJSON(rows, &e); err != nil {
+			return nil, err
+		}
+		res = append(res, &e)
+	}
+
+	return res, rows.Err()
+}
+func (s *SQLite) FindIncident(ctx context.Context, hostID string, id int) (*types.Incident, error) {
+	var e types.Incident
+	err := scanJSON(s.conn.QueryRowContext(ctx, `SELECT data FROM incidents WHERE host_id = ? AND id = ?`, hostID, id), &e)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, types.ErrIncidentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *SQLite) AddAnnouncement(ctx context.Context, a *types.Announcement) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO announcements (start_ts, data) VALUES (?, '{}')`, a.StartTS.UTC().UnixNano())
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	a.ID = int(id)
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE announcements SET data = ?, end_ts = ? WHERE id = ?`, string(data), unixNano(a.EndTS), id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+func (s *SQLite) AddAnnouncementUpdate(ctx context.Context, id int, e *types.IncidentEvent) error {
+	return s.updateAnnouncement(ctx, id, func(a *types.Announcement) {
+		a.Updates = append(a.Updates, *e)
+	})
+}
+func (s *SQLite) EndAnnouncement(ctx context.Context, id int, ts time.Time) error {
+	return s.updateAnnouncement(ctx, id, func(a *types.Announcement) {
+		a.EndTS = &ts
+	})
+}
+func (s *SQLite) DeleteAnnouncement(ctx context.Context, id int) error {
+	res, err := s.conn.ExecContext(ctx, `DELETE FROM announcements WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return types.ErrAnnouncementNotFound
+	}
+	return nil
+}
+func (s *SQLite) FindAnnouncements(ctx context.Context, skip, limit int) ([]*types.Announcement, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.conn.QueryContext(ctx, `SELECT data FROM announcements ORDER BY id DESC LIMIT ? OFFSET ?`, limit, max(skip, 0))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []*types.Announcement{}
+	for rows.Next() {
+		var a types.Announcement
+		if err := scanJSON(rows, &a); err != nil {
+			return nil, err
+		}
+		res = append(res, &a)
+	}
+
+	return res, rows.Err()
+}
+
+// responses - decodes the responses from the rows
+func (s *SQLite) responses(rows *sql.Rows) ([]*types.HttpResponse, error) {
+	defer rows.Close()
+
+	res := []*types.HttpResponse{}
+	for rows.Next() {
+		var r types.HttpResponse
+		if err := scanJSON(rows, &r); err != nil {
+			return nil, err
+		}
+		res = append(res, &r)
+	}
+
+	return res, rows.Err()
+}
+
+// updateIncident - applies the change to the stored incident in the transaction
+func (s *SQLite) updateIncident(ctx context.Context, hostID string, id int, change func(e *types.Incident) error) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var e types.Incident
+	err = scanJSON(tx.QueryRowContext(ctx, `SELECT data FROM incidents WHERE host_id = ? AND id = ?`, hostID, id), &e)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.ErrIncidentNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := change(&e); err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE incidents SET data = ?, end_ts = ? WHERE id = ?`, string(data), unixNano(e.EndTS), id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateAnnouncement - applies the change to the stored announcement in the transaction
+func (s *SQLite) updateAnnouncement(ctx context.Context, id int, change func(a *types.Announcement)) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var a types.Announcement
+	err = scanJSON(tx.QueryRowContext(ctx, `SELECT data FROM announcements WHERE id = ?`, id), &a)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.ErrAnnouncementNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	change(&a)
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE announcements SET data = ?, end_ts = ? WHERE id = ?`, string(data), unixNano(a.EndTS), id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// scanJSON - scans the single json column of the row into v
+func scanJSON(row interface{ Scan(dest ...any) error }, v any) error {
+	var data []byte
+	if err := row.Scan(&data); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// unixNano - returns the nullable timestamp column value
+func unixNano(ts *time.Time) *int64 {
+	if ts == nil {
+		return nil
+	}
+	v := ts.UTC().UnixNano()
+	return &v
+}
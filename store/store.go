@@ -1,11 +1,14 @@
 package store
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"log"
+	"math"
 	"math/rand/v2"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/exelban/JAM/types"
@@ -14,10 +17,16 @@ import (
 type Interface interface {
 	// AddResponse adds a new response to the history of the given ID.
 	// DeleteResponse deletes the given keys from the history of the given ID.
-	// FindResponses returns the history of the given ID.
+	// FindResponses returns the whole history of the given ID, use the range queries for the recent responses.
+	// FindResponsesRange returns the responses between from and to inclusive, starting from the oldest one.
+	// Zero from or to means no bound, the limit is not applied if it's zero.
+	// LastResponses returns the last n responses, starting from the oldest one. The list is empty if n is not positive.
+	// LastResponse returns the latest response or nil if there are no responses.
 	AddResponse(ctx context.Context, hostID string, r *types.HttpResponse) error
 	DeleteResponse(ctx context.Context, hostID string, keys []time.Time) error
 	FindResponses(ctx context.Context, hostID string) ([]*types.HttpResponse, error)
+	FindResponsesRange(ctx context.Context, hostID string, from, to time.Time, limit int) ([]*types.HttpResponse, error)
+	LastResponses(ctx context.Context, hostID string, n int) ([]*types.HttpResponse, error)
 	LastResponse(ctx context.Context, hostID string) (*types.HttpResponse, error)
 
 	// Hosts returns a list of all hosts that has any responses in the store.
@@ -27,40 +36,39 @@ type Interface interface {
 	// EndIncident marks the incident as finished by setting the end time.
 	// DeleteIncident removes the incident from the store.
 	// FindIncidents returns the list of incidents for the ID.
+	// FindIncident returns the incident by id, types.ErrIncidentNotFound if there is no such incident.
+	// AddIncidentEvent appends the event to the incident timeline with types.Incident.AddEvent, returns types.ErrIncidentNotFound if there is no such incident.
 	AddIncident(ctx context.Context, hostID string, e *types.Incident) error
 	EndIncident(ctx context.Context, hostID string, eventID int, ts time.Time) error
+	AddIncidentEvent(ctx context.Context, hostID string, incidentID int, e *types.IncidentEvent) error
 	DeleteIncident(ctx context.Context, hostID string, eventID int) error
 	FindIncidents(ctx context.Context, hostID string, skip, limit int) ([]*types.Incident, error)
+	FindIncident(ctx context.Context, hostID string, id int) (*types.Incident, error)
+
+	// AddAnnouncement puts a new manually declared announcement to the store.
+	// AddAnnouncementUpdate appends the update to the announcement.
+	// EndAnnouncement marks the announcement as resolved by setting the end time.
+	// DeleteAnnouncement removes the announcement from the store.
+	// FindAnnouncements returns the list of announcements starting from the latest one.
+	// Methods return types.ErrAnnouncementNotFound if there is no announcement with such ID.
+	AddAnnouncement(ctx context.Context, a *types.Announcement) error
+	AddAnnouncementUpdate(ctx context.Context, id int, e *types.IncidentEvent) error
+	EndAnnouncement(ctx context.Context, id int, ts time.Time) error
+	DeleteAnnouncement(ctx context.Context, id int) error
+	FindAnnouncements(ctx context.Context, skip, limit int) ([]*types.Announcement, error)
 
 	Close() error
 }
 
-func New(ctx context.Context, typ string, cfg *types.Cfg) (Interface, error) {
-	var store Interface
-
-	switch typ {
-	case "memory":
-		store = NewMemory(ctx)
-		log.Printf("[INFO] using memory storage")
-	default:
-		dbPath := "./data"
-		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
-			if err := os.Mkdir(dbPath, 0755); err != nil {
-				return nil, fmt.Errorf("failed to create data directory: %w", err)
-			}
-		}
-		dbFilePath := fmt.Sprintf("%s/%s", dbPath, "jam.db")
-
-		s, err := NewBolt(ctx, dbFilePath)
-		if err != nil {
-			return nil, err
-		}
-		store = s
-
-		log.Printf("[INFO] using bolt storage at %s", dbFilePath)
+// New - creates the storage of the type, the dsn is used only by the postgres storage.
+// The history is aggregated on startup and every midnight by the retention, the zero one uses the defaults.
+func New(ctx context.Context, typ, dsn string, retention types.Retention) (Interface, error) {
+	store, err := Open(ctx, typ, dsn)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := Aggregate(ctx, store); err != nil {
+	if err := Aggregate(ctx, store, retention); err != nil {
 		return nil, fmt.Errorf("failed to aggregate: %w", err)
 	}
 
@@ -69,7 +77,7 @@ func New(ctx context.Context, typ string, cfg *types.Cfg) (Interface, error) {
 		for {
 			select {
 			case <-tk.C:
-				if err := Aggregate(ctx, store); err != nil {
+				if err := Aggregate(ctx, store, retention); err != nil {
 					log.Printf("[ERROR] failed to aggregate: %v", err)
 				}
 				nextRun := hoursToMidnight()
@@ -85,10 +93,82 @@ func New(ctx context.Context, typ string, cfg *types.Cfg) (Interface, error) {
 	return store, nil
 }
 
-func Aggregate(ctx context.Context, s Interface) error {
+// Open - opens the storage of the type without the aggregation, the dsn is used only by the postgres storage
+func Open(ctx context.Context, typ, dsn string) (Interface, error) {
+	var store Interface
+
+	switch typ {
+	case "memory":
+		store = NewMemory(ctx)
+		log.Printf("[INFO] using memory storage")
+	case "sqlite":
+		if err := dataDir(); err != nil {
+			return nil, err
+		}
+		dbFilePath := fmt.Sprintf("%s/%s", dataPath, "jam.sqlite")
+
+		s, err := NewSQLite(ctx, dbFilePath)
+		if err != nil {
+			return nil, err
+		}
+		store = s
+
+		log.Printf("[INFO] using sqlite storage at %s", dbFilePath)
+	case "postgres":
+		s, err := NewPostgres(ctx, dsn)
+		if err != nil {
+			return nil, err
+		}
+		store = s
+
+		log.Printf("[INFO] using postgres storage")
+	default:
+		if err := dataDir(); err != nil {
+			return nil, err
+		}
+		dbFilePath := fmt.Sprintf("%s/%s", dataPath, "jam.db")
+
+		s, err := NewBolt(ctx, dbFilePath)
+		if err != nil {
+			return nil, err
+		}
+		store = s
+
+		log.Printf("[INFO] using bolt storage at %s", dbFilePath)
+	}
+
+	return store, nil
+}
+
+// dataPath - directory of the file storages
+const dataPath = "./data"
+
+// dataDir - creates the data directory if it does not exist
+func dataDir() error {
+	if _, err := os.Stat(dataPath); os.IsNotExist(err) {
+		if err := os.Mkdir(dataPath, 0755); err != nil {
+			return fmt.Errorf("failed to create data directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// Rollup resolutions, the aggregations without the resolution are daily
+const (
+	HourlyResolution = time.Hour
+	DailyResolution  = time.Hour * 24
+)
+
+// Aggregate - applies the retention to the history of all hosts. The raw checks older than the raw retention are rolled up by hour,
+// the ones older than the hourly retention are rolled up by day, and everything older than the daily retention is deleted.
+func Aggregate(ctx context.Context, s Interface, retention types.Retention) error {
 	log.Printf("[INFO] aggregating data")
 	start := time.Now()
 
+	if err := retention.Validate(); err != nil {
+		return fmt.Errorf("invalid retention: %w", err)
+	}
+
 	hosts, err := s.Hosts(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get keys: %w", err)
@@ -96,38 +176,45 @@ func Aggregate(ctx context.Context, s Interface) error {
 
 	now := time.Now()
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	for _, hostID := range hosts {
-		days := make(map[time.Time][]*types.HttpResponse)
+	rawFrom := today.AddDate(0, 0, 1-retention.Raw)
+	hourlyFrom := today.AddDate(0, 0, 1-retention.Hourly)
+	dailyFrom := today.AddDate(0, 0, 1-retention.Daily)
 
-		history, err := s.FindResponses(ctx, hostID)
+	for _, hostID := range hosts {
+		history, err := s.FindResponsesRange(ctx, hostID, time.Time{}, rawFrom.Add(-time.Nanosecond), 0)
 		if err != nil {
 			return fmt.Errorf("failed to get history for %s: %w", hostID, err)
 		}
 
+		expired := make([]time.Time, 0)
+		hours := make(map[time.Time][]*types.HttpResponse)
+		days := make(map[time.Time][]*types.HttpResponse)
 		for _, r := range history {
-			if r.Timestamp.After(today) || r.IsAggregated {
-				continue
-			}
-			y, m, d := r.Timestamp.Date()
-			day := time.Date(y, m, d, 0, 0, 0, 0, r.Timestamp.Location())
-			if _, ok := days[day]; !ok {
-				days[day] = make([]*types.HttpResponse, 0)
+			switch {
+			case r.Timestamp.Before(dailyFrom):
+				expired = append(expired, r.Timestamp)
+			case r.Timestamp.Before(hourlyFrom):
+				y, m, d := r.Timestamp.Date()
+				day := time.Date(y, m, d, 0, 0, 0, 0, r.Timestamp.Location())
+				days[day] = append(days[day], r)
+			default:
+				hour := time.Date(r.Timestamp.Year(), r.Timestamp.Month(), r.Timestamp.Day(), r.Timestamp.Hour(), 0, 0, 0, r.Timestamp.Location())
+				hours[hour] = append(hours[hour], r)
 			}
-			days[day] = append(days[day], r)
 		}
 
-		for day, responses := range days {
-			aggregation := AggregateDay(day, responses)
-
-			toDelete := make([]time.Time, 0)
-			for _, r := range responses {
-				toDelete = append(toDelete, r.Timestamp)
+		if len(expired) > 0 {
+			if err := s.DeleteResponse(ctx, hostID, expired); err != nil {
+				return err
 			}
-
-			if err := s.DeleteResponse(ctx, hostID, toDelete); err != nil {
+		}
+		for ts, responses := range hours {
+			if err := compact(ctx, s, hostID, ts, HourlyResolution, responses); err != nil {
 				return err
 			}
-			if err := s.AddResponse(ctx, hostID, aggregation); err != nil {
+		}
+		for ts, responses := range days {
+			if err := compact(ctx, s, hostID, ts, DailyResolution, responses); err != nil {
 				return err
 			}
 		}
@@ -136,27 +223,113 @@ func Aggregate(ctx context.Context, s Interface) error {
 	log.Printf("[INFO] aggregation took %v", time.Since(start))
 	return nil
 }
+
+// compact - replaces the responses with the aggregation, nothing is done if the period is already aggregated
+func compact(ctx context.Context, s Interface, hostID string, ts time.Time, resolution time.Duration, responses []*types.HttpResponse) error {
+	if len(responses) == 1 && Resolution(responses[0]) >= resolution {
+		return nil
+	}
+
+	// the rollup is saved first so the failed delete leaves the duplicates instead of the gap,
+	// the response at the start of the period is replaced by the rollup
+	if err := s.AddResponse(ctx, hostID, Rollup(ts, resolution, responses)); err != nil {
+		return err
+	}
+
+	toDelete := make([]time.Time, 0, len(responses))
+	for _, r := range responses {
+		if !r.Timestamp.Equal(ts) {
+			toDelete = append(toDelete, r.Timestamp)
+		}
+	}
+	if len(toDelete) == 0 {
+		return nil
+	}
+	return s.DeleteResponse(ctx, hostID, toDelete)
+}
+
+// AggregateDay - returns the daily aggregation of the responses
 func AggregateDay(ts time.Time, responses []*types.HttpResponse) *types.HttpResponse {
+	return Rollup(ts, DailyResolution, responses)
+}
+
+// Rollup - aggregates the responses of the period. The responses could be the raw checks or the aggregations of the shorter periods,
+// the aggregations are weighted by the number of the checks. The percentile of the aggregations is estimated from their percentiles.
+func Rollup(ts time.Time, resolution time.Duration, responses []*types.HttpResponse) *types.HttpResponse {
 	aggregation := &types.HttpResponse{
 		Timestamp:    ts,
 		IsAggregated: true,
+		Resolution:   resolution,
 		Uptime:       0,
-		Count:        len(responses),
+		Count:        0,
 		Time:         0,
 	}
 	if len(responses) == 0 {
 		return aggregation
 	}
 
+	type sample struct {
+		time   time.Duration
+		weight int
+	}
+	samples := make([]sample, 0, len(responses))
+	statuses := make(map[types.StatusType]int)
+	var sum time.Duration
 	for _, r := range responses {
-		if r.StatusType != types.DOWN {
-			aggregation.Uptime++
+		weight, minTime, maxTime, p95Time := 1, r.Time, r.Time, r.Time
+		if r.IsAggregated {
+			weight = max(r.Count, 1)
+			aggregation.Uptime += r.Uptime * float64(weight)
+			if r.Statuses != nil {
+				for status, n := range r.Statuses {
+					statuses[status] += n
+				}
+			} else if r.StatusType != "" {
+				statuses[r.StatusType] += weight
+			}
+			if r.MinTime != 0 || r.MaxTime != 0 {
+				minTime, maxTime, p95Time = r.MinTime, r.MaxTime, r.P95Time
+			}
+		} else {
+			if r.StatusType == types.FLAPPING {
+				if r.Status {
+					aggregation.Uptime++
+				}
+			} else if r.StatusType != types.DOWN && r.StatusType != types.UNREACHABLE {
+				aggregation.Uptime++
+			}
+			if r.StatusType != "" {
+				statuses[r.StatusType]++
+			}
+		}
+
+		if aggregation.Count == 0 || minTime < aggregation.MinTime {
+			aggregation.MinTime = minTime
 		}
-		aggregation.Time += r.Time
+		aggregation.MaxTime = max(aggregation.MaxTime, maxTime)
+		aggregation.Count += weight
+		sum += r.Time * time.Duration(weight)
+		samples = append(samples, sample{time: p95Time, weight: weight})
 	}
 
-	aggregation.Uptime = aggregation.Uptime / float64(len(responses))
-	aggregation.Time = aggregation.Time / time.Duration(len(responses))
+	aggregation.Uptime = aggregation.Uptime / float64(aggregation.Count)
+	aggregation.Time = sum / time.Duration(aggregation.Count)
+	if len(statuses) > 0 {
+		aggregation.Statuses = statuses
+	}
+
+	// nearest-rank percentile of the weighted samples
+	slices.SortFunc(samples, func(a, b sample) int {
+		return cmp.Compare(a.time, b.time)
+	})
+	rank := int(math.Ceil(0.95 * float64(aggregation.Count)))
+	for _, s := range samples {
+		rank -= s.weight
+		if rank <= 0 {
+			aggregation.P95Time = s.time
+			break
+		}
+	}
 
 	if aggregation.Uptime > 0.95 {
 		aggregation.StatusType = types.UP
@@ -169,6 +342,17 @@ func AggregateDay(ts time.Time, responses []*types.HttpResponse) *types.HttpResp
 	return aggregation
 }
 
+// Resolution - returns the period of the aggregation, zero for the raw check
+func Resolution(r *types.HttpResponse) time.Duration {
+	if !r.IsAggregated {
+		return 0
+	}
+	if r.Resolution == 0 {
+		return DailyResolution
+	}
+	return r.Resolution
+}
+
 func GenerateHistory(s Interface, start time.Time, id string) int {
 	ctx := context.Background()
 	now := time.Now()
@@ -202,6 +386,22 @@ func GenerateHistory(s Interface, start time.Time, id string) int {
 	return today
 }
 
+// minKeyTime and maxKeyTime - range of the time that can be kept as the unix nanoseconds in the bolt keys and the sqlite columns
+var (
+	minKeyTime = time.Unix(0, 0)
+	maxKeyTime = time.Unix(0, math.MaxInt64)
+)
+
+// unixKey - returns the unix nanoseconds of the time, the time out of the range (1970-2262) is clamped, so the range queries keep the order
+func unixKey(ts time.Time) int64 {
+	if ts.Before(minKeyTime) {
+		ts = minKeyTime
+	} else if ts.After(maxKeyTime) {
+		ts = maxKeyTime
+	}
+	return ts.UnixNano()
+}
+
 func randInt(min, max int) int {
 	return rand.IntN(max-min) + min
 }
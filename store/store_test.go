@@ -2,9 +2,11 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -30,6 +32,13 @@ func TestStore_AddResponse(t *testing.T) {
 
 			return b
 		},
+		"sqlite": func() Interface {
+			s, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "test.sqlite"))
+			require.NoError(t, err)
+			require.NotNil(t, s)
+
+			return s
+		},
 	}
 	now := time.Now()
 
@@ -43,6 +52,16 @@ func TestStore_AddResponse(t *testing.T) {
 			h, err := s.FindResponses(ctx, "test")
 			require.NoError(t, err)
 			require.Equal(t, count, len(h))
+
+			// the checks in the same second are kept
+			second := now.Truncate(time.Second)
+			require.NoError(t, s.AddResponse(ctx, "sub", &types.HttpResponse{Code: 1, Timestamp: second.Add(time.Millisecond * 100)}))
+			require.NoError(t, s.AddResponse(ctx, "sub", &types.HttpResponse{Code: 2, Timestamp: second.Add(time.Millisecond * 200)}))
+			h, err = s.FindResponses(ctx, "sub")
+			require.NoError(t, err)
+			require.Len(t, h, 2)
+			require.Equal(t, 1, h[0].Code)
+			require.Equal(t, 2, h[1].Code)
 		})
 	}
 }
@@ -64,6 +83,13 @@ func TestStore_ResponseHistory(t *testing.T) {
 
 			return b
 		},
+		"sqlite": func() Interface {
+			s, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "test.sqlite"))
+			require.NoError(t, err)
+			require.NotNil(t, s)
+
+			return s
+		},
 	}
 
 	for name, f := range list {
@@ -110,6 +136,13 @@ func TestStore_DeleteResponse(t *testing.T) {
 
 			return b
 		},
+		"sqlite": func() Interface {
+			s, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "test.sqlite"))
+			require.NoError(t, err)
+			require.NotNil(t, s)
+
+			return s
+		},
 	}
 	now := time.Now()
 
@@ -152,6 +185,114 @@ func TestStore_DeleteResponse(t *testing.T) {
 	}
 }
 
+// testStores - constructors of the storages checked by the tests, the integration tests add the external databases
+var testStores = map[string]func(t *testing.T, ctx context.Context) Interface{
+	"memory": func(t *testing.T, ctx context.Context) Interface {
+		return NewMemory(ctx)
+	},
+	"bolt": func(t *testing.T, ctx context.Context) Interface {
+		file, err := os.CreateTemp("", "test.db")
+		require.NoError(t, err)
+		defer os.RemoveAll(file.Name())
+
+		b, err := NewBolt(ctx, file.Name())
+		require.NoError(t, err)
+		require.NotNil(t, b)
+
+		return b
+	},
+	"sqlite": func(t *testing.T, ctx context.Context) Interface {
+		s, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "test.sqlite"))
+		require.NoError(t, err)
+		require.NotNil(t, s)
+
+		return s
+	},
+}
+
+// stores - returns the constructors of the empty storages
+func stores(t *testing.T, ctx context.Context) map[string]func() Interface {
+	list := make(map[string]func() Interface, len(testStores))
+	for name, f := range testStores {
+		list[name] = func() Interface {
+			return f(t, ctx)
+		}
+	}
+	return list
+}
+
+func TestStore_FindResponsesRange(t *testing.T) {
+	ctx := context.Background()
+	list := stores(t, ctx)
+	now := time.Now().Truncate(time.Second)
+
+	for name, f := range list {
+		t.Run(name, func(t *testing.T) {
+			s := f()
+			for i := 0; i < 10; i++ {
+				require.NoError(t, s.AddResponse(ctx, "test", &types.HttpResponse{Code: i, Timestamp: now.Add(time.Minute * time.Duration(i))}))
+			}
+			require.NoError(t, s.AddResponse(ctx, "other", &types.HttpResponse{Code: 100, Timestamp: now.Add(time.Minute * 3)}))
+
+			codes := func(list []*types.HttpResponse) []int {
+				res := []int{}
+				for _, r := range list {
+					res = append(res, r.Code)
+				}
+				return res
+			}
+
+			h, err := s.FindResponsesRange(ctx, "test", now.Add(time.Minute*2), now.Add(time.Minute*5), 0)
+			require.NoError(t, err)
+			require.Equal(t, []int{2, 3, 4, 5}, codes(h))
+
+			h, err = s.FindResponsesRange(ctx, "test", now.Add(time.Minute*2), now.Add(time.Minute*5), 2)
+			require.NoError(t, err)
+			require.Equal(t, []int{2, 3}, codes(h))
+
+			h, err = s.FindResponsesRange(ctx, "test", time.Time{}, now.Add(time.Minute*2), 0)
+			require.NoError(t, err)
+			require.Equal(t, []int{0, 1, 2}, codes(h))
+
+			h, err = s.FindResponsesRange(ctx, "test", now.Add(time.Minute*8), time.Time{}, 0)
+			require.NoError(t, err)
+			require.Equal(t, []int{8, 9}, codes(h))
+
+			h, err = s.FindResponsesRange(ctx, "test", now.Add(time.Hour), time.Time{}, 0)
+			require.NoError(t, err)
+			require.Empty(t, h)
+
+			// the time out of the range of the unix nanoseconds
+			h, err = s.FindResponsesRange(ctx, "test", time.Unix(-1, 0), time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+			require.NoError(t, err)
+			require.Len(t, h, 10)
+
+			h, err = s.FindResponsesRange(ctx, "test", time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC), now.Add(time.Minute), 0)
+			require.NoError(t, err)
+			require.Equal(t, []int{0, 1}, codes(h))
+
+			h, err = s.LastResponses(ctx, "test", 3)
+			require.NoError(t, err)
+			require.Equal(t, []int{7, 8, 9}, codes(h))
+
+			h, err = s.LastResponses(ctx, "test", 20)
+			require.NoError(t, err)
+			require.Len(t, h, 10)
+
+			for _, n := range []int{0, -1} {
+				h, err = s.LastResponses(ctx, "test", n)
+				require.NoError(t, err)
+				require.NotNil(t, h)
+				require.Empty(t, h)
+			}
+
+			h, err = s.LastResponses(ctx, "unknown", 3)
+			require.NoError(t, err)
+			require.Empty(t, h)
+		})
+	}
+}
+
 func TestStore_Hosts(t *testing.T) {
 	ctx := context.Background()
 	list := map[string]func() Interface{
@@ -169,6 +310,13 @@ func TestStore_Hosts(t *testing.T) {
 
 			return b
 		},
+		"sqlite": func() Interface {
+			s, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "test.sqlite"))
+			require.NoError(t, err)
+			require.NotNil(t, s)
+
+			return s
+		},
 	}
 	now := time.Now()
 
@@ -204,6 +352,13 @@ func TestStore_AddEvent(t *testing.T) {
 
 			return b
 		},
+		"sqlite": func() Interface {
+			s, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "test.sqlite"))
+			require.NoError(t, err)
+			require.NotNil(t, s)
+
+			return s
+		},
 	}
 	now := time.Now()
 
@@ -232,6 +387,147 @@ func TestStore_AddEvent(t *testing.T) {
 	}
 }
 
+func TestStore_AddIncidentEvent(t *testing.T) {
+	ctx := context.Background()
+	list := map[string]func() Interface{
+		"memory": func() Interface {
+			return NewMemory(ctx)
+		},
+		"bolt": func() Interface {
+			file, err := os.CreateTemp("", "test.db")
+			require.NoError(t, err)
+			defer os.RemoveAll(file.Name())
+
+			b, err := NewBolt(ctx, file.Name())
+			require.NoError(t, err)
+			require.NotNil(t, b)
+
+			return b
+		},
+		"sqlite": func() Interface {
+			s, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "test.sqlite"))
+			require.NoError(t, err)
+			require.NotNil(t, s)
+
+			return s
+		},
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for name, f := range list {
+		t.Run(name, func(t *testing.T) {
+			s := f()
+			incident := &types.Incident{
+				StartTS:  now,
+				Timeline: []types.IncidentEvent{{Type: types.StatusEvent, Status: types.DOWN, TS: now}},
+			}
+			require.NoError(t, s.AddIncident(ctx, "test", incident))
+			require.NoError(t, s.AddIncident(ctx, "test", &types.Incident{StartTS: now}))
+
+			require.NoError(t, s.AddIncidentEvent(ctx, "test", incident.ID, &types.IncidentEvent{Type: types.AckEvent, Author: "john", TS: now}))
+			require.NoError(t, s.AddIncidentEvent(ctx, "test", incident.ID, &types.IncidentEvent{Type: types.NoteEvent, Message: "investigating", Public: true, TS: now}))
+			require.ErrorIs(t, s.AddIncidentEvent(ctx, "test", 100, &types.IncidentEvent{Type: types.NoteEvent}), types.ErrIncidentNotFound)
+			require.ErrorIs(t, s.AddIncidentEvent(ctx, "unknown", incident.ID, &types.IncidentEvent{Type: types.NoteEvent}), types.ErrIncidentNotFound)
+
+			e, err := s.FindIncidents(ctx, "test", 0, 0)
+			require.NoError(t, err)
+			require.Len(t, e, 2)
+			require.Empty(t, e[0].Timeline)
+			require.Len(t, e[1].Timeline, 3)
+			require.Equal(t, types.AckEvent, e[1].Timeline[1].Type)
+			require.Equal(t, "john", e[1].Acknowledgement().Author)
+			require.Equal(t, []types.IncidentEvent{{Type: types.NoteEvent, Message: "investigating", Public: true, TS: now}}, e[1].PublicUpdates())
+			require.Len(t, incident.Timeline, 1)
+
+			// the incident is acknowledged only once and the closed incident cannot be acknowledged
+			require.ErrorIs(t, s.AddIncidentEvent(ctx, "test", incident.ID, &types.IncidentEvent{Type: types.AckEvent, Author: "bob", TS: now}), types.ErrIncidentAcknowledged)
+			require.NoError(t, s.EndIncident(ctx, "test", e[0].ID, now))
+			require.ErrorIs(t, s.AddIncidentEvent(ctx, "test", e[0].ID, &types.IncidentEvent{Type: types.AckEvent, Author: "bob", TS: now}), types.ErrIncidentClosed)
+			require.NoError(t, s.AddIncidentEvent(ctx, "test", e[0].ID, &types.IncidentEvent{Type: types.NoteEvent, Message: "resolved", TS: now}))
+
+			found, err := s.FindIncident(ctx, "test", incident.ID)
+			require.NoError(t, err)
+			require.Equal(t, incident.ID, found.ID)
+			require.Len(t, found.Timeline, 3)
+			require.Equal(t, "john", found.Acknowledgement().Author)
+			_, err = s.FindIncident(ctx, "test", 100)
+			require.ErrorIs(t, err, types.ErrIncidentNotFound)
+			_, err = s.FindIncident(ctx, "unknown", incident.ID)
+			require.ErrorIs(t, err, types.ErrIncidentNotFound)
+		})
+	}
+}
+
+func TestStore_Announcements(t *testing.T) {
+	ctx := context.Background()
+	list := map[string]func() Interface{
+		"memory": func() Interface {
+			return NewMemory(ctx)
+		},
+		"bolt": func() Interface {
+			file, err := os.CreateTemp("", "test.db")
+			require.NoError(t, err)
+			defer os.RemoveAll(file.Name())
+
+			b, err := NewBolt(ctx, file.Name())
+			require.NoError(t, err)
+			require.NotNil(t, b)
+
+			return b
+		},
+		"sqlite": func() Interface {
+			s, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "test.sqlite"))
+			require.NoError(t, err)
+			require.NotNil(t, s)
+
+			return s
+		},
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for name, f := range list {
+		t.Run(name, func(t *testing.T) {
+			s := f()
+			for i := 0; i < 5; i++ {
+				a := &types.Announcement{
+					Title:    fmt.Sprintf("announcement %d", i),
+					Severity: types.DEGRADED,
+					Groups:   []string{"payments"},
+					StartTS:  now,
+				}
+				require.NoError(t, s.AddAnnouncement(ctx, a))
+				require.Equal(t, i+1, a.ID)
+			}
+
+			require.NoError(t, s.AddAnnouncementUpdate(ctx, 2, &types.IncidentEvent{Type: types.NoteEvent, Message: "fixed", Public: true, TS: now}))
+			require.NoError(t, s.EndAnnouncement(ctx, 2, now))
+			require.NoError(t, s.DeleteAnnouncement(ctx, 4))
+			require.ErrorIs(t, s.DeleteAnnouncement(ctx, 4), types.ErrAnnouncementNotFound)
+			require.ErrorIs(t, s.EndAnnouncement(ctx, 10, now), types.ErrAnnouncementNotFound)
+			require.ErrorIs(t, s.AddAnnouncementUpdate(ctx, 10, &types.IncidentEvent{}), types.ErrAnnouncementNotFound)
+
+			res, err := s.FindAnnouncements(ctx, 0, 0)
+			require.NoError(t, err)
+			require.Len(t, res, 4)
+			require.Equal(t, []int{5, 3, 2, 1}, []int{res[0].ID, res[1].ID, res[2].ID, res[3].ID})
+			require.Equal(t, "announcement 1", res[2].Title)
+			require.Equal(t, []string{"payments"}, res[2].Groups)
+			require.Equal(t, now.Unix(), res[2].EndTS.Unix())
+			require.Len(t, res[2].Updates, 1)
+			require.Nil(t, res[1].EndTS)
+
+			res, err = s.FindAnnouncements(ctx, 1, 2)
+			require.NoError(t, err)
+			require.Len(t, res, 2)
+			require.Equal(t, 3, res[0].ID)
+
+			hosts, err := s.Hosts(ctx)
+			require.NoError(t, err)
+			require.Empty(t, hosts)
+		})
+	}
+}
+
 func TestStore_FindEvents(t *testing.T) {
 	ctx := context.Background()
 	list := map[string]func() Interface{
@@ -249,6 +545,13 @@ func TestStore_FindEvents(t *testing.T) {
 
 			return b
 		},
+		"sqlite": func() Interface {
+			s, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "test.sqlite"))
+			require.NoError(t, err)
+			require.NotNil(t, s)
+
+			return s
+		},
 	}
 	now := time.Now()
 
@@ -309,14 +612,14 @@ func TestStore_FindEvents(t *testing.T) {
 func TestStore_aggregation(t *testing.T) {
 	t.Run("one day history", func(t *testing.T) {
 		ctx := context.Background()
-		s, err := New(ctx, "memory", nil)
+		s, err := New(ctx, "memory", "", types.Retention{})
 		require.NoError(t, err)
 		require.NotNil(t, s)
 
 		start := time.Now().Add(-24 * time.Hour).Truncate(time.Hour * 24)
 		today := GenerateHistory(s, start, "test")
 
-		require.NoError(t, Aggregate(ctx, s))
+		require.NoError(t, Aggregate(ctx, s, types.Retention{Raw: 1, Hourly: 1, Daily: 2000}))
 
 		history, err := s.FindResponses(ctx, "test")
 		require.NoError(t, err)
@@ -324,7 +627,7 @@ func TestStore_aggregation(t *testing.T) {
 	})
 	t.Run("random days history back", func(t *testing.T) {
 		ctx := context.Background()
-		s, err := New(ctx, "memory", nil)
+		s, err := New(ctx, "memory", "", types.Retention{})
 		require.NoError(t, err)
 		require.NotNil(t, s)
 
@@ -332,7 +635,7 @@ func TestStore_aggregation(t *testing.T) {
 		start := time.Now().Add(-24 * time.Hour * time.Duration(days)).Truncate(time.Hour * 24)
 		today := GenerateHistory(s, start, "test")
 
-		require.NoError(t, Aggregate(ctx, s))
+		require.NoError(t, Aggregate(ctx, s, types.Retention{Raw: 1, Hourly: 1, Daily: 2000}))
 
 		history, err := s.FindResponses(ctx, "test")
 		require.NoError(t, err)
@@ -340,7 +643,7 @@ func TestStore_aggregation(t *testing.T) {
 	})
 	t.Run("uptime, status and responseTime type per day", func(t *testing.T) {
 		ctx := context.Background()
-		s, err := New(ctx, "memory", nil)
+		s, err := New(ctx, "memory", "", types.Retention{})
 		require.NoError(t, err)
 		require.NotNil(t, s)
 
@@ -382,7 +685,7 @@ func TestStore_aggregation(t *testing.T) {
 			statistics[ts] = stat
 		}
 
-		require.NoError(t, Aggregate(ctx, s))
+		require.NoError(t, Aggregate(ctx, s, types.Retention{Raw: 1, Hourly: 1, Daily: 2000}))
 
 		history, err = s.FindResponses(ctx, "test")
 		require.NoError(t, err)
@@ -406,3 +709,144 @@ func TestStore_aggregation(t *testing.T) {
 		}
 	})
 }
+
+func TestStore_retention(t *testing.T) {
+	ctx := context.Background()
+	list := stores(t, ctx)
+	now := time.Now()
+	y, m, d := now.Date()
+	at := func(days, hour, minute int) time.Time {
+		return time.Date(y, m, d-days, hour, minute, 0, 0, now.Location())
+	}
+	retention := types.Retention{Raw: 2, Hourly: 5, Daily: 10}
+
+	for name, f := range list {
+		t.Run(name, func(t *testing.T) {
+			s := f()
+			responses := []*types.HttpResponse{
+				{Timestamp: at(1, 10, 0), StatusType: types.UP, Time: time.Millisecond}, // raw
+				{Timestamp: at(1, 11, 0), StatusType: types.UP, Time: time.Millisecond},
+				{Timestamp: at(3, 10, 0), StatusType: types.UP, Time: time.Millisecond * 10}, // hourly
+				{Timestamp: at(3, 10, 10), StatusType: types.DOWN, Time: time.Millisecond * 20},
+				{Timestamp: at(3, 10, 20), StatusType: types.UP, Time: time.Millisecond * 30},
+				{Timestamp: at(3, 11, 0), StatusType: types.UP, Time: time.Millisecond * 40},
+				{Timestamp: at(7, 1, 0), StatusType: types.UP, Time: time.Millisecond * 10}, // daily
+				{Timestamp: at(7, 2, 0), StatusType: types.UP, Time: time.Millisecond * 20},
+				Rollup(at(7, 3, 0), HourlyResolution, []*types.HttpResponse{
+					{Timestamp: at(7, 3, 0), StatusType: types.DOWN, Time: time.Millisecond * 30},
+					{Timestamp: at(7, 3, 30), StatusType: types.UP, Time: time.Millisecond * 30},
+				}),
+				{Timestamp: at(20, 1, 0), StatusType: types.UP}, // expired
+			}
+			for _, r := range responses {
+				require.NoError(t, s.AddResponse(ctx, "test", r))
+			}
+
+			for i := 0; i < 2; i++ {
+				require.NoError(t, Aggregate(ctx, s, retention))
+
+				history, err := s.FindResponses(ctx, "test")
+				require.NoError(t, err)
+				require.Len(t, history, 5)
+
+				day := history[0]
+				require.True(t, day.Timestamp.Equal(at(7, 0, 0)))
+				require.Equal(t, DailyResolution, day.Resolution)
+				require.Equal(t, 4, day.Count)
+				require.Equal(t, 0.75, day.Uptime)
+				require.Equal(t, time.Millisecond*10, day.MinTime)
+				require.Equal(t, time.Millisecond*30, day.MaxTime)
+				require.Equal(t, map[types.StatusType]int{types.UP: 3, types.DOWN: 1}, day.Statuses)
+
+				hour := history[1]
+				require.True(t, hour.Timestamp.Equal(at(3, 10, 0)))
+				require.Equal(t, HourlyResolution, hour.Resolution)
+				require.Equal(t, 3, hour.Count)
+				require.Equal(t, time.Millisecond*20, hour.Time)
+				require.Equal(t, time.Millisecond*30, hour.P95Time)
+				require.Equal(t, types.DEGRADED, hour.StatusType)
+
+				require.True(t, history[2].Timestamp.Equal(at(3, 11, 0)))
+				require.Equal(t, 1, history[2].Count)
+				require.False(t, history[3].IsAggregated)
+				require.False(t, history[4].IsAggregated)
+			}
+		})
+	}
+}
+
+// failingDelete - the storage that fails to delete the responses
+type failingDelete struct {
+	Interface
+}
+
+func (f failingDelete) DeleteResponse(ctx context.Context, hostID string, keys []time.Time) error {
+	return errors.New("delete failed")
+}
+
+func TestCompact(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory(ctx)
+	hour := time.Now().Add(-time.Hour).Truncate(time.Hour)
+	responses := []*types.HttpResponse{
+		{Timestamp: hour, StatusType: types.UP},
+		{Timestamp: hour.Add(time.Minute), StatusType: types.DOWN},
+	}
+	for _, r := range responses {
+		require.NoError(t, s.AddResponse(ctx, "test", r))
+	}
+
+	require.Error(t, compact(ctx, failingDelete{s}, "test", hour, HourlyResolution, responses))
+	history, err := s.FindResponses(ctx, "test")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, HourlyResolution, history[0].Resolution)
+	require.True(t, history[1].Timestamp.Equal(hour.Add(time.Minute)))
+
+	require.NoError(t, compact(ctx, s, "test", hour, HourlyResolution, responses))
+	history, err = s.FindResponses(ctx, "test")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, 2, history[0].Count)
+}
+
+func TestRollup(t *testing.T) {
+	ts := time.Now().Truncate(time.Hour)
+	responses := []*types.HttpResponse{}
+	for i := 1; i <= 20; i++ {
+		status := types.UP
+		if i == 20 {
+			status = types.DOWN
+		}
+		responses = append(responses, &types.HttpResponse{Timestamp: ts.Add(time.Minute * time.Duration(i)), StatusType: status, Time: time.Millisecond * time.Duration(i)})
+	}
+
+	r := Rollup(ts, HourlyResolution, responses)
+	require.True(t, r.IsAggregated)
+	require.Equal(t, 20, r.Count)
+	require.Equal(t, 0.95, r.Uptime)
+	require.Equal(t, types.DEGRADED, r.StatusType)
+	require.Equal(t, time.Microsecond*10500, r.Time)
+	require.Equal(t, time.Millisecond, r.MinTime)
+	require.Equal(t, time.Millisecond*20, r.MaxTime)
+	require.Equal(t, time.Millisecond*19, r.P95Time)
+	require.Equal(t, map[types.StatusType]int{types.UP: 19, types.DOWN: 1}, r.Statuses)
+
+	t.Run("aggregations", func(t *testing.T) {
+		legacy := &types.HttpResponse{Timestamp: ts.Add(time.Hour), IsAggregated: true, StatusType: types.UP, Uptime: 1, Count: 20, Time: time.Millisecond * 100}
+		day := Rollup(ts, DailyResolution, []*types.HttpResponse{r, legacy})
+		require.Equal(t, 40, day.Count)
+		require.Equal(t, 0.975, day.Uptime)
+		require.Equal(t, types.UP, day.StatusType)
+		require.Equal(t, time.Microsecond*55250, day.Time)
+		require.Equal(t, time.Millisecond, day.MinTime)
+		require.Equal(t, time.Millisecond*100, day.MaxTime)
+		require.Equal(t, time.Millisecond*100, day.P95Time)
+		require.Equal(t, map[types.StatusType]int{types.UP: 39, types.DOWN: 1}, day.Statuses)
+	})
+	t.Run("empty", func(t *testing.T) {
+		r := Rollup(ts, HourlyResolution, nil)
+		require.True(t, r.IsAggregated)
+		require.Zero(t, r.Count)
+	})
+}
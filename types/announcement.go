@@ -0,0 +1,99 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// Announcement - manually declared incident or announcement shown on the status page.
+// Announcements from the config are read-only, the ones created via api are kept in the store.
+type Announcement struct {
+	ID       int        `json:"id" yaml:"-"`
+	Title    string     `json:"title" yaml:"title"`
+	Severity StatusType `json:"severity" yaml:"severity"` // up for the informational announcement, degraded, down or maintenance, default degraded
+	Message  string     `json:"message,omitempty" yaml:"message,omitempty"`
+
+	Hosts  []string `json:"hosts,omitempty" yaml:"hosts,omitempty"`   // ids, names or urls of the hosts, the whole page if hosts and groups are empty
+	Groups []string `json:"groups,omitempty" yaml:"groups,omitempty"` // groups of the hosts
+
+	StartTS time.Time       `json:"startTS" yaml:"start"`
+	EndTS   *time.Time      `json:"endTS,omitempty" yaml:"end,omitempty"`
+	Updates []IncidentEvent `json:"updates,omitempty" yaml:"updates,omitempty"`
+
+	Config bool `json:"config,omitempty" yaml:"-"` // defined in the config, cannot be changed via api
+
+	Affected []string `json:"-" yaml:"-"` // names of the affected groups and hosts
+	Start    string   `json:"-" yaml:"-"`
+	End      string   `json:"-" yaml:"-"`
+}
+
+var (
+	ErrAnnouncementNotFound = errors.New("announcement not found")
+	ErrAnnouncementResolved = errors.New("announcement is resolved")
+	ErrAnnouncementInvalid  = errors.New("invalid announcement")
+	ErrAnnouncementReadOnly = errors.New("announcement is defined in the config")
+)
+
+// Validate - sets the default severity and checks the required fields
+func (a *Announcement) Validate() error {
+	if a.Title == "" {
+		return errors.New("announcement must have title")
+	}
+	if a.Severity == "" {
+		a.Severity = DEGRADED
+	}
+	switch a.Severity {
+	case UP, DEGRADED, DOWN, MAINTENANCE:
+	default:
+		return fmt.Errorf("announcement severity must be up, degraded, down or maintenance, got %s", a.Severity)
+	}
+	if a.StartTS.IsZero() {
+		return errors.New("announcement must have start")
+	}
+	if a.EndTS != nil && a.EndTS.Before(a.StartTS) {
+		return errors.New("announcement end must be after start")
+	}
+	for i := range a.Updates {
+		a.Updates[i].Type = NoteEvent
+		a.Updates[i].Public = true
+		if a.Updates[i].Message == "" {
+			return errors.New("announcement update must have message")
+		}
+	}
+	return nil
+}
+
+// configID - returns the id of the announcement from the config. It's built from the title and the start, so it's the same after the restart,
+// and it's negative, so it does not clash with the ids of the stored announcements
+func (a *Announcement) configID() int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(a.Title))
+	_, _ = h.Write([]byte(a.StartTS.UTC().Format(time.RFC3339Nano)))
+	return -int(h.Sum32()>>1) - 1
+}
+
+// Active - returns true if the announcement started and is not resolved at t
+func (a *Announcement) Active(t time.Time) bool {
+	return !a.StartTS.After(t) && (a.EndTS == nil || a.EndTS.After(t))
+}
+
+// Global - returns true if the announcement is for the whole page
+func (a *Announcement) Global() bool {
+	return len(a.Hosts) == 0 && len(a.Groups) == 0
+}
+
+// Applies - returns true if the announcement is for the whole page, the host or the group of the host
+func (a *Announcement) Applies(h *Host) bool {
+	return a.Global() || h.Matches(a.Hosts, a.Groups)
+}
+
+// PublicUpdates - returns the updates of the announcement, the newest first
+func (a *Announcement) PublicUpdates() []IncidentEvent {
+	list := make([]IncidentEvent, 0, len(a.Updates))
+	for i := len(a.Updates) - 1; i >= 0; i-- {
+		list = append(list, a.Updates[i])
+	}
+	return list
+}
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnouncement_Validate(t *testing.T) {
+	a := &Announcement{}
+	require.EqualError(t, a.Validate(), "announcement must have title")
+
+	a.Title = "Network issues"
+	require.EqualError(t, a.Validate(), "announcement must have start")
+
+	a.StartTS = time.Now()
+	end := a.StartTS.Add(-time.Minute)
+	a.EndTS = &end
+	require.EqualError(t, a.Validate(), "announcement end must be after start")
+
+	a.EndTS = nil
+	a.Updates = []IncidentEvent{{}}
+	require.EqualError(t, a.Validate(), "announcement update must have message")
+
+	a.Updates[0].Message = "provider is investigating"
+	require.NoError(t, a.Validate())
+	require.Equal(t, DEGRADED, a.Severity)
+	require.Equal(t, NoteEvent, a.Updates[0].Type)
+	require.True(t, a.Updates[0].Public)
+}
+
+func TestAnnouncement_Active(t *testing.T) {
+	now := time.Now()
+	end := now.Add(time.Hour)
+	a := &Announcement{StartTS: now, EndTS: &end}
+
+	require.False(t, a.Active(now.Add(-time.Minute)))
+	require.True(t, a.Active(now))
+	require.True(t, a.Active(now.Add(30*time.Minute)))
+	require.False(t, a.Active(end))
+
+	a.EndTS = nil
+	require.True(t, a.Active(now.Add(time.Hour*24*365)))
+}
+
+func TestAnnouncement_Applies(t *testing.T) {
+	name, group := "API", "backend"
+	api := &Host{ID: "api", URL: "http://api", Name: &name, Group: &group}
+	web := &Host{ID: "web", URL: "http://web"}
+
+	a := &Announcement{}
+	require.True(t, a.Global())
+	require.True(t, a.Applies(api))
+	require.True(t, a.Applies(web))
+
+	a.Hosts = []string{"API"}
+	require.False(t, a.Global())
+	require.True(t, a.Applies(api))
+	require.False(t, a.Applies(web))
+
+	a.Hosts, a.Groups = nil, []string{"backend"}
+	require.True(t, a.Applies(api))
+	require.False(t, a.Applies(web))
+}
@@ -0,0 +1,236 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Assertion - additional check of the response. Only one of JSONPath, Regex, NotContains, Header or ResponseTime must be defined.
+type Assertion struct {
+	JSONPath     string         `json:"jsonPath,omitempty" yaml:"jsonPath,omitempty"`         // value in the json body, e.g. $.data.items[0].status
+	Regex        string         `json:"regex,omitempty" yaml:"regex,omitempty"`               // body must match the regex
+	NotContains  string         `json:"notContains,omitempty" yaml:"notContains,omitempty"`   // body must not contain the keyword
+	Header       string         `json:"header,omitempty" yaml:"header,omitempty"`             // response header
+	ResponseTime *time.Duration `json:"responseTime,omitempty" yaml:"responseTime,omitempty"` // max response time
+
+	Operator string `json:"operator,omitempty" yaml:"operator,omitempty"` // jsonPath and header only: eq (default), ne, gt, gte, lt, lte, contains, exists
+	Value    string `json:"value,omitempty" yaml:"value,omitempty"`
+
+	NonCritical bool `json:"nonCritical,omitempty" yaml:"nonCritical,omitempty"` // failed assertion marks the host as degraded instead of down
+
+	regex *regexp.Regexp // compiled regex, set by the validation
+}
+
+// Degradation - rules when the host that passes the conditions is marked as degraded
+type Degradation struct {
+	ResponseTime *time.Duration `json:"responseTime,omitempty" yaml:"responseTime,omitempty"` // response time above which the check is degraded
+	Threshold    int            `json:"threshold,omitempty" yaml:"threshold,omitempty"`       // number of consecutive degraded checks to mark the host as degraded
+}
+
+var operators = []string{"", "eq", "ne", "gt", "gte", "lt", "lte", "contains", "exists"}
+
+// Validate - checks if the assertion is defined correctly
+func (a *Assertion) Validate() error {
+	kinds := 0
+	for _, ok := range []bool{a.JSONPath != "", a.Regex != "", a.NotContains != "", a.Header != "", a.ResponseTime != nil} {
+		if ok {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return errors.New("assertion must have exactly one of jsonPath, regex, notContains, header or responseTime")
+	}
+
+	a.regex = nil
+	if a.Regex != "" {
+		re, err := regexp.Compile(a.Regex)
+		if err != nil {
+			return fmt.Errorf("assertion regex: %w", err)
+		}
+		a.regex = re
+	}
+
+	for _, op := range operators {
+		if a.Operator == op {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown assertion operator `%s`", a.Operator)
+}
+
+// Check - returns the readable reason if the response does not pass the assertion, otherwise empty string
+func (a *Assertion) Check(resp *HttpResponse) string {
+	switch {
+	case a.JSONPath != "":
+		var data interface{}
+		if err := json.Unmarshal(resp.Bytes, &data); err != nil {
+			return fmt.Sprintf("json %s: body is not a valid json", a.JSONPath)
+		}
+		value, ok := jsonPath(data, a.JSONPath)
+		if !compare(a.Operator, value, ok, a.Value) {
+			if !ok {
+				return fmt.Sprintf("json %s: not found", a.JSONPath)
+			}
+			return fmt.Sprintf("json %s: expected %s %q, got %q", a.JSONPath, operator(a.Operator), a.Value, value)
+		}
+	case a.Regex != "":
+		re := a.regex
+		if re == nil {
+			re, _ = regexp.Compile(a.Regex)
+		}
+		if re == nil || !re.Match(resp.Bytes) {
+			return fmt.Sprintf("body does not match regex %q", a.Regex)
+		}
+	case a.NotContains != "":
+		if bytes.Contains(resp.Bytes, []byte(a.NotContains)) {
+			return fmt.Sprintf("body contains %q", a.NotContains)
+		}
+	case a.Header != "":
+		values, ok := resp.Headers[http.CanonicalHeaderKey(a.Header)]
+		value := strings.Join(values, ", ")
+		if !compare(a.Operator, value, ok, a.Value) {
+			if !ok {
+				return fmt.Sprintf("header %s: not found", a.Header)
+			}
+			return fmt.Sprintf("header %s: expected %s %q, got %q", a.Header, operator(a.Operator), a.Value, value)
+		}
+	case a.ResponseTime != nil:
+		if resp.Time > *a.ResponseTime {
+			return fmt.Sprintf("response time %s exceeds %s", resp.Time.Truncate(time.Millisecond), *a.ResponseTime)
+		}
+	}
+
+	return ""
+}
+
+// Assert - checks the response against the host assertions and degradation rules.
+// Returns the list of failed critical assertions and the list of reasons why the response is degraded.
+func (h *Host) Assert(resp *HttpResponse) ([]string, []string) {
+	var failures, warnings []string
+
+	if h.Conditions != nil {
+		for i, a := range h.Conditions.Assertions {
+			reason := h.Conditions.Assertions[i].Check(resp)
+			if reason == "" {
+				continue
+			}
+			if a.NonCritical {
+				warnings = append(warnings, reason)
+			} else {
+				failures = append(failures, reason)
+			}
+		}
+	}
+
+	if h.Degraded != nil && h.Degraded.ResponseTime != nil && resp.Time > *h.Degraded.ResponseTime {
+		warnings = append(warnings, fmt.Sprintf("response time %s exceeds %s", resp.Time.Truncate(time.Millisecond), *h.Degraded.ResponseTime))
+	}
+
+	return failures, warnings
+}
+
+// jsonPath - returns the value from the decoded json by path in the dot notation: $.key.list[0].key
+func jsonPath(data interface{}, path string) (string, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	current := data
+
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			continue
+		}
+
+		name, indexes := part, []int{}
+		if idx := strings.Index(part, "["); idx != -1 {
+			name = part[:idx]
+			for _, raw := range strings.Split(strings.TrimSuffix(part[idx+1:], "]"), "][") {
+				i, err := strconv.Atoi(raw)
+				if err != nil {
+					return "", false
+				}
+				indexes = append(indexes, i)
+			}
+		}
+
+		if name != "" {
+			obj, ok := current.(map[string]interface{})
+			if !ok {
+				return "", false
+			}
+			if current, ok = obj[name]; !ok {
+				return "", false
+			}
+		}
+		for _, i := range indexes {
+			list, ok := current.([]interface{})
+			if !ok || i < 0 || i >= len(list) {
+				return "", false
+			}
+			current = list[i]
+		}
+	}
+
+	switch v := current.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case nil:
+		return "null", true
+	default:
+		b, _ := json.Marshal(v)
+		return string(b), true
+	}
+}
+
+// compare - compares the actual value with the expected one using the operator
+func compare(op, actual string, found bool, expected string) bool {
+	if op == "exists" {
+		return found
+	}
+	if !found {
+		return false
+	}
+
+	switch op {
+	case "", "eq":
+		return actual == expected
+	case "ne":
+		return actual != expected
+	case "contains":
+		return strings.Contains(actual, expected)
+	}
+
+	a, errA := strconv.ParseFloat(actual, 64)
+	e, errE := strconv.ParseFloat(expected, 64)
+	if errA != nil || errE != nil {
+		return false
+	}
+	switch op {
+	case "gt":
+		return a > e
+	case "gte":
+		return a >= e
+	case "lt":
+		return a < e
+	case "lte":
+		return a <= e
+	}
+
+	return false
+}
+
+func operator(op string) string {
+	if op == "" {
+		return "eq"
+	}
+	return op
+}
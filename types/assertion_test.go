@@ -0,0 +1,108 @@
+package types
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssertion_Validate(t *testing.T) {
+	d := time.Second
+
+	require.NoError(t, (&Assertion{JSONPath: "$.status", Value: "ok"}).Validate())
+	require.NoError(t, (&Assertion{ResponseTime: &d}).Validate())
+	require.Error(t, (&Assertion{}).Validate())
+	require.Error(t, (&Assertion{JSONPath: "$.status", Regex: "ok"}).Validate())
+	require.Error(t, (&Assertion{Regex: "("}).Validate())
+
+	a := &Assertion{Regex: `ok$`}
+	require.NoError(t, a.Validate())
+	require.NotNil(t, a.regex)
+	require.Equal(t, "", a.Check(&HttpResponse{Bytes: []byte("status: ok")}))
+	a.Regex = "("
+	require.Error(t, a.Validate())
+	require.Nil(t, a.regex)
+	require.Error(t, (&Assertion{Header: "Content-Type", Operator: "like"}).Validate())
+}
+
+func TestAssertion_Check(t *testing.T) {
+	resp := &HttpResponse{
+		Time:    time.Millisecond * 300,
+		Bytes:   []byte(`{"status": "ok", "queue": {"size": 42}, "items": [{"id": 1}, {"id": 2}], "version": "1.2.3"}`),
+		Headers: http.Header{"Content-Type": []string{"application/json"}},
+	}
+	limit, slow := time.Second, time.Millisecond*100
+
+	list := []struct {
+		name      string
+		assertion Assertion
+		reason    string
+	}{
+		{"json eq", Assertion{JSONPath: "$.status", Value: "ok"}, ""},
+		{"json eq fail", Assertion{JSONPath: "$.status", Value: "fail"}, `json $.status: expected eq "fail", got "ok"`},
+		{"json nested lt", Assertion{JSONPath: "$.queue.size", Operator: "lt", Value: "100"}, ""},
+		{"json nested gt fail", Assertion{JSONPath: "queue.size", Operator: "gt", Value: "100"}, `json queue.size: expected gt "100", got "42"`},
+		{"json index", Assertion{JSONPath: "$.items[1].id", Value: "2"}, ""},
+		{"json not found", Assertion{JSONPath: "$.items[5].id", Value: "2"}, "json $.items[5].id: not found"},
+		{"json exists", Assertion{JSONPath: "$.version", Operator: "exists"}, ""},
+		{"regex", Assertion{Regex: `"version": "\d+\.\d+\.\d+"`}, ""},
+		{"regex fail", Assertion{Regex: `"version": "2\.`}, `body does not match regex "\"version\": \"2\\."`},
+		{"not contains", Assertion{NotContains: "maintenance"}, ""},
+		{"not contains fail", Assertion{NotContains: "queue"}, `body contains "queue"`},
+		{"header", Assertion{Header: "content-type", Operator: "contains", Value: "json"}, ""},
+		{"header not found", Assertion{Header: "X-Version", Operator: "exists"}, "header X-Version: not found"},
+		{"response time", Assertion{ResponseTime: &limit}, ""},
+		{"response time fail", Assertion{ResponseTime: &slow}, "response time 300ms exceeds 100ms"},
+	}
+
+	for _, tc := range list {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.reason, tc.assertion.Check(resp))
+		})
+	}
+
+	t.Run("invalid json", func(t *testing.T) {
+		a := Assertion{JSONPath: "$.status", Value: "ok"}
+		require.Equal(t, "json $.status: body is not a valid json", a.Check(&HttpResponse{Bytes: []byte("ok")}))
+	})
+}
+
+func TestHost_Assert(t *testing.T) {
+	h := Host{
+		Conditions: &Success{
+			Code: []int{200},
+			Assertions: []Assertion{
+				{JSONPath: "$.status", Value: "ok"},
+				{NotContains: "error"},
+			},
+		},
+	}
+
+	failures, _ := h.Assert(&HttpResponse{Bytes: []byte(`{"status": "ok"}`)})
+	require.Empty(t, failures)
+	failures, _ = h.Assert(&HttpResponse{Bytes: []byte(`{"status": "error"}`)})
+	require.Len(t, failures, 2)
+
+	t.Run("non-critical and slow", func(t *testing.T) {
+		limit := time.Millisecond * 100
+		h := Host{
+			Conditions: &Success{
+				Assertions: []Assertion{
+					{JSONPath: "$.status", Value: "ok"},
+					{JSONPath: "$.queue", Operator: "lt", Value: "100", NonCritical: true},
+				},
+			},
+			Degraded: &Degradation{ResponseTime: &limit},
+		}
+
+		failures, warnings := h.Assert(&HttpResponse{Time: time.Millisecond * 50, Bytes: []byte(`{"status": "ok", "queue": 10}`)})
+		require.Empty(t, failures)
+		require.Empty(t, warnings)
+
+		failures, warnings = h.Assert(&HttpResponse{Time: time.Millisecond * 150, Bytes: []byte(`{"status": "ok", "queue": 500}`)})
+		require.Empty(t, failures)
+		require.Equal(t, []string{`json $.queue: expected lt "100", got "500"`, "response time 150ms exceeds 100ms"}, warnings)
+	})
+}
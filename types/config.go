@@ -8,6 +8,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -33,10 +34,38 @@ type SMTP struct {
 	To       []string `json:"to" yaml:"to"`
 }
 
+type Webhook struct {
+	URL     string            `json:"url" yaml:"url"`
+	Method  string            `json:"method" yaml:"method"`   // POST by default
+	Headers map[string]string `json:"headers" yaml:"headers"` // supports env: and file: values
+	Body    string            `json:"body" yaml:"body"`       // text/template of the request body, json of the event by default
+	Secret  string            `json:"secret" yaml:"secret"`   // key of the HMAC-SHA256 signature sent in the X-JAM-Signature header, supports env: and file: values
+}
+
+type Discord struct {
+	WebhookURL string `json:"webhookURL" yaml:"webhookURL"`
+}
+
+type Teams struct {
+	WebhookURL string `json:"webhookURL" yaml:"webhookURL"`
+}
+
+// PagerDutyURL - default url of the PagerDuty Events API v2
+const PagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+type PagerDuty struct {
+	RoutingKey string `json:"routingKey" yaml:"routingKey"` // integration key of the Events API v2 service, supports env: and file: values
+	URL        string `json:"url" yaml:"url"`               // events api url, PagerDutyURL by default
+}
+
 type Notifications struct {
-	Slack    *Slack    `json:"slack" yaml:"slack"`
-	Telegram *Telegram `json:"telegram" yaml:"telegram"`
-	SMTP     *SMTP     `json:"smtp" yaml:"smtp"`
+	Slack     *Slack     `json:"slack" yaml:"slack"`
+	Telegram  *Telegram  `json:"telegram" yaml:"telegram"`
+	SMTP      *SMTP      `json:"smtp" yaml:"smtp"`
+	Discord   *Discord   `json:"discord" yaml:"discord"`
+	Teams     *Teams     `json:"teams" yaml:"teams"`
+	PagerDuty *PagerDuty `json:"pagerDuty" yaml:"pagerDuty"`
+	Webhook   *Webhook   `json:"webhook" yaml:"webhook"`
 
 	InitializationMessage *bool `json:"initializationMessage" yaml:"initializationMessage"`
 	ShutdownMessage       bool  `json:"shutdownMessage" yaml:"shutdownMessage"`
@@ -45,6 +74,38 @@ type Notifications struct {
 type UI struct {
 	Title   string `json:"title" yaml:"title"`     // web page title
 	HideURL bool   `json:"hideURL" yaml:"hideURL"` // allows to hide URL of the host in the UI
+	URL     string `json:"url" yaml:"url"`         // public url of the status page, used for the links in the notifications
+}
+
+// Retention - how long the history is kept in days, today is counted as the first day.
+// The older checks are rolled up into the hourly aggregations, then into the daily ones, and deleted after all.
+type Retention struct {
+	Raw    int `json:"raw,omitempty" yaml:"raw,omitempty"`       // days of the raw checks, default 2
+	Hourly int `json:"hourly,omitempty" yaml:"hourly,omitempty"` // days of the hourly aggregations including the raw days, default 30
+	Daily  int `json:"daily,omitempty" yaml:"daily,omitempty"`   // days of the daily aggregations including the hourly days, default 365
+}
+
+// Validate - sets the default values and checks the order of the tiers
+func (r *Retention) Validate() error {
+	if r.Raw == 0 {
+		r.Raw = 2
+	}
+	if r.Hourly == 0 {
+		r.Hourly = max(30, r.Raw)
+	}
+	if r.Daily == 0 {
+		r.Daily = max(365, r.Hourly)
+	}
+	if r.Raw < 0 || r.Hourly < 0 || r.Daily < 0 {
+		return errors.New("retention cannot be negative")
+	}
+	if r.Hourly < r.Raw {
+		return fmt.Errorf("hourly retention (%d) must not be shorter than raw (%d)", r.Hourly, r.Raw)
+	}
+	if r.Daily < r.Hourly {
+		return fmt.Errorf("daily retention (%d) must not be shorter than hourly (%d)", r.Daily, r.Hourly)
+	}
+	return nil
 }
 
 type Cfg struct {
@@ -60,6 +121,16 @@ type Cfg struct {
 	Conditions *Success          `json:"success" yaml:"success,omitempty"`
 	Headers    map[string]string `json:"headers" yaml:"headers,omitempty"`
 
+	SSLExpiry []int `json:"sslExpiry,omitempty" yaml:"sslExpiry,omitempty"`
+
+	Reminder *time.Duration `json:"reminder,omitempty" yaml:"reminder,omitempty"`
+	Flapping *Flapping      `json:"flapping,omitempty" yaml:"flapping,omitempty"`
+
+	Maintenance   []*Maintenance  `json:"maintenance,omitempty" yaml:"maintenance,omitempty"`
+	Announcements []*Announcement `json:"announcements,omitempty" yaml:"announcements,omitempty"`
+
+	Retention Retention `json:"retention" yaml:"retention,omitempty"`
+
 	UI            UI            `json:"ui" yaml:"ui"`
 	Notifications Notifications `json:"notifications" yaml:"notifications,omitempty"`
 	FileHosts     []*Host       `json:"hosts" yaml:"hosts"`
@@ -179,6 +250,70 @@ func (c *Cfg) Validate() error {
 		c.Notifications = *c.Alerts
 	}
 
+	if d := c.Notifications.Discord; d != nil {
+		if d.WebhookURL == "" {
+			return errors.New("discord cannot be without webhookURL")
+		}
+		url, err := secret(d.WebhookURL)
+		if err != nil {
+			return fmt.Errorf("discord webhookURL: %w", err)
+		}
+		d.WebhookURL = url
+	}
+	if t := c.Notifications.Teams; t != nil {
+		if t.WebhookURL == "" {
+			return errors.New("teams cannot be without webhookURL")
+		}
+		url, err := secret(t.WebhookURL)
+		if err != nil {
+			return fmt.Errorf("teams webhookURL: %w", err)
+		}
+		t.WebhookURL = url
+	}
+	if pd := c.Notifications.PagerDuty; pd != nil {
+		if pd.RoutingKey == "" {
+			return errors.New("pagerDuty cannot be without routingKey")
+		}
+		key, err := secret(pd.RoutingKey)
+		if err != nil {
+			return fmt.Errorf("pagerDuty routingKey: %w", err)
+		}
+		pd.RoutingKey = key
+		if pd.URL == "" {
+			pd.URL = PagerDutyURL
+		}
+	}
+	if w := c.Notifications.Webhook; w != nil {
+		if w.URL == "" {
+			return errors.New("webhook cannot be without url")
+		}
+		if w.Method == "" {
+			w.Method = "POST"
+		}
+		key, err := secret(w.Secret)
+		if err != nil {
+			return fmt.Errorf("webhook secret: %w", err)
+		}
+		w.Secret = key
+		for k, v := range w.Headers {
+			value, err := secret(v)
+			if err != nil {
+				return fmt.Errorf("webhook header %s: %w", k, err)
+			}
+			w.Headers[k] = value
+		}
+	}
+
+	for i, m := range c.Maintenance {
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("maintenance %d: %w", i, err)
+		}
+	}
+
+	if err := c.Retention.Validate(); err != nil {
+		return fmt.Errorf("retention: %w", err)
+	}
+
 	for i, host := range c.FileHosts {
 		if host.URL == "" {
 			return errors.New("host cannot be without url")
@@ -212,18 +347,88 @@ func (c *Cfg) Validate() error {
 			host.Conditions.Code = c.Conditions.Code
 		}
 
+		for i := range host.Conditions.Assertions {
+			if err := host.Conditions.Assertions[i].Validate(); err != nil {
+				return fmt.Errorf("host %s: %w", host.URL, err)
+			}
+		}
+
+		if host.Degraded != nil && host.Degraded.Threshold == 0 {
+			host.Degraded.Threshold = 1
+		}
+
 		if host.SuccessThreshold == 0 {
 			host.SuccessThreshold = c.SuccessThreshold
 		}
 		if host.FailureThreshold == 0 {
 			host.FailureThreshold = c.FailureThreshold
 		}
+		if host.SSLExpiry == nil {
+			host.SSLExpiry = c.SSLExpiry
+		}
+		if host.Reminder == nil {
+			host.Reminder = c.Reminder
+		}
+		if host.Reminder != nil && *host.Reminder < 0 {
+			return fmt.Errorf("host %s: reminder cannot be negative", host.URL)
+		}
+		for name, d := range host.Reminders {
+			if d < 0 {
+				return fmt.Errorf("host %s: reminder for %s cannot be negative", host.URL, name)
+			}
+		}
+		for _, e := range host.Escalation {
+			if e.After <= 0 || len(e.Alerts) == 0 {
+				return fmt.Errorf("host %s: escalation must have positive after and alerts", host.URL)
+			}
+		}
+		if host.Flapping == nil {
+			host.Flapping = c.Flapping
+		}
+		if host.Flapping != nil {
+			if err := host.Flapping.Validate(); err != nil {
+				return fmt.Errorf("host %s: %w", host.URL, err)
+			}
+		}
+		if host.Type == PushType {
+			if host.Token == "" {
+				return fmt.Errorf("push host %s cannot be without token", host.URL)
+			}
+			for _, h := range c.FileHosts[:i] {
+				if h.Type == PushType && h.Token == host.Token {
+					return fmt.Errorf("push host %s has the same token as %s", host.URL, h.URL)
+				}
+			}
+			if host.GracePeriod == nil {
+				grace := time.Minute
+				host.GracePeriod = &grace
+			}
+		}
 
+		if host.Headers == nil && len(c.Headers) > 0 {
+			host.Headers = make(map[string]string, len(c.Headers))
+		}
 		for key, value := range c.Headers {
 			if _, ok := host.Headers[key]; !ok {
 				host.Headers[key] = value
 			}
 		}
+		if err := host.resolveSecrets(); err != nil {
+			return fmt.Errorf("host %s: %w", host.URL, err)
+		}
+
+		host.MaintenanceWindows = nil
+		for _, m := range host.Maintenance {
+			if err := m.Validate(); err != nil {
+				return fmt.Errorf("host %s: %w", host.URL, err)
+			}
+			host.MaintenanceWindows = append(host.MaintenanceWindows, m)
+		}
+		for _, m := range c.Maintenance {
+			if m.applies(host) {
+				host.MaintenanceWindows = append(host.MaintenanceWindows, m)
+			}
+		}
 
 		if idx == -1 {
 			c.addHost(host)
@@ -259,6 +464,128 @@ func (c *Cfg) Validate() error {
 		return errors.New("no hosts for monitoring")
 	}
 
+	if err := c.dependencies(); err != nil {
+		return err
+	}
+
+	ids := make(map[int]int, len(c.Announcements))
+	for i, a := range c.Announcements {
+		if err := a.Validate(); err != nil {
+			return fmt.Errorf("announcement %d: %w", i, err)
+		}
+		a.Config = true
+		a.ID = a.configID()
+		if j, ok := ids[a.ID]; ok {
+			return fmt.Errorf("announcement %d: duplicate of announcement %d, the title or the start must be different", i, j)
+		}
+		ids[a.ID] = i
+		for _, ref := range a.Hosts {
+			if !slices.ContainsFunc(c.Hosts, func(h *Host) bool { return h.Matches([]string{ref}, nil) }) {
+				return fmt.Errorf("announcement %d: unknown host %s", i, ref)
+			}
+		}
+		for _, group := range a.Groups {
+			if !slices.ContainsFunc(c.Hosts, func(h *Host) bool { return h.Matches(nil, []string{group}) }) {
+				return fmt.Errorf("announcement %d: unknown group %s", i, group)
+			}
+		}
+	}
+
+	return nil
+}
+
+// dependencies - resolves the dependsOn of the hosts, rejects cycles and collects the dependents of each host
+func (c *Cfg) dependencies() error {
+	hosts := make(map[string]*Host, len(c.Hosts))
+	for _, h := range c.Hosts {
+		hosts[h.ID] = h
+	}
+
+	for _, h := range c.Hosts {
+		h.Parents = nil
+		for _, ref := range h.DependsOn {
+			var parent *Host
+			for _, p := range c.Hosts {
+				if p.ID == ref || p.URL == ref || (p.Name != nil && *p.Name == ref) {
+					parent = p
+					break
+				}
+			}
+			if parent == nil {
+				return fmt.Errorf("host %s depends on unknown host %s", h.URL, ref)
+			}
+			if parent.ID == h.ID {
+				return fmt.Errorf("host %s depends on itself", h.URL)
+			}
+			h.Parents = append(h.Parents, parent.ID)
+		}
+	}
+
+	// depth-first search, the host that is visited again in the same path is a cycle
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(c.Hosts))
+	var path []string
+	var visit func(h *Host) error
+	visit = func(h *Host) error {
+		switch state[h.ID] {
+		case visiting:
+			cycle := []string{}
+			for i, id := range path {
+				if id == h.ID {
+					for _, id := range append(path[i:], h.ID) {
+						cycle = append(cycle, hosts[id].URL)
+					}
+					break
+				}
+			}
+			return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+		case done:
+			return nil
+		}
+
+		state[h.ID] = visiting
+		path = append(path, h.ID)
+		for _, id := range h.Parents {
+			if err := visit(hosts[id]); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[h.ID] = done
+		return nil
+	}
+	for _, h := range c.Hosts {
+		if err := visit(h); err != nil {
+			return err
+		}
+	}
+
+	for _, h := range c.Hosts {
+		h.Dependents = nil
+		seen := map[string]bool{h.ID: true}
+		queue := []string{h.ID}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			for _, child := range c.Hosts {
+				if seen[child.ID] {
+					continue
+				}
+				for _, parent := range child.Parents {
+					if parent == id {
+						seen[child.ID] = true
+						h.Dependents = append(h.Dependents, child)
+						queue = append(queue, child.ID)
+						break
+					}
+				}
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -295,8 +622,31 @@ func (c *Cfg) updateHost(at int, host *Host) {
 
 	c.Hosts[at].Conditions = host.Conditions
 	c.Hosts[at].Headers = host.Headers
+	c.Hosts[at].Degraded = host.Degraded
+
+	c.Hosts[at].Body = host.Body
+	c.Hosts[at].BodyFile = host.BodyFile
+	c.Hosts[at].Form = host.Form
+	c.Hosts[at].Auth = host.Auth
+
+	c.Hosts[at].Payload = host.Payload
+	c.Hosts[at].Banner = host.Banner
+
+	c.Hosts[at].SSLExpiry = host.SSLExpiry
+
+	c.Hosts[at].Token = host.Token
+	c.Hosts[at].GracePeriod = host.GracePeriod
 
 	c.Hosts[at].Alerts = host.Alerts
+	c.Hosts[at].Reminder = host.Reminder
+	c.Hosts[at].Reminders = host.Reminders
+	c.Hosts[at].Escalation = host.Escalation
+	c.Hosts[at].Flapping = host.Flapping
+
+	c.Hosts[at].DependsOn = host.DependsOn
+
+	c.Hosts[at].Maintenance = host.Maintenance
+	c.Hosts[at].MaintenanceWindows = host.MaintenanceWindows
 
 	c.Hosts[at].Hidden = host.Hidden
 }
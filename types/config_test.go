@@ -281,6 +281,231 @@ func TestConfig_Validate(t *testing.T) {
 		})
 	})
 
+	t.Run("push host", func(t *testing.T) {
+		t.Run("without token", func(t *testing.T) {
+			cfg := &Cfg{
+				FileHosts: []*Host{
+					{URL: "push://backup"},
+				},
+			}
+			require.EqualError(t, cfg.Validate(), "push host push://backup cannot be without token")
+		})
+		t.Run("duplicated token", func(t *testing.T) {
+			cfg := &Cfg{
+				FileHosts: []*Host{
+					{URL: "push://backup", Token: "secret"},
+					{URL: "push://cleanup", Token: "secret"},
+				},
+			}
+			require.EqualError(t, cfg.Validate(), "push host push://cleanup has the same token as push://backup")
+		})
+		t.Run("default grace period", func(t *testing.T) {
+			cfg := &Cfg{
+				FileHosts: []*Host{
+					{URL: "push://backup", Token: "secret"},
+				},
+			}
+			require.NoError(t, cfg.Validate())
+			require.Equal(t, PushType, cfg.Hosts[0].Type)
+			require.Equal(t, time.Minute, *cfg.Hosts[0].GracePeriod)
+		})
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		t.Run("without url", func(t *testing.T) {
+			cfg := &Cfg{
+				Notifications: Notifications{Webhook: &Webhook{}},
+				FileHosts:     []*Host{{URL: "test"}},
+			}
+			require.EqualError(t, cfg.Validate(), "webhook cannot be without url")
+		})
+		t.Run("defaults and secrets", func(t *testing.T) {
+			t.Setenv("JAM_WEBHOOK_SECRET", "secret")
+			t.Setenv("JAM_WEBHOOK_TOKEN", "token")
+			cfg := &Cfg{
+				Notifications: Notifications{Webhook: &Webhook{
+					URL:     "https://example.com/hook",
+					Secret:  "env:JAM_WEBHOOK_SECRET",
+					Headers: map[string]string{"Authorization": "env:JAM_WEBHOOK_TOKEN"},
+				}},
+				FileHosts: []*Host{{URL: "test"}},
+			}
+			require.NoError(t, cfg.Validate())
+			require.Equal(t, "POST", cfg.Notifications.Webhook.Method)
+			require.Equal(t, "secret", cfg.Notifications.Webhook.Secret)
+			require.Equal(t, "token", cfg.Notifications.Webhook.Headers["Authorization"])
+		})
+		t.Run("missing secret", func(t *testing.T) {
+			cfg := &Cfg{
+				Notifications: Notifications{Webhook: &Webhook{
+					URL:    "https://example.com/hook",
+					Secret: "env:JAM_WEBHOOK_UNKNOWN",
+				}},
+				FileHosts: []*Host{{URL: "test"}},
+			}
+			require.Error(t, cfg.Validate())
+		})
+	})
+
+	t.Run("pagerDuty", func(t *testing.T) {
+		cfg := &Cfg{
+			Notifications: Notifications{PagerDuty: &PagerDuty{}},
+			FileHosts:     []*Host{{URL: "test"}},
+		}
+		require.EqualError(t, cfg.Validate(), "pagerDuty cannot be without routingKey")
+
+		cfg.Notifications.PagerDuty.RoutingKey = "key"
+		require.NoError(t, cfg.Validate())
+		require.Equal(t, "https://events.pagerduty.com/v2/enqueue", cfg.Notifications.PagerDuty.URL)
+	})
+
+	t.Run("maintenance", func(t *testing.T) {
+		d := time.Hour
+		group, name := "db", "api"
+		all := &Maintenance{Cron: "0 3 * * sun", Duration: &d}
+		groups := &Maintenance{Cron: "0 4 * * sun", Duration: &d, Groups: []string{"db"}}
+		hosts := &Maintenance{Cron: "0 5 * * sun", Duration: &d, Hosts: []string{"api"}}
+		own := &Maintenance{Cron: "0 6 * * sun", Duration: &d}
+		cfg := &Cfg{
+			Maintenance: []*Maintenance{all, groups, hosts},
+			FileHosts: []*Host{
+				{URL: "api", Name: &name, Maintenance: []*Maintenance{own}},
+				{URL: "db-1", Group: &group},
+				{URL: "web"},
+			},
+		}
+		require.NoError(t, cfg.Validate())
+		require.Equal(t, []*Maintenance{own, all, hosts}, cfg.Hosts[0].MaintenanceWindows)
+		require.Equal(t, []*Maintenance{all, groups}, cfg.Hosts[1].MaintenanceWindows)
+		require.Equal(t, []*Maintenance{all}, cfg.Hosts[2].MaintenanceWindows)
+
+		require.NoError(t, cfg.Validate())
+		require.Len(t, cfg.Hosts[0].MaintenanceWindows, 3)
+
+		cfg.Maintenance = append(cfg.Maintenance, &Maintenance{Cron: "0 3 * * sun"})
+		require.ErrorContains(t, cfg.Validate(), "maintenance 3")
+	})
+
+	t.Run("dependencies", func(t *testing.T) {
+		router, db := "router", "db"
+		cfg := &Cfg{
+			FileHosts: []*Host{
+				{URL: "http://router", Name: &router},
+				{URL: "http://db", Name: &db, DependsOn: []string{"router"}},
+				{URL: "http://api", DependsOn: []string{"db", "http://router"}},
+				{URL: "http://web"},
+			},
+		}
+		require.NoError(t, cfg.Validate())
+		require.Empty(t, cfg.Hosts[0].Parents)
+		require.Equal(t, []string{cfg.Hosts[0].ID}, cfg.Hosts[1].Parents)
+		require.Equal(t, []string{cfg.Hosts[1].ID, cfg.Hosts[0].ID}, cfg.Hosts[2].Parents)
+		require.Equal(t, []*Host{cfg.Hosts[1], cfg.Hosts[2]}, cfg.Hosts[0].Dependents)
+		require.Equal(t, []*Host{cfg.Hosts[2]}, cfg.Hosts[1].Dependents)
+		require.Empty(t, cfg.Hosts[2].Dependents)
+		require.Empty(t, cfg.Hosts[3].Dependents)
+
+		cfg.FileHosts[3].DependsOn = []string{"unknown"}
+		require.EqualError(t, cfg.Validate(), "host http://web depends on unknown host unknown")
+
+		cfg.FileHosts[3].DependsOn = []string{"http://web"}
+		require.EqualError(t, cfg.Validate(), "host http://web depends on itself")
+
+		cfg.FileHosts[3].DependsOn = nil
+		cfg.FileHosts[0].DependsOn = []string{"http://api"}
+		require.ErrorContains(t, cfg.Validate(), "dependency cycle: http://router -> http://api -> http://db -> http://router")
+	})
+
+	t.Run("reminders", func(t *testing.T) {
+		reminder, negative := 30*time.Minute, -time.Minute
+		cfg := &Cfg{
+			Reminder: &reminder,
+			FileHosts: []*Host{
+				{URL: "http://host"},
+				{URL: "http://escalation", Escalation: []Escalation{{After: time.Minute}}},
+			},
+		}
+		require.EqualError(t, cfg.Validate(), "host http://escalation: escalation must have positive after and alerts")
+
+		cfg.FileHosts[1].Escalation[0].Alerts = []string{"pagerduty"}
+		require.NoError(t, cfg.Validate())
+		require.Equal(t, reminder, *cfg.Hosts[0].Reminder)
+		require.Equal(t, reminder, *cfg.Hosts[1].Reminder)
+
+		cfg.FileHosts[0].Reminder = &negative
+		require.EqualError(t, cfg.Validate(), "host http://host: reminder cannot be negative")
+	})
+
+	t.Run("flapping", func(t *testing.T) {
+		cfg := &Cfg{
+			Flapping: &Flapping{},
+			FileHosts: []*Host{
+				{URL: "http://host"},
+				{URL: "http://custom", Flapping: &Flapping{Window: 10, High: 40, Low: 60}},
+			},
+		}
+		require.EqualError(t, cfg.Validate(), "host http://custom: flapping thresholds must be 0 <= low < high <= 100")
+
+		cfg.FileHosts[1].Flapping.Low = 20
+		require.NoError(t, cfg.Validate())
+		require.Equal(t, &Flapping{Window: 21, High: 50, Low: 25}, cfg.Hosts[0].Flapping)
+		require.Equal(t, &Flapping{Window: 10, High: 40, Low: 20}, cfg.Hosts[1].Flapping)
+	})
+
+	t.Run("retention", func(t *testing.T) {
+		hosts := []*Host{{URL: "http://host"}}
+		cfg := &Cfg{FileHosts: hosts}
+		require.NoError(t, cfg.Validate())
+		require.Equal(t, Retention{Raw: 2, Hourly: 30, Daily: 365}, cfg.Retention)
+
+		cfg = &Cfg{FileHosts: hosts, Retention: Retention{Raw: 60}}
+		require.NoError(t, cfg.Validate())
+		require.Equal(t, Retention{Raw: 60, Hourly: 60, Daily: 365}, cfg.Retention)
+
+		cfg = &Cfg{Retention: Retention{Raw: 7, Hourly: 3}}
+		require.EqualError(t, cfg.Validate(), "retention: hourly retention (3) must not be shorter than raw (7)")
+
+		cfg = &Cfg{Retention: Retention{Hourly: 90, Daily: 30}}
+		require.EqualError(t, cfg.Validate(), "retention: daily retention (30) must not be shorter than hourly (90)")
+	})
+
+	t.Run("announcements", func(t *testing.T) {
+		group := "databases"
+		cfg := &Cfg{
+			FileHosts: []*Host{
+				{URL: "http://host"},
+				{URL: "http://db", Group: &group},
+			},
+			Announcements: []*Announcement{
+				{Title: "Planned migration", StartTS: time.Now(), Groups: []string{group}},
+				{Title: "Degraded performance", StartTS: time.Now(), Hosts: []string{"http://unknown"}},
+			},
+		}
+		require.EqualError(t, cfg.Validate(), "announcement 1: unknown host http://unknown")
+
+		cfg.Announcements[1].Hosts = []string{"http://host"}
+		cfg.Announcements[1].Groups = []string{"unknown"}
+		require.EqualError(t, cfg.Validate(), "announcement 1: unknown group unknown")
+
+		cfg.Announcements[1].Groups = nil
+		require.NoError(t, cfg.Validate())
+		require.Equal(t, DEGRADED, cfg.Announcements[0].Severity)
+		require.True(t, cfg.Announcements[0].Config)
+		id := cfg.Announcements[0].ID
+		require.Negative(t, id)
+		require.NotEqual(t, id, cfg.Announcements[1].ID)
+		require.NoError(t, cfg.Validate())
+		require.Equal(t, id, cfg.Announcements[0].ID, "the id is the same after the reload")
+		require.False(t, cfg.Announcements[0].Applies(cfg.Hosts[0]))
+		require.True(t, cfg.Announcements[0].Applies(cfg.Hosts[1]))
+
+		cfg.Announcements[1].Severity = FLAPPING
+		require.EqualError(t, cfg.Validate(), "announcement 1: announcement severity must be up, degraded, down or maintenance, got flapping")
+
+		cfg.Announcements[1] = &Announcement{Title: cfg.Announcements[0].Title, StartTS: cfg.Announcements[0].StartTS}
+		require.EqualError(t, cfg.Validate(), "announcement 1: duplicate of announcement 0, the title or the start must be different")
+	})
+
 	t.Run("add host", func(t *testing.T) {
 		cfg := &Cfg{
 			FileHosts: []*Host{
@@ -7,14 +7,38 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// Auth - credentials for the http request. Values can be loaded from env (env:NAME) or file (file:/path).
+type Auth struct {
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Token    string `json:"token,omitempty" yaml:"token,omitempty"` // bearer token
+}
+
 type Success struct {
-	Code []int   `json:"code" yaml:"code"`
-	Body *string `json:"body" yaml:"body"`
+	Code       []int       `json:"code" yaml:"code"`
+	Body       *string     `json:"body" yaml:"body"`
+	Records    []string    `json:"records,omitempty" yaml:"records,omitempty"` // dns only: expected answer set
+	Assertions []Assertion `json:"assertions,omitempty" yaml:"assertions,omitempty"`
+}
+
+// Escalation - notification channels that are added when the incident lasts longer than after
+type Escalation struct {
+	After  time.Duration `json:"after" yaml:"after"`
+	Alerts []string      `json:"alerts" yaml:"alerts"`
+}
+
+// Flapping - detection of the host that changes the state too often.
+// The percent of the state changes in the window is weighted like in Nagios, the recent changes weigh more.
+type Flapping struct {
+	Window int     `json:"window,omitempty" yaml:"window,omitempty"` // number of the last checks, default 21
+	High   float64 `json:"high,omitempty" yaml:"high,omitempty"`     // percent of the state changes when the host starts flapping, default 50
+	Low    float64 `json:"low,omitempty" yaml:"low,omitempty"`       // percent of the state changes when the host stops flapping, default 25
 }
 
 // Host - host structure
@@ -38,8 +62,34 @@ type Host struct {
 
 	Conditions *Success          `json:"conditions,omitempty" yaml:"conditions,omitempty"`
 	Headers    map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Degraded   *Degradation      `json:"degraded,omitempty" yaml:"degraded,omitempty"`
+
+	Body     *string           `json:"body,omitempty" yaml:"body,omitempty"`         // request body
+	BodyFile *string           `json:"bodyFile,omitempty" yaml:"bodyFile,omitempty"` // path to the file with request body
+	Form     map[string]string `json:"form,omitempty" yaml:"form,omitempty"`         // form values, sent url encoded
+	Auth     *Auth             `json:"auth,omitempty" yaml:"auth,omitempty"`
+
+	Payload *string `json:"payload,omitempty" yaml:"payload,omitempty"` // tcp only: data sent after the connection is established
+	Banner  *string `json:"banner,omitempty" yaml:"banner,omitempty"`   // tcp only: expected prefix of the data received from the host
+
+	SSLExpiry []int `json:"sslExpiry,omitempty" yaml:"sslExpiry,omitempty"` // days before the certificate expiry when the notification must be sent
+
+	Token       string         `json:"token,omitempty" yaml:"token,omitempty"`             // push only: secret used in the check-in url
+	GracePeriod *time.Duration `json:"gracePeriod,omitempty" yaml:"gracePeriod,omitempty"` // push only: time after the interval before the check-in is missed
+
+	Alerts     []string                 `json:"alerts,omitempty" yaml:"alerts,omitempty"`
+	Reminder   *time.Duration           `json:"reminder,omitempty" yaml:"reminder,omitempty"`     // repeat the notification while the host is down or degraded
+	Reminders  map[string]time.Duration `json:"reminders,omitempty" yaml:"reminders,omitempty"`   // reminder interval per notification channel, overrides reminder, 0 disables
+	Escalation []Escalation             `json:"escalation,omitempty" yaml:"escalation,omitempty"` // additional channels notified when the incident lasts longer
+
+	Flapping *Flapping `json:"flapping,omitempty" yaml:"flapping,omitempty"`
 
-	Alerts []string `json:"alerts,omitempty" yaml:"alerts,omitempty"`
+	DependsOn  []string `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"` // ids, names or urls of the hosts this host depends on
+	Parents    []string `json:"-" yaml:"-"`                                     // resolved ids of the hosts from dependsOn
+	Dependents []*Host  `json:"-" yaml:"-"`                                     // hosts that directly or transitively depend on this host
+
+	Maintenance        []*Maintenance `json:"maintenance,omitempty" yaml:"maintenance,omitempty"`
+	MaintenanceWindows []*Maintenance `json:"-" yaml:"-"` // host and top level windows that apply to the host
 
 	Hidden bool `json:"hidden" yaml:"hidden"` // acceptable only if group is defined
 
@@ -90,6 +140,23 @@ func (h *Host) String() string {
 	return fmt.Sprintf("%s (%s)", *h.Name, h.URL)
 }
 
+// Matches - returns true if the host id, name or url is in the hosts or the group of the host is in the groups
+func (h *Host) Matches(hosts, groups []string) bool {
+	for _, v := range hosts {
+		if v == h.ID || v == h.URL || (h.Name != nil && v == *h.Name) {
+			return true
+		}
+	}
+	if h.Group != nil {
+		for _, v := range groups {
+			if v == *h.Group {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // GetType - return a host type based on url
 func (h *Host) GetType() HostType {
 	if h.Type != "" {
@@ -99,6 +166,18 @@ func (h *Host) GetType() HostType {
 	if strings.HasPrefix(h.URL, "mongodb://") {
 		return MongoType
 	}
+	if strings.HasPrefix(h.URL, "tcp://") {
+		return TCPType
+	}
+	if strings.HasPrefix(h.URL, "dns://") {
+		return DNSType
+	}
+	if strings.HasPrefix(h.URL, "tls://") {
+		return TLSType
+	}
+	if strings.HasPrefix(h.URL, "push://") {
+		return PushType
+	}
 	if !strings.Contains(h.URL, "http://") && !strings.Contains(h.URL, "https://") && isIPv4(h.URL) {
 		return ICMPType
 	}
@@ -123,6 +202,67 @@ func (h *Host) SecureURL() string {
 	return url
 }
 
+// resolveSecrets - replaces the auth and header values that reference env or file with the actual value
+func (h *Host) resolveSecrets() error {
+	if h.Auth != nil {
+		for _, v := range []*string{&h.Auth.Username, &h.Auth.Password, &h.Auth.Token} {
+			value, err := secret(*v)
+			if err != nil {
+				return err
+			}
+			*v = value
+		}
+	}
+	for key, v := range h.Headers {
+		value, err := secret(v)
+		if err != nil {
+			return err
+		}
+		h.Headers[key] = value
+	}
+	return nil
+}
+
+// Validate - sets the default values and checks the thresholds
+func (f *Flapping) Validate() error {
+	if f.Window == 0 {
+		f.Window = 21
+	}
+	if f.High == 0 {
+		f.High = 50
+	}
+	if f.Low == 0 {
+		f.Low = 25
+	}
+	if f.Window < 3 {
+		return errors.New("flapping window must be at least 3 checks")
+	}
+	if f.Low < 0 || f.Low >= f.High || f.High > 100 {
+		return errors.New("flapping thresholds must be 0 <= low < high <= 100")
+	}
+	return nil
+}
+
+// secret - returns the value of env variable for env:NAME, the content of the file for file:/path or the value itself
+func secret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "env:"):
+		name := strings.TrimPrefix(value, "env:")
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("env variable %s is not set", name)
+		}
+		return v, nil
+	case strings.HasPrefix(value, "file:"):
+		b, err := os.ReadFile(strings.TrimPrefix(value, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("read secret: %w", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	return value, nil
+}
+
 func isIPv4(host string) bool {
 	parts := strings.Split(host, ".")
 
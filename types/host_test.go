@@ -3,6 +3,7 @@ package types
 import (
 	"crypto/md5"
 	"encoding/base64"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -52,6 +53,44 @@ func TestHost_String(t *testing.T) {
 	require.Equal(t, "url", url.String())
 }
 
+func TestHost_GetType(t *testing.T) {
+	require.Equal(t, HttpType, (&Host{URL: "https://example.com"}).GetType())
+	require.Equal(t, MongoType, (&Host{URL: "mongodb://localhost:27017"}).GetType())
+	require.Equal(t, ICMPType, (&Host{URL: "10.0.0.1"}).GetType())
+	require.Equal(t, TCPType, (&Host{URL: "tcp://localhost:5432"}).GetType())
+	require.Equal(t, DNSType, (&Host{URL: "dns://1.1.1.1/example.com?type=MX"}).GetType())
+	require.Equal(t, TLSType, (&Host{URL: "tls://smtp.example.com:465"}).GetType())
+	require.Equal(t, TCPType, (&Host{URL: "localhost:5432", Type: TCPType}).GetType())
+}
+
+func TestHost_resolveSecrets(t *testing.T) {
+	t.Setenv("JAM_TEST_PASSWORD", "password")
+	file, err := os.CreateTemp("", "token")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("token\n")
+	require.NoError(t, err)
+
+	h := Host{
+		Auth: &Auth{
+			Username: "user",
+			Password: "env:JAM_TEST_PASSWORD",
+			Token:    "file:" + file.Name(),
+		},
+		Headers: map[string]string{
+			"X-Api-Key": "env:JAM_TEST_PASSWORD",
+		},
+	}
+	require.NoError(t, h.resolveSecrets())
+	require.Equal(t, "user", h.Auth.Username)
+	require.Equal(t, "password", h.Auth.Password)
+	require.Equal(t, "token", h.Auth.Token)
+	require.Equal(t, "password", h.Headers["X-Api-Key"])
+
+	h = Host{Auth: &Auth{Password: "env:JAM_TEST_NOT_EXIST"}}
+	require.Error(t, h.resolveSecrets())
+}
+
 func TestHost_GenerateID(t *testing.T) {
 	url := "url"
 	group := "group"
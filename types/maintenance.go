@@ -0,0 +1,258 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maintenance - planned window when the host is checked, but incidents and notifications are suppressed.
+// One-off window is defined by start and end, recurring one by cron and duration.
+type Maintenance struct {
+	Name        string `json:"name,omitempty" yaml:"name,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	Start *time.Time `json:"start,omitempty" yaml:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty" yaml:"end,omitempty"`
+
+	Cron     string         `json:"cron,omitempty" yaml:"cron,omitempty"`         // start of the recurring window: minute hour day-of-month month day-of-week, in local time
+	Duration *time.Duration `json:"duration,omitempty" yaml:"duration,omitempty"` // length of the recurring window
+
+	Hosts  []string `json:"hosts,omitempty" yaml:"hosts,omitempty"`   // top level only: ids, names or urls of the hosts, all hosts if hosts and groups are empty
+	Groups []string `json:"groups,omitempty" yaml:"groups,omitempty"` // top level only: groups of the hosts
+
+	schedule *schedule
+}
+
+// MaintenanceWindow - active or upcoming maintenance that is shown on the page
+type MaintenanceWindow struct {
+	Name        string
+	Description string
+	Hosts       []string
+	Active      bool
+	Start       time.Time
+	End         time.Time
+	StartTS     string
+	EndTS       string
+}
+
+// Validate - checks if the window is defined correctly and parses the cron expression
+func (m *Maintenance) Validate() error {
+	oneOff := m.Start != nil || m.End != nil
+	recurring := m.Cron != "" || m.Duration != nil
+	switch {
+	case oneOff && recurring:
+		return errors.New("maintenance must have either start and end or cron and duration")
+	case oneOff:
+		if m.Start == nil || m.End == nil {
+			return errors.New("maintenance must have start and end")
+		}
+		if !m.End.After(*m.Start) {
+			return errors.New("maintenance end must be after start")
+		}
+	case recurring:
+		if m.Cron == "" || m.Duration == nil || *m.Duration <= 0 {
+			return errors.New("maintenance must have cron and positive duration")
+		}
+		s, err := parseCron(m.Cron)
+		if err != nil {
+			return fmt.Errorf("maintenance cron: %w", err)
+		}
+		m.schedule = s
+	default:
+		return errors.New("maintenance must have either start and end or cron and duration")
+	}
+	return nil
+}
+
+// Window - returns the active window at t or the next one after t
+func (m *Maintenance) Window(t time.Time) (time.Time, time.Time, bool) {
+	if m.Start != nil && m.End != nil {
+		if m.End.After(t) {
+			return *m.Start, *m.End, true
+		}
+		return time.Time{}, time.Time{}, false
+	}
+	if m.schedule == nil || m.Duration == nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	// the window is active if it started during the last duration
+	start := m.schedule.next(t.Add(-*m.Duration))
+	if start.IsZero() {
+		return time.Time{}, time.Time{}, false
+	}
+	if start.After(t) {
+		start = m.schedule.next(t)
+	}
+	if start.IsZero() {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, start.Add(*m.Duration), true
+}
+
+// Active - returns true if the window is active at t
+func (m *Maintenance) Active(t time.Time) bool {
+	start, end, ok := m.Window(t)
+	return ok && !start.After(t) && end.After(t)
+}
+
+// applies - returns true if the top level window applies to the host
+func (m *Maintenance) applies(h *Host) bool {
+	if len(m.Hosts) == 0 && len(m.Groups) == 0 {
+		return true
+	}
+	return h.Matches(m.Hosts, m.Groups)
+}
+
+// InMaintenance - returns the active maintenance window of the host or nil
+func (h *Host) InMaintenance(t time.Time) *Maintenance {
+	for _, m := range h.MaintenanceWindows {
+		if m.Active(t) {
+			return m
+		}
+	}
+	return nil
+}
+
+// schedule - parsed cron expression, each field is a set of allowed values
+type schedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	anyDOM, anyDOW                bool
+}
+
+var (
+	cronMonths = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+	cronDays   = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// parseCron - parses the 5 fields cron expression. Supports *, lists, ranges, steps and names of months and days.
+func parseCron(expr string) (*schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	s := &schedule{
+		anyDOM: strings.HasPrefix(fields[2], "*"),
+		anyDOW: strings.HasPrefix(fields[4], "*"),
+	}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, cronMonths); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7, cronDays); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+
+	return s, nil
+}
+
+func parseCronField(field string, min, max int, names map[string]int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	value := func(v string) (int, error) {
+		if i, ok := names[strings.ToLower(v)]; ok {
+			return i, nil
+		}
+		i, err := strconv.Atoi(v)
+		if err != nil || i < min || i > max {
+			return 0, fmt.Errorf("invalid value `%s`", v)
+		}
+		return i, nil
+	}
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx != -1 {
+			i, err := strconv.Atoi(part[idx+1:])
+			if err != nil || i <= 0 {
+				return nil, fmt.Errorf("invalid step `%s`", part[idx+1:])
+			}
+			step = i
+			part = part[:idx]
+		}
+
+		from, to := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			i, err := value(bounds[0])
+			if err != nil {
+				return nil, err
+			}
+			from, to = i, i
+			if len(bounds) == 2 {
+				if to, err = value(bounds[1]); err != nil {
+					return nil, err
+				}
+			} else if step != 1 {
+				to = max
+			}
+			if from > to {
+				return nil, fmt.Errorf("invalid range `%s`", part)
+			}
+		}
+
+		for i := from; i <= to; i += step {
+			values[i] = true
+		}
+	}
+
+	return values, nil
+}
+
+// next - returns the first time after t that matches the schedule, zero time if there is no match in 5 years
+func (s *schedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !s.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.day(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// day - checks the day of month and day of week, when both are restricted any of them must match like in cron
+func (s *schedule) day(t time.Time) bool {
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	switch {
+	case s.anyDOM && s.anyDOW:
+		return true
+	case s.anyDOM:
+		return dow
+	case s.anyDOW:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,129 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := parseCron(expr)
+		require.Error(t, err, expr)
+	}
+
+	s, err := parseCron("*/15 2,4 1-10/3 jan-mar sun")
+	require.NoError(t, err)
+	require.Equal(t, map[int]bool{0: true, 15: true, 30: true, 45: true}, s.minute)
+	require.Equal(t, map[int]bool{2: true, 4: true}, s.hour)
+	require.Equal(t, map[int]bool{1: true, 4: true, 7: true, 10: true}, s.dom)
+	require.Equal(t, map[int]bool{1: true, 2: true, 3: true}, s.month)
+	require.Equal(t, map[int]bool{0: true}, s.dow)
+
+	s, err = parseCron("0 0 * * 7")
+	require.NoError(t, err)
+	require.True(t, s.dow[0])
+}
+
+func TestSchedule_next(t *testing.T) {
+	ts := time.Date(2024, 1, 10, 10, 30, 15, 0, time.UTC) // wednesday
+
+	tests := map[string]time.Time{
+		"* * * * *":     time.Date(2024, 1, 10, 10, 31, 0, 0, time.UTC),
+		"0 * * * *":     time.Date(2024, 1, 10, 11, 0, 0, 0, time.UTC),
+		"30 10 * * *":   time.Date(2024, 1, 11, 10, 30, 0, 0, time.UTC),
+		"0 3 * * sun":   time.Date(2024, 1, 14, 3, 0, 0, 0, time.UTC),
+		"0 0 1 * *":     time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 29 2 *":    time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		"0 0 31 * *":    time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		"0 0 15 * mon":  time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), // day of month or day of week
+		"0 0 20 * thu":  time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC),
+		"0 0 30 feb *":  {},
+		"*/20 22 * * *": time.Date(2024, 1, 10, 22, 0, 0, 0, time.UTC),
+	}
+	for expr, expected := range tests {
+		s, err := parseCron(expr)
+		require.NoError(t, err, expr)
+		require.Equal(t, expected, s.next(ts), expr)
+	}
+}
+
+func TestMaintenance_Validate(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	d := time.Hour
+
+	require.Error(t, (&Maintenance{}).Validate())
+	require.Error(t, (&Maintenance{Start: &now}).Validate())
+	require.Error(t, (&Maintenance{Start: &later, End: &now}).Validate())
+	require.Error(t, (&Maintenance{Start: &now, End: &later, Cron: "* * * * *"}).Validate())
+	require.Error(t, (&Maintenance{Cron: "* * * * *"}).Validate())
+	require.Error(t, (&Maintenance{Cron: "* * *", Duration: &d}).Validate())
+	require.NoError(t, (&Maintenance{Start: &now, End: &later}).Validate())
+	require.NoError(t, (&Maintenance{Cron: "0 3 * * sun", Duration: &d}).Validate())
+}
+
+func TestMaintenance_Window(t *testing.T) {
+	t.Run("one-off", func(t *testing.T) {
+		start := time.Date(2024, 1, 10, 10, 0, 0, 0, time.UTC)
+		end := start.Add(time.Hour)
+		m := &Maintenance{Start: &start, End: &end}
+		require.NoError(t, m.Validate())
+
+		s, e, ok := m.Window(start.Add(-time.Hour))
+		require.True(t, ok)
+		require.Equal(t, start, s)
+		require.Equal(t, end, e)
+		require.False(t, m.Active(start.Add(-time.Minute)))
+		require.True(t, m.Active(start))
+		require.True(t, m.Active(end.Add(-time.Second)))
+		require.False(t, m.Active(end))
+
+		_, _, ok = m.Window(end)
+		require.False(t, ok)
+	})
+
+	t.Run("recurring", func(t *testing.T) {
+		d := time.Hour * 2
+		m := &Maintenance{Cron: "0 3 * * sun", Duration: &d}
+		require.NoError(t, m.Validate())
+
+		sunday := time.Date(2024, 1, 14, 3, 0, 0, 0, time.UTC)
+
+		s, e, ok := m.Window(sunday.Add(-time.Hour * 24))
+		require.True(t, ok)
+		require.Equal(t, sunday, s)
+		require.Equal(t, sunday.Add(d), e)
+
+		s, _, ok = m.Window(sunday.Add(time.Hour))
+		require.True(t, ok)
+		require.Equal(t, sunday, s)
+
+		s, _, ok = m.Window(sunday.Add(d))
+		require.True(t, ok)
+		require.Equal(t, sunday.AddDate(0, 0, 7), s)
+
+		require.False(t, m.Active(sunday.Add(-time.Second)))
+		require.True(t, m.Active(sunday))
+		require.True(t, m.Active(sunday.Add(time.Hour*2-time.Second)))
+		require.False(t, m.Active(sunday.Add(time.Hour*2)))
+	})
+}
+
+func TestHost_InMaintenance(t *testing.T) {
+	now := time.Now()
+	start, end := now.Add(-time.Minute), now.Add(time.Minute)
+	future := now.Add(time.Hour)
+	m1 := &Maintenance{Start: &end, End: &future}
+	m2 := &Maintenance{Start: &start, End: &end}
+	require.NoError(t, m1.Validate())
+	require.NoError(t, m2.Validate())
+
+	h := &Host{}
+	require.Nil(t, h.InMaintenance(now))
+	h.MaintenanceWindows = []*Maintenance{m1}
+	require.Nil(t, h.InMaintenance(now))
+	h.MaintenanceWindows = []*Maintenance{m1, m2}
+	require.Equal(t, m2, h.InMaintenance(now))
+}
@@ -21,19 +21,29 @@ type Chart struct {
 type SSLDetails struct {
 	ExpireInDays int
 	ExpireTS     string
+	ExpireAt     time.Time
+	Issuer       string
+	DNSNames     []string
+	ChainValid   bool
+	ChainError   string
 }
 type LastOutageDetails struct {
 	Duration string
 	Since    string
 	TS       string
+	At       time.Time
 }
 
 // Details is a struct that contains the uptime and response time of a host.
 type Details struct {
 	Uptime       string
 	ResponseTime string
-	SSL          *SSLDetails
-	LastOutage   *LastOutageDetails
+
+	UptimePercent   float64
+	AvgResponseTime time.Duration
+
+	SSL        *SSLDetails
+	LastOutage *LastOutageDetails
 }
 
 // Stat is a struct that contains the stats of a host.
@@ -46,16 +56,30 @@ type Stat struct {
 	Uptime       int
 	ResponseTime string
 	Chart        Chart
+	Hours        Chart // hourly chart of the last 24 hours, only on the host page
 	Hosts        []Stat
 	Details      *Details
 
 	Index int
 }
 
+// ActiveIncident is a struct that contains the incident with the public updates shown on the main page.
+type ActiveIncident struct {
+	ID       string
+	Name     *string
+	Host     string
+	Incident *Incident
+}
+
 // Stats is a struct that contains the stats of all hosts.
 type Stats struct {
-	IsHost    bool
-	Status    StatusType
-	Hosts     []Stat
-	Incidents []*Incident
+	IsHost      bool
+	Status      StatusType
+	Hosts       []Stat
+	Incidents   []*Incident
+	Active      []*ActiveIncident
+	Maintenance []*MaintenanceWindow
+
+	Announcements       []*Announcement // active announcements
+	AnnouncementHistory []*Announcement // resolved announcements
 }
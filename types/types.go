@@ -1,6 +1,9 @@
 package types
 
 import (
+	"errors"
+	"net/http"
+	"slices"
 	"time"
 )
 
@@ -15,9 +18,17 @@ const (
 	DEGRADED StatusType = "degraded"
 	DOWN     StatusType = "down"
 
+	MAINTENANCE StatusType = "maintenance"
+	UNREACHABLE StatusType = "unreachable" // one of the hosts the host depends on is down
+	FLAPPING    StatusType = "flapping"    // the host changes the state too often
+
 	HttpType  HostType = "http"
 	MongoType HostType = "mongo"
 	ICMPType  HostType = "icmp"
+	TCPType   HostType = "tcp"
+	DNSType   HostType = "dns"
+	TLSType   HostType = "tls"
+	PushType  HostType = "push"
 )
 
 // Tag - color tag structure for Service
@@ -39,20 +50,48 @@ type HttpResponse struct {
 	Code      int           `json:"code,omitempty"`
 	Body      string        `json:"body,omitempty"`
 
-	OK         bool       `json:"-"`
-	Bytes      []byte     `json:"-"`
-	Status     bool       `json:"status,omitempty"`
-	StatusType StatusType `json:"statusType,omitempty"`
+	OK         bool        `json:"-"`
+	Bytes      []byte      `json:"-"`
+	Headers    http.Header `json:"-"`
+	Status     bool        `json:"status,omitempty"`
+	StatusType StatusType  `json:"statusType,omitempty"`
+	Reasons    []string    `json:"reasons,omitempty"`  // failed assertions
+	Degraded   bool        `json:"degraded,omitempty"` // response is slow or non-critical assertion failed
 
 	DNS           time.Duration `json:"DNS,omitempty"`
 	TLSHandshake  time.Duration `json:"TLSHandshake,omitempty"`
 	Connect       time.Duration `json:"connect,omitempty"`
 	TTFB          time.Duration `json:"TTFB,omitempty"`
 	SSLCertExpiry *time.Time    `json:"SSLExpiry,omitempty"`
+	Certificate   *Certificate  `json:"certificate,omitempty"`
+
+	IsAggregated bool               `json:"isAggregated"`
+	Uptime       float64            `json:"uptime,omitempty"`     // aggregation uptime
+	Count        int                `json:"count,omitempty"`      // aggregation count
+	Resolution   time.Duration      `json:"resolution,omitempty"` // aggregation period, an hour or a day. Empty in the old daily aggregations
+	MinTime      time.Duration      `json:"minTime,omitempty"`    // aggregation min response time
+	MaxTime      time.Duration      `json:"maxTime,omitempty"`    // aggregation max response time
+	P95Time      time.Duration      `json:"p95Time,omitempty"`    // aggregation 95th percentile of the response time
+	Statuses     map[StatusType]int `json:"statuses,omitempty"`   // aggregation number of the checks per status
+}
 
-	IsAggregated bool    `json:"isAggregated"`
-	Uptime       float64 `json:"uptime,omitempty"` // aggregation uptime
-	Count        int     `json:"count,omitempty"`  // aggregation count
+// Certificate - details of the peer certificate received during the TLS handshake
+type Certificate struct {
+	Subject    string    `json:"subject"`
+	Issuer     string    `json:"issuer"`
+	DNSNames   []string  `json:"dnsNames,omitempty"`
+	NotAfter   time.Time `json:"notAfter"`
+	ChainValid bool      `json:"chainValid"`
+	ChainError string    `json:"chainError,omitempty"`
+}
+
+// Equal - checks if the certificates have the same details, so the unchanged certificate is not saved with every response
+func (c *Certificate) Equal(o *Certificate) bool {
+	if c == nil || o == nil {
+		return c == o
+	}
+	return c.Subject == o.Subject && c.Issuer == o.Issuer && slices.Equal(c.DNSNames, o.DNSNames) &&
+		c.NotAfter.Equal(o.NotAfter) && c.ChainValid == o.ChainValid && c.ChainError == o.ChainError
 }
 
 // Aggregation - aggregation structure for the history per day
@@ -67,17 +106,92 @@ type Aggregation struct {
 type Incident struct {
 	ID       int             `json:"id"`
 	Text     string          `json:"-"`
+	Severity StatusType      `json:"severity,omitempty"` // down or degraded, empty means down
 	Details  IncidentDetails `json:"details"`
 	Start    string          `json:"-"`
 	End      string          `json:"-"`
 	Duration string          `json:"-"`
 	StartTS  time.Time       `json:"startTS"`
 	EndTS    *time.Time      `json:"endTS,omitempty"`
+	Timeline []IncidentEvent `json:"timeline,omitempty"`
+}
+
+// IncidentEventType - type of the incident timeline event
+type IncidentEventType string
+
+const (
+	StatusEvent       IncidentEventType = "status"       // the host status changed
+	AssertionEvent    IncidentEventType = "assertion"    // the assertions failed
+	NotificationEvent IncidentEventType = "notification" // the notification was sent
+	AckEvent          IncidentEventType = "ack"          // somebody acknowledged the incident
+	NoteEvent         IncidentEventType = "note"         // free-text update, public ones are shown on the status page
+)
+
+// IncidentEvent - entry of the incident timeline
+type IncidentEvent struct {
+	Type     IncidentEventType `json:"type"`
+	Status   StatusType        `json:"status,omitempty"`
+	Message  string            `json:"message,omitempty"`
+	Author   string            `json:"author,omitempty"`
+	Public   bool              `json:"public,omitempty"`
+	Channels []string          `json:"channels,omitempty"` // channels of the notification event, used to restore the reminders after the restart
+	TS       time.Time         `json:"ts"`
+}
+
+var (
+	ErrIncidentNotFound     = errors.New("incident not found")
+	ErrIncidentClosed       = errors.New("incident is closed")
+	ErrIncidentAcknowledged = errors.New("incident is already acknowledged")
+)
+
+// GetSeverity - returns the status of the incident, incidents without severity are down
+func (i *Incident) GetSeverity() StatusType {
+	if i.Severity == "" {
+		return DOWN
+	}
+	return i.Severity
+}
+
+// Acknowledgement - returns the first acknowledgement of the incident or nil if nobody acknowledged it
+func (i *Incident) Acknowledgement() *IncidentEvent {
+	for j := range i.Timeline {
+		if i.Timeline[j].Type == AckEvent {
+			return &i.Timeline[j]
+		}
+	}
+	return nil
+}
+
+// AddEvent - appends the event to the timeline. The closed incident cannot be acknowledged and the incident is acknowledged only once,
+// the stores call it in the same transaction as the save, so the concurrent acknowledgements do not add two events.
+func (i *Incident) AddEvent(e IncidentEvent) error {
+	if e.Type == AckEvent {
+		if i.EndTS != nil {
+			return ErrIncidentClosed
+		}
+		if i.Acknowledgement() != nil {
+			return ErrIncidentAcknowledged
+		}
+	}
+	i.Timeline = append(i.Timeline, e)
+	return nil
+}
+
+// PublicUpdates - returns the public notes of the incident, the newest first
+func (i *Incident) PublicUpdates() []IncidentEvent {
+	list := []IncidentEvent{}
+	for j := len(i.Timeline) - 1; j >= 0; j-- {
+		if i.Timeline[j].Type == NoteEvent && i.Timeline[j].Public {
+			list = append(list, i.Timeline[j])
+		}
+	}
+	return list
 }
 
 type IncidentDetails struct {
 	StatusCode int       `json:"statusCode"`
 	StatusText string    `json:"-"`
 	Response   string    `json:"response,omitempty"`
+	Reasons    []string  `json:"reasons,omitempty"`
 	TS         time.Time `json:"ts"`
 }